@@ -0,0 +1,61 @@
+package main
+
+import (
+	"graunt/pkg/exactsubstr"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// exactsubstr 在单机上对 JSONL 分片做精确子串去重:
+//
+//	go run ./cmd/exactsubstr -min-len 100 -index dup.index.jsonl -clean cleaned.jsonl shard-*.jsonl.zst
+//
+// 生成的索引放到服务的 $GRAUNT_DATA_DIR/exactsubstr 下，即可交给 exact_substr 过滤器/改写算法 (参数 exact_substr_index 为文件名) 在流水线中使用。
+func main() {
+	minLen := flag.Int("min-len", 100, "minimum repeated substring length in bytes")
+	indexPath := flag.String("index", "exactsubstr.index.jsonl", "output span index path")
+	cleanPath := flag.String("clean", "", "optional output JSONL with duplicated spans removed")
+	dropFraction := flag.Float64("drop-fraction", 1, "with -clean, drop documents whose duplicated fraction exceeds this value")
+	flag.Parse()
+	if flag.NArg() == 0 { log.Fatal("usage: exactsubstr [flags] shard.jsonl[.gz|.zst] ...") }
+
+	start := time.Now()
+	b := exactsubstr.NewBuilder(*minLen)
+	for _, path := range flag.Args() {
+		n, err := b.AddShard(path)
+		if err != nil { log.Fatalf("read %s: %v", path, err) }
+		log.Printf("loaded %d docs from %s", n, path)
+	}
+
+	docs := b.Build()
+	if err := exactsubstr.WriteIndex(*indexPath, docs); err != nil { log.Fatalf("write index: %v", err) }
+	dupBytes := 0
+	for _, d := range docs { dupBytes += d.DupBytes }
+	log.Printf("%d/%d docs contain duplicated spans (%d bytes), index written to %s in %s", len(docs), b.Len(), dupBytes, *indexPath, time.Since(start))
+
+	if *cleanPath == "" { return }
+	affected := make(map[int]exactsubstr.DocSpans, len(docs))
+	for _, d := range docs { affected[d.Doc] = d }
+	f, err := os.Create(*cleanPath)
+	if err != nil { log.Fatal(err) }
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	kept := 0
+	for i := 0; i < b.Len(); i++ {
+		id, text := b.Doc(i)
+		if d, ok := affected[i]; ok {
+			if d.Length > 0 && float64(d.DupBytes)/float64(d.Length) > *dropFraction { continue }
+			text = exactsubstr.Cut(text, d.Spans)
+		}
+		if err := enc.Encode(map[string]string{"id": id, "text": text}); err != nil { log.Fatal(err) }
+		kept++
+	}
+	if err := bw.Flush(); err != nil { log.Fatal(err) }
+	if err := f.Close(); err != nil { log.Fatal(err) }
+	fmt.Printf("wrote %d docs to %s\n", kept, *cleanPath)
+}
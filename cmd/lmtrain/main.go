@@ -0,0 +1,37 @@
+package main
+
+import (
+	"graunt/pkg/lm"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// lmtrain 离线训练 Kneser-Ney n-gram 模型，默认按 langid 分语种输出 <out>/<lang>.lm:
+//
+//	go run ./cmd/lmtrain -order 3 -out data/lm/wiki wiki-*.jsonl.zst
+//
+// 输出目录可直接作为 perplexity 过滤器的 perplexity_model 参数。
+func main() {
+	order := flag.Int("order", 3, "n-gram order")
+	out := flag.String("out", "lm", "output directory")
+	lang := flag.String("lang", "", "train a single model for this language instead of splitting by langid")
+	minTokens := flag.Int("min-tokens", 1000, "skip languages with fewer training tokens")
+	flag.Parse()
+	if flag.NArg() == 0 { log.Fatal("usage: lmtrain [flags] shard.jsonl[.gz|.zst] ...") }
+
+	start := time.Now()
+	t := lm.NewSetTrainer(*order, *lang)
+	for _, path := range flag.Args() {
+		n, err := t.AddJSONL(path)
+		if err != nil { log.Fatalf("read %s: %v", path, err) }
+		log.Printf("loaded %d docs from %s", n, path)
+	}
+	models, err := t.Save(*out, *minTokens)
+	if err != nil { log.Fatalf("save: %v", err) }
+	for _, m := range models {
+		fmt.Printf("%s\t%d docs\t%d tokens\tvocab %d\t%s\n", m.Lang, m.Documents, m.Tokens, m.Vocab, m.Path)
+	}
+	log.Printf("trained %d models in %s", len(models), time.Since(start))
+}
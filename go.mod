@@ -1,3 +1,11 @@
 module graunt
 
-go 1.22
\ No newline at end of file
+go 1.22
+
+require github.com/klauspost/compress v1.18.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/net v0.25.0
+
+require golang.org/x/text v0.15.0
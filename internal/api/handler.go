@@ -5,29 +5,71 @@ import (
 	"graunt/internal/model"
 	"graunt/internal/service"
 	"graunt/internal/store"
+	"graunt/pkg/algorithm"
 	"graunt/pkg/cluster"
+	"graunt/pkg/jsonl"
+	"graunt/pkg/rewrite"
+	"graunt/pkg/vault"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type APIHandler struct {
 	VLLMClient *external.VLLMClient
+	LLM        external.LLM      // 交给算法的模型接口，默认为带统计的 VLLMClient
+	LLMMetrics *external.Metrics
+	LLMCache   *external.ResponseCache // 未启用响应缓存时为 nil
+	Jobs       *service.JobManager
+	StateDir   string
+	LMDir      string
+	ClfDir     string
+	VaultDir   string
 }
 
 func NewAPIHandler() *APIHandler {
-	return &APIHandler{VLLMClient: external.NewVLLMClient()}
+	client, metrics := external.NewVLLMClient(), external.NewMetrics()
+	return &APIHandler{VLLMClient: client, LLM: external.Wrap(client, external.Instrument(metrics)), LLMMetrics: metrics}
 }
 
 func (h *APIHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/pipeline/filter", h.handleDynamicFilter)
+	mux.HandleFunc("POST /api/pipeline/filter/batch", h.handleBatchFilter)
+	mux.HandleFunc("GET /api/filters/{name}/stats", h.handleFilterStats)
+	mux.HandleFunc("GET /api/rewrites/{name}/stats", h.handleRewriteStats)
+	mux.HandleFunc("POST /api/filters/{name}/state/save", h.handleSaveFilterState)
+	mux.HandleFunc("POST /api/filters/{name}/state/merge", h.handleMergeFilterState)
+	mux.HandleFunc("POST /api/pipeline/validate", h.handleValidatePipeline)
+	mux.HandleFunc("POST /api/pipeline/run", h.handleRunPipeline)
 	mux.HandleFunc("POST /api/dynamic/rewrite", h.handleDynamicRewrite)
 	mux.HandleFunc("POST /api/dynamic/distill", h.handleDynamicDistill)
+	mux.HandleFunc("POST /api/dynamic/rewrite/stream", h.handleStreamRewrite)
+	mux.HandleFunc("POST /api/dynamic/distill/stream", h.handleStreamDistill)
 	mux.HandleFunc("POST /api/dynamic/synthetic", h.handleDynamicSynthetic)
+	mux.HandleFunc("GET /api/backends", h.handleBackends)
+	mux.HandleFunc("GET /api/llm/stats", h.handleLLMStats)
+
+	mux.HandleFunc("POST /api/jobs", h.handleSubmitJob)
+	mux.HandleFunc("GET /api/jobs", h.handleListJobs)
+	mux.HandleFunc("GET /api/jobs/{id}", h.handleGetJob)
+	mux.HandleFunc("DELETE /api/jobs/{id}", h.handleCancelJob)
+	mux.HandleFunc("GET /api/jobs/{id}/result", h.handleJobResult)
 
 	mux.HandleFunc("POST /api/rlhf/known_eval", h.handleRLHFKnownEval)
 	mux.HandleFunc("POST /api/rlhf/infer", h.handleRLHFInfer)
 
 	mux.HandleFunc("POST /api/pretrain/cluster", h.handleCluster)
+	mux.HandleFunc("POST /api/decontam/ingest", h.handleIngestBenchmark)
+	mux.HandleFunc("POST /api/pii/reidentify", h.handleReidentifyPII)
+	mux.HandleFunc("POST /api/lm/train", h.handleTrainLM)
+	mux.HandleFunc("POST /api/classifiers/train", h.handleTrainClassifier)
+	mux.HandleFunc("POST /api/classifiers/{name}/evaluate", h.handleEvaluateClassifier)
 	mux.HandleFunc("POST /api/data/expert", h.handleAddExpert)
 	mux.HandleFunc("POST /api/data/reference", h.handleAddReference)
 }
@@ -39,16 +81,154 @@ func respond(w http.ResponseWriter, status int, data interface{}) {
 }
 func parse(r *http.Request, dest interface{}) error { return json.NewDecoder(r.Body).Decode(dest) }
 
+// llmStatus 把模型调用错误映射为状态码：单次调用超时 504，暂时性故障 503 (客户端可重试)，上游拒绝请求 502，其余 500
+func llmStatus(err error) int {
+	var api *external.APIError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded): return 504
+	case external.IsTransient(err): return 503
+	case errors.As(err, &api): return 502
+	}
+	return 500
+}
+
+func llmError(w http.ResponseWriter, err error) {
+	respond(w, llmStatus(err), map[string]interface{}{"error": err.Error(), "transient": external.IsTransient(err)})
+}
+
+// handleBackends 返回后端池中各模型副本的健康与负载状态
+func (h *APIHandler) handleBackends(w http.ResponseWriter, r *http.Request) {
+	if h.VLLMClient.Pool == nil { respond(w, 200, map[string]interface{}{"enabled": false}); return }
+	respond(w, 200, map[string]interface{}{"enabled": true, "backends": h.VLLMClient.Pool.Status()})
+}
+
+// handleLLMStats 返回各类模型调用的次数、错误与耗时，以及响应缓存的命中情况
+func (h *APIHandler) handleLLMStats(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]interface{}{"calls": h.LLMMetrics.Stats()}
+	if h.LLMCache != nil { stats["cache"] = h.LLMCache.Stats() }
+	respond(w, 200, stats)
+}
+
+// llmContext 返回模型调用使用的 context：请求头 Cache-Control: no-cache 或参数 llm_cache=false 时绕过响应缓存
+func llmContext(r *http.Request, params map[string]interface{}) context.Context {
+	if r.Header.Get("Cache-Control") == "no-cache" || params["llm_cache"] == false { return external.BypassCache(r.Context()) }
+	return r.Context()
+}
+
 func (h *APIHandler) handleDynamicFilter(w http.ResponseWriter, r *http.Request) {
 	var req model.PipelineFilterRequest
 	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
-	for _, algoName := range req.Algorithms {
-		algo, err := service.GetFilter(algoName)
-		if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
-		keep, reason := algo.Evaluate(req.Text, req.Params)
-		if !keep { respond(w, 200, map[string]interface{}{"passed": false, "reason": reason}); return }
+	filters, err := service.ResolveFilters(req.Algorithms)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	if err := service.ValidateFilters(filters, req.Params); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	keep, _, verdicts, meta, err := service.EvaluateFilters(req.Text, filters, req.Params)
+	if err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
+	if !keep { respond(w, 200, map[string]interface{}{"passed": false, "reason": verdicts[len(verdicts)-1].Reason, "metadata": meta}); return }
+	respond(w, 200, map[string]interface{}{"passed": true, "reason": "ok", "metadata": meta})
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" { out = append(out, part) }
+	}
+	return out
+}
+
+// handleBatchFilter 请求体为 JSONL ({"id","text"} 每行一条，可 gzip/zstd 压缩)，
+// 过滤链与参数通过 query 传入: ?algorithms=entropy,minhash&params={...}&workers=8
+func (h *APIHandler) handleBatchFilter(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filters, err := service.ResolveFilters(splitList(q.Get("algorithms")))
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	opts := service.BatchFilterOptions{Filters: filters, Params: map[string]interface{}{}}
+	if raw := q.Get("params"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Params); err != nil { respond(w, 400, map[string]string{"error": "invalid params: " + err.Error()}); return }
+	}
+	if raw := q.Get("workers"); raw != "" {
+		if opts.Workers, err = strconv.Atoi(raw); err != nil { respond(w, 400, map[string]string{"error": "invalid workers: " + err.Error()}); return }
 	}
-	respond(w, 200, map[string]interface{}{"passed": true, "reason": "ok"})
+	if err := service.ValidateFilters(filters, opts.Params); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding == "" { encoding = q.Get("encoding") }
+	body, err := jsonl.NewReader(r.Body, encoding)
+	if err != nil { respond(w, 415, map[string]string{"error": err.Error()}); return }
+	defer body.Close()
+
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Trailer", "X-Batch-Total, X-Batch-Kept, X-Batch-Dropped, X-Batch-Errors")
+	w.WriteHeader(200)
+
+	stats, _ := service.StreamFilterBatch(r.Context(), body, flushWriter{w, rc}, opts)
+	w.Header().Set("X-Batch-Total", strconv.Itoa(stats.Total))
+	w.Header().Set("X-Batch-Kept", strconv.Itoa(stats.Kept))
+	w.Header().Set("X-Batch-Dropped", strconv.Itoa(stats.Dropped))
+	w.Header().Set("X-Batch-Errors", strconv.Itoa(stats.Errors))
+}
+
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil { return n, err }
+	return n, f.rc.Flush()
+}
+
+func (h *APIHandler) handleFilterStats(w http.ResponseWriter, r *http.Request) {
+	algo, err := service.GetFilter(r.PathValue("name"))
+	if err != nil { respond(w, 404, map[string]string{"error": err.Error()}); return }
+	sp, ok := algo.(algorithm.StatsProvider)
+	if !ok { respond(w, 404, map[string]string{"error": "filter '" + algo.Name() + "' does not expose stats"}); return }
+	respond(w, 200, sp.Stats())
+}
+
+func (h *APIHandler) handleRewriteStats(w http.ResponseWriter, r *http.Request) {
+	algo, err := service.GetRewrite(r.PathValue("name"))
+	if err != nil { respond(w, 404, map[string]string{"error": err.Error()}); return }
+	sp, ok := algo.(algorithm.StatsProvider)
+	if !ok { respond(w, 404, map[string]string{"error": "rewrite '" + algo.Name() + "' does not expose stats"}); return }
+	respond(w, 200, sp.Stats())
+}
+
+func (h *APIHandler) handleSaveFilterState(w http.ResponseWriter, r *http.Request) {
+	if h.StateDir == "" { respond(w, 503, map[string]string{"error": "state dir is not configured"}); return }
+	if err := service.SaveFilterState(h.StateDir, r.PathValue("name")); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, map[string]string{"status": "saved"})
+}
+
+func (h *APIHandler) handleMergeFilterState(w http.ResponseWriter, r *http.Request) {
+	var req model.FilterStateMergeRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	stats, err := service.MergeFilterState(r.PathValue("name"), req.Path, req.Params)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, stats)
+}
+
+// handleValidatePipeline 请求体为 YAML 或 JSON 流水线定义
+func (h *APIHandler) handleValidatePipeline(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	spec, err := service.ParsePipelineSpec(data)
+	if err != nil { respond(w, 400, map[string]interface{}{"valid": false, "error": err.Error()}); return }
+	p, err := service.CompilePipeline(spec)
+	if err != nil { respond(w, 400, map[string]interface{}{"valid": false, "error": err.Error()}); return }
+	respond(w, 200, map[string]interface{}{"valid": true, "pipeline": p.Spec})
+}
+
+func (h *APIHandler) handleRunPipeline(w http.ResponseWriter, r *http.Request) {
+	var req model.PipelineRunRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	p, err := service.CompilePipeline(req.Pipeline)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	result, err := p.Run(model.BatchDocument{ID: req.ID, Text: req.Text}, h.LLM.WithContext(llmContext(r, nil)))
+	if err != nil { respond(w, llmStatus(err), result); return }
+	respond(w, 200, result)
 }
 
 func (h *APIHandler) handleDynamicRewrite(w http.ResponseWriter, r *http.Request) {
@@ -58,9 +238,10 @@ func (h *APIHandler) handleDynamicRewrite(w http.ResponseWriter, r *http.Request
 	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
 	if req.Params == nil { req.Params = make(map[string]interface{}) }
 	req.Params["model"], req.Params["vllm_base_url"] = req.Model, req.VLLMBaseURL
-	result, err := algo.Rewrite(req.Text, req.Params, h.VLLMClient)
-	if err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
-	respond(w, 200, map[string]string{"rewritten": result})
+	if err := service.ValidateRewrite(algo, req.Params); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	result, meta, err := service.RewriteText(algo, req.Text, req.Params, h.LLM.WithContext(llmContext(r, req.Params)))
+	if err != nil { llmError(w, err); return }
+	respond(w, 200, map[string]interface{}{"rewritten": result, "metadata": meta})
 }
 
 func (h *APIHandler) handleDynamicDistill(w http.ResponseWriter, r *http.Request) {
@@ -70,11 +251,90 @@ func (h *APIHandler) handleDynamicDistill(w http.ResponseWriter, r *http.Request
 	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
 	if req.Params == nil { req.Params = make(map[string]interface{}) }
 	req.Params["model"], req.Params["vllm_base_url"] = req.Model, req.VLLMBaseURL
-	result, err := algo.Distill(req.Prompt, req.Params, h.VLLMClient)
-	if err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
+	result, err := algo.Distill(req.Prompt, req.Params, h.LLM.WithContext(llmContext(r, req.Params)))
+	if err != nil { llmError(w, err); return }
 	respond(w, 200, map[string]interface{}{"distilled": result})
 }
 
+// handleStreamRewrite 与 handleStreamDistill 以 SSE 返回：模型生成的片段作为 token 事件实时发出，
+// 触发 stream_max_chars / stream_repeat_span 时发出 stopped 事件，最后发出 result 或 error 事件
+func (h *APIHandler) handleStreamRewrite(w http.ResponseWriter, r *http.Request) {
+	var req model.DynamicRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	algo, err := service.GetRewrite(req.Algorithm)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	if req.Params == nil { req.Params = make(map[string]interface{}) }
+	req.Params["model"], req.Params["vllm_base_url"] = req.Model, req.VLLMBaseURL
+	if err := service.ValidateRewrite(algo, req.Params); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	h.streamLLM(w, r, req.Params, func(llm external.LLM) (interface{}, error) {
+		result, meta, err := service.RewriteText(algo, req.Text, req.Params, llm)
+		return map[string]interface{}{"rewritten": result, "metadata": meta}, err
+	})
+}
+
+func (h *APIHandler) handleStreamDistill(w http.ResponseWriter, r *http.Request) {
+	var req model.DynamicRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	algo, err := service.GetDistill(req.Algorithm)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	if req.Params == nil { req.Params = make(map[string]interface{}) }
+	req.Params["model"], req.Params["vllm_base_url"] = req.Model, req.VLLMBaseURL
+	h.streamLLM(w, r, req.Params, func(llm external.LLM) (interface{}, error) {
+		result, err := algo.Distill(req.Prompt, req.Params, llm)
+		return map[string]interface{}{"distilled": result}, err
+	})
+}
+
+func (h *APIHandler) streamLLM(w http.ResponseWriter, r *http.Request, params map[string]interface{}, run func(llm external.LLM) (interface{}, error)) {
+	stop := external.StopConditions{RepeatSpan: 512}
+	if v, ok := params["stream_max_chars"].(float64); ok { stop.MaxChars = int(v) }
+	if v, ok := params["stream_repeat_span"].(float64); ok { stop.RepeatSpan = int(v) }
+	sse, err := newSSEWriter(w)
+	if err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
+	result, err := run(external.Wrap(h.LLM, external.Streaming(stop, sse)).WithContext(llmContext(r, params)))
+	if err != nil {
+		sse.send("error", map[string]interface{}{"error": err.Error(), "status": llmStatus(err), "transient": external.IsTransient(err)})
+		return
+	}
+	sse.send("result", result)
+}
+
+// sseWriter 实现 external.StreamSink；同一请求中的多个调用可能并发输出 (如 dpo_pairs)，写入需串行
+type sseWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+	f  http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	f, ok := w.(http.Flusher)
+	if !ok { return nil, errors.New("streaming is not supported by this connection") }
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	f.Flush()
+	return &sseWriter{w: w, f: f}, nil
+}
+
+func (s *sseWriter) send(event string, data interface{}) error {
+	bts, err := json.Marshal(data)
+	if err != nil { return err }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, bts); err != nil { return err }
+	s.f.Flush()
+	return nil
+}
+
+func (s *sseWriter) Delta(seq int, text string) error {
+	return s.send("token", map[string]interface{}{"call": seq, "delta": text})
+}
+
+func (s *sseWriter) Stopped(seq int, reason string) {
+	s.send("stopped", map[string]interface{}{"call": seq, "reason": reason})
+}
+
 func (h *APIHandler) handleDynamicSynthetic(w http.ResponseWriter, r *http.Request) {
 	var req model.DynamicRequest
 	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
@@ -82,9 +342,60 @@ func (h *APIHandler) handleDynamicSynthetic(w http.ResponseWriter, r *http.Reque
 	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
 	if req.Params == nil { req.Params = make(map[string]interface{}) }
 	req.Params["model"], req.Params["vllm_base_url"] = req.Model, req.VLLMBaseURL
-	result, err := algo.Synthesize(req.Prompt, req.Params, h.VLLMClient)
-	if err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
-	respond(w, 200, map[string]interface{}{"synthetic": result})
+	result, meta, err := service.Synthesize(algo, req.Prompt, req.Params, h.LLM.WithContext(llmContext(r, req.Params)))
+	var contaminated *service.ContaminationError
+	if errors.As(err, &contaminated) { respond(w, 422, map[string]interface{}{"error": err.Error(), "metadata": contaminated.Metadata}); return }
+	if err != nil { llmError(w, err); return }
+	respond(w, 200, map[string]interface{}{"synthetic": result, "metadata": meta})
+}
+
+func (h *APIHandler) jobsEnabled(w http.ResponseWriter) bool {
+	if h.Jobs == nil { respond(w, 503, map[string]string{"error": "job manager is not configured"}); return false }
+	return true
+}
+
+func jobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrJobNotFound) { respond(w, 404, map[string]string{"error": err.Error()}); return }
+	respond(w, 500, map[string]string{"error": err.Error()})
+}
+
+func (h *APIHandler) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if !h.jobsEnabled(w) { return }
+	var req model.JobRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	job, err := h.Jobs.Submit(req)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 202, job)
+}
+
+func (h *APIHandler) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if !h.jobsEnabled(w) { return }
+	respond(w, 200, h.Jobs.List())
+}
+
+func (h *APIHandler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if !h.jobsEnabled(w) { return }
+	job, err := h.Jobs.Get(r.PathValue("id"))
+	if err != nil { jobError(w, err); return }
+	respond(w, 200, job)
+}
+
+func (h *APIHandler) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if !h.jobsEnabled(w) { return }
+	job, err := h.Jobs.Cancel(r.PathValue("id"))
+	if err != nil { jobError(w, err); return }
+	respond(w, 202, job)
+}
+
+func (h *APIHandler) handleJobResult(w http.ResponseWriter, r *http.Request) {
+	if !h.jobsEnabled(w) { return }
+	results, job, err := h.Jobs.OpenResults(r.PathValue("id"))
+	if err != nil { jobError(w, err); return }
+	defer results.Close()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Job-Status", job.Status)
+	w.WriteHeader(200)
+	io.Copy(w, results)
 }
 
 func (h *APIHandler) handleRLHFKnownEval(w http.ResponseWriter, r *http.Request) {
@@ -111,6 +422,55 @@ func (h *APIHandler) handleCluster(w http.ResponseWriter, r *http.Request) {
 	respond(w, 200, res)
 }
 
+// handleIngestBenchmark 导入基准后立即落盘索引，不等待定时保存
+func (h *APIHandler) handleIngestBenchmark(w http.ResponseWriter, r *http.Request) {
+	var req model.DecontamIngestRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	res, err := service.IngestBenchmark(req)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	if h.StateDir != "" {
+		if err := service.SaveFilterState(h.StateDir, "decontam"); err != nil { respond(w, 500, map[string]string{"error": err.Error()}); return }
+	}
+	respond(w, 200, res)
+}
+
+func (h *APIHandler) handleReidentifyPII(w http.ResponseWriter, r *http.Request) {
+	if h.VaultDir == "" { respond(w, 503, map[string]string{"error": "pii vault dir is not configured"}); return }
+	var req model.PIIReidentifyRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	texts, err := rewrite.Reidentify(h.VaultDir, req.Vault, req.Passphrase, req.Texts)
+	if errors.Is(err, vault.ErrPassphrase) { respond(w, 403, map[string]string{"error": err.Error()}); return }
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, map[string]interface{}{"texts": texts})
+}
+
+func (h *APIHandler) handleTrainLM(w http.ResponseWriter, r *http.Request) {
+	if h.LMDir == "" { respond(w, 503, map[string]string{"error": "language model dir is not configured"}); return }
+	var req model.LMTrainRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	res, err := service.TrainLanguageModels(h.LMDir, req)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, res)
+}
+
+func (h *APIHandler) handleTrainClassifier(w http.ResponseWriter, r *http.Request) {
+	if h.ClfDir == "" { respond(w, 503, map[string]string{"error": "classifier dir is not configured"}); return }
+	var req model.ClassifierTrainRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	res, err := service.TrainClassifier(h.ClfDir, req)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, res)
+}
+
+func (h *APIHandler) handleEvaluateClassifier(w http.ResponseWriter, r *http.Request) {
+	if h.ClfDir == "" { respond(w, 503, map[string]string{"error": "classifier dir is not configured"}); return }
+	var req model.ClassifierEvalRequest
+	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	res, err := service.EvaluateClassifier(h.ClfDir, r.PathValue("name"), req)
+	if err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
+	respond(w, 200, res)
+}
+
 func (h *APIHandler) handleAddExpert(w http.ResponseWriter, r *http.Request) {
 	var req model.QAPair
 	if err := parse(r, &req); err != nil { respond(w, 400, map[string]string{"error": err.Error()}); return }
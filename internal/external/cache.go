@@ -0,0 +1,339 @@
+package external
+
+import (
+	"graunt/internal/model"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore 保存序列化后的响应，键为请求的规范化哈希
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Put(key string, value []byte)
+}
+
+type cacheBypassKey struct{}
+
+// BypassCache 返回带绕过标记的 context，在其下的调用既不读也不写响应缓存
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool { v, _ := ctx.Value(cacheBypassKey{}).(bool); return v }
+
+// ResponseCache 是响应缓存中间件的配置与统计。默认只缓存确定性调用：temperature 为 0 或固定了 seed 的对话与补全，
+// 以及向量与 logprob 打分；CacheSampled 为 true 时其余采样调用也缓存。
+// 键只由请求内容决定 (不含 baseURL 与 stream)，同一模型名的不同副本共享缓存；流式调用命中时把缓存内容作为一段输出
+type ResponseCache struct {
+	Store        CacheStore
+	CacheSampled bool
+	mu           sync.Mutex
+	stats        CacheStats
+}
+
+type CacheStats struct {
+	Hits                  int64       `json:"hits"`
+	Misses                int64       `json:"misses"`
+	Bypassed              int64       `json:"bypassed"`
+	Uncacheable           int64       `json:"uncacheable"` // 未缓存的采样调用
+	Stores                int64       `json:"stores"`
+	HitRate               float64     `json:"hit_rate"`
+	SavedPromptTokens     int64       `json:"saved_prompt_tokens"` // 命中的响应原本消耗的 token
+	SavedCompletionTokens int64       `json:"saved_completion_tokens"`
+	Store                 interface{} `json:"store,omitempty"`
+}
+
+func NewResponseCache(store CacheStore) *ResponseCache { return &ResponseCache{Store: store} }
+
+// Cache 是 NewResponseCache(store).Middleware() 的简写
+func Cache(store CacheStore) Middleware { return NewResponseCache(store).Middleware() }
+
+// Stats 返回命中统计的副本，存储实现了 Stats() 时一并返回
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	st := c.stats
+	c.mu.Unlock()
+	if n := st.Hits + st.Misses; n > 0 { st.HitRate = float64(st.Hits) / float64(n) }
+	if sp, ok := c.Store.(interface{ Stats() interface{} }); ok { st.Store = sp.Stats() }
+	return st
+}
+
+func (c *ResponseCache) count(f func(st *CacheStats)) {
+	c.mu.Lock()
+	f(&c.stats)
+	c.mu.Unlock()
+}
+
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call Call) (interface{}, error) {
+			if cacheBypassed(ctx) { c.count(func(st *CacheStats) { st.Bypassed++ }); return next(ctx, call) }
+			if !c.CacheSampled && !Deterministic(call) { c.count(func(st *CacheStats) { st.Uncacheable++ }); return next(ctx, call) }
+			key, err := CacheKey(call)
+			if err != nil { return next(ctx, call) }
+			if data, ok := c.Store.Get(key); ok {
+				if out, err := decodeResult(call.Method, data); err == nil {
+					c.count(func(st *CacheStats) {
+						st.Hits++
+						if u := usageOf(out); u != nil { st.SavedPromptTokens += int64(u.PromptTokens); st.SavedCompletionTokens += int64(u.CompletionTokens) }
+					})
+					if call.OnDelta != nil { return replay(out.(*model.VLLMResponse), call.OnDelta) }
+					return out, nil
+				}
+			}
+			c.count(func(st *CacheStats) { st.Misses++ })
+			out, err := next(ctx, call)
+			if err != nil { return nil, err }
+			// 被 stop 条件截断的流式结果不完整，不缓存
+			if resp, ok := out.(*model.VLLMResponse); ok && (resp.Choices[0].FinishReason == StopMaxChars || resp.Choices[0].FinishReason == StopRepetition) { return out, nil }
+			if data, err := json.Marshal(out); err == nil {
+				c.Store.Put(key, data)
+				c.count(func(st *CacheStats) { st.Stores++ })
+			}
+			return out, nil
+		}
+	}
+}
+
+func usageOf(out interface{}) *model.Usage {
+	switch r := out.(type) {
+	case *model.VLLMResponse: return r.Usage
+	case *model.CompletionResponse: return r.Usage
+	}
+	return nil
+}
+
+// replay 把缓存的对话内容作为一段交给流式回调
+func replay(resp *model.VLLMResponse, onDelta func(string) error) (interface{}, error) {
+	if err := onDelta(resp.Choices[0].Message.Content); err != nil {
+		var stop *StopError
+		if !errors.As(err, &stop) { return nil, err }
+		resp.Choices[0].FinishReason = stop.Reason
+	}
+	return resp, nil
+}
+
+// Deterministic 报告同一请求是否应得到同一结果
+func Deterministic(call Call) bool {
+	switch req := call.Request.(type) {
+	case model.VLLMRequest: return req.Temperature == 0 || req.Seed != nil
+	case model.CompletionRequest: return req.Temperature == 0 || req.Seed != nil
+	}
+	return true
+}
+
+// CacheKey 为方法与请求 JSON (字段顺序固定，不含 stream) 的 SHA-256
+func CacheKey(call Call) (string, error) {
+	req := call.Request
+	if r, ok := req.(model.VLLMRequest); ok { r.Stream = false; req = r }
+	data, err := json.Marshal(req)
+	if err != nil { return "", err }
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", call.Method)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func decodeResult(method string, data []byte) (interface{}, error) {
+	switch method {
+	case MethodChat:
+		var out model.VLLMResponse
+		if err := json.Unmarshal(data, &out); err != nil { return nil, err }
+		if len(out.Choices) == 0 { return nil, ErrNoChoices }
+		return &out, nil
+	case MethodCompletion:
+		var out model.CompletionResponse
+		return &out, json.Unmarshal(data, &out)
+	case MethodEmbeddings:
+		var out model.EmbeddingResponse
+		return &out, json.Unmarshal(data, &out)
+	case MethodLogprobs:
+		var out []model.TokenLogprob
+		err := json.Unmarshal(data, &out)
+		return out, err
+	}
+	return nil, fmt.Errorf("unknown llm method '%s'", method)
+}
+
+// MemoryCache 是按条目数淘汰的进程内 LRU
+type MemoryCache struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 { maxEntries = 10000 }
+	return &MemoryCache{max: maxEntries, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok { return nil, false }
+	c.order.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, true
+}
+
+func (c *MemoryCache) Put(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoryEntry).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key, value})
+	for c.order.Len() > c.max {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*memoryEntry).key)
+	}
+}
+
+// DiskCache 按键寻址把响应存为 dir/<键前两位>/<键>.json，进程重启后仍然有效。
+// 写入超过 TTL 的条目视为未命中并删除；总大小超过 MaxBytes 时按最近访问时间淘汰到 90%
+type DiskCache struct {
+	dir       string
+	ttl       time.Duration
+	maxBytes  int64
+	mu        sync.Mutex
+	index     map[string]*diskEntry
+	bytes     int64
+	expired   int64
+	evictions int64
+}
+
+type diskEntry struct {
+	size    int64
+	created time.Time
+	used    time.Time
+}
+
+type DiskCacheStats struct {
+	Dir       string `json:"dir"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+	TTL       string `json:"ttl"`
+	Expired   int64  `json:"expired"`
+	Evictions int64  `json:"evictions"`
+}
+
+// OpenDiskCache 打开 (必要时创建) 缓存目录并扫描已有条目；ttl 与 maxBytes 为 0 表示不限制
+func OpenDiskCache(dir string, ttl time.Duration, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+	c := &DiskCache{dir: dir, ttl: ttl, maxBytes: maxBytes, index: make(map[string]*diskEntry)}
+	now := time.Now()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() { return err }
+		name := d.Name()
+		// 清理上次异常退出留下的临时文件与过期条目
+		if strings.HasPrefix(name, ".tmp-") { os.Remove(path); return nil }
+		key, ok := strings.CutSuffix(name, ".json")
+		if !ok { return nil }
+		info, err := d.Info()
+		if err != nil { return nil }
+		if ttl > 0 && now.Sub(info.ModTime()) > ttl { os.Remove(path); return nil }
+		c.index[key] = &diskEntry{size: info.Size(), created: info.ModTime(), used: info.ModTime()}
+		c.bytes += info.Size()
+		return nil
+	})
+	if err != nil { return nil, err }
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return c, nil
+}
+
+func (c *DiskCache) path(key string) string { return filepath.Join(c.dir, key[:2], key+".json") }
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	e, ok := c.index[key]
+	if !ok { c.mu.Unlock(); return nil, false }
+	now := time.Now()
+	if c.ttl > 0 && now.Sub(e.created) > c.ttl {
+		c.removeLocked(key, e)
+		c.expired++
+		c.mu.Unlock()
+		return nil, false
+	}
+	e.used = now
+	c.mu.Unlock()
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		c.mu.Lock()
+		if c.index[key] == e { c.removeLocked(key, e) }
+		c.mu.Unlock()
+		return nil, false
+	}
+	return data, true
+}
+
+// Put 先写临时文件再改名，并发读取不会看到写了一半的条目；写入失败时静默放弃
+func (c *DiskCache) Put(key string, value []byte) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\.`) { return }
+	sub := filepath.Join(c.dir, key[:2])
+	if err := os.MkdirAll(sub, 0o755); err != nil { return }
+	f, err := os.CreateTemp(sub, ".tmp-*")
+	if err != nil { return }
+	_, err = f.Write(value)
+	if cerr := f.Close(); err == nil { err = cerr }
+	if err == nil { err = os.Rename(f.Name(), c.path(key)) }
+	if err != nil { os.Remove(f.Name()); return }
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.index[key]; ok { c.bytes -= old.size }
+	c.index[key] = &diskEntry{size: int64(len(value)), created: now, used: now}
+	c.bytes += int64(len(value))
+	c.evictLocked()
+}
+
+func (c *DiskCache) removeLocked(key string, e *diskEntry) {
+	delete(c.index, key)
+	c.bytes -= e.size
+	os.Remove(c.path(key))
+}
+
+// evictLocked 在超过 maxBytes 时淘汰最久未访问的条目，直到降到 90%
+func (c *DiskCache) evictLocked() {
+	if c.maxBytes <= 0 || c.bytes <= c.maxBytes { return }
+	keys := make([]string, 0, len(c.index))
+	for k := range c.index { keys = append(keys, k) }
+	sort.Slice(keys, func(i, j int) bool { return c.index[keys[i]].used.Before(c.index[keys[j]].used) })
+	for _, k := range keys {
+		if c.bytes <= c.maxBytes*9/10 { break }
+		c.removeLocked(k, c.index[k])
+		c.evictions++
+	}
+}
+
+func (c *DiskCache) Stats() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := DiskCacheStats{Dir: c.dir, Entries: len(c.index), Bytes: c.bytes, MaxBytes: c.maxBytes, Expired: c.expired, Evictions: c.evictions}
+	if c.ttl > 0 { st.TTL = c.ttl.String() }
+	return st
+}
@@ -0,0 +1,100 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrEmptyBaseURL = errors.New("vllm base url is empty")
+	ErrNoChoices    = errors.New("vllm returned no choices")
+)
+
+// APIError 是 vLLM 返回的非 200 响应；RetryAfter 来自 Retry-After 头 (没有时为 0)
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	body := strings.TrimSpace(e.Body)
+	if len(body) > 512 { body = body[:512] + "..." }
+	return fmt.Sprintf("vllm api error %d: %s", e.StatusCode, body)
+}
+
+// Temporary 报告该状态码是否值得重试：429 与 5xx (501 除外)
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout ||
+		(e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
+
+// DecodeError 表示响应体不是合法的 completion JSON，属于永久错误
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return "vllm response decode failed: " + e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// RetryError 包装重试耗尽后的最后一个错误
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string { return fmt.Sprintf("vllm call failed after %d attempts: %v", e.Attempts, e.Err) }
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// StreamError 表示流式响应在已向调用方输出部分内容后中断，已输出的内容无法撤回，因此不再重试
+type StreamError struct {
+	Chars int // 中断前已输出的字符数
+	Err   error
+}
+
+func (e *StreamError) Error() string { return fmt.Sprintf("vllm stream interrupted after %d chars: %v", e.Chars, e.Err) }
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// StopError 由流式回调返回，要求提前结束生成；Reason 作为响应的 finish_reason
+type StopError struct {
+	Reason string
+}
+
+func (e *StopError) Error() string { return "generation stopped: " + e.Reason }
+
+// IsTransient 报告 err 是否为暂时性故障 (429/5xx、连接被拒或重置、单次调用超时、响应被截断)，
+// 调用方可以稍后重试；调用方自己取消的 context 不算
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) { return false }
+	var se *StreamError
+	if errors.As(err, &se) { return false }
+	var api *APIError
+	if errors.As(err, &api) { return api.Temporary() }
+	var dec *DecodeError
+	if errors.As(err, &dec) { return errors.Is(dec.Err, io.ErrUnexpectedEOF) }
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) { return true }
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() { return true }
+	var op *net.OpError
+	return errors.As(err, &op)
+}
+
+// parseRetryAfter 支持秒数与 HTTP 日期两种格式
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" { return 0 }
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 { return time.Duration(secs) * time.Second }
+	if t, err := http.ParseTime(v); err == nil && t.After(now) { return t.Sub(now) }
+	return 0
+}
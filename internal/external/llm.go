@@ -0,0 +1,96 @@
+package external
+
+import (
+	"graunt/internal/model"
+	"context"
+	"hash/fnv"
+	"math"
+	"unicode/utf8"
+)
+
+// LLM 是 OpenAI 兼容推理服务的抽象 (vLLM、SGLang、llama.cpp server、TGI 等)，算法只依赖该接口。
+// 方法不带 context：调用方用 WithContext 绑定请求或任务的 context 后再交给算法，之后的调用都在该 context 下执行。
+// baseURL 为空时由实现决定路由 (如 VLLMClient 的后端池)。
+// CallChatCompletion 成功时至少返回一个 choice，否则返回 ErrNoChoices，调用方可直接使用 Choices[0]；
+// 不满足该约定的实现经 Wrap 包装后由包装层补上检查。
+type LLM interface {
+	CallChatCompletion(baseURL string, req model.VLLMRequest) (*model.VLLMResponse, error)
+	CallCompletion(baseURL string, req model.CompletionRequest) (*model.CompletionResponse, error)
+	CallEmbeddings(baseURL string, req model.EmbeddingRequest) (*model.EmbeddingResponse, error)
+	CallLogprobs(baseURL string, req model.LogprobsRequest) ([]model.TokenLogprob, error)
+	WithContext(ctx context.Context) LLM
+}
+
+// SeedParam 读取算法参数中的 seed，给出时采样结果可复现 (响应缓存也据此把采样调用视为确定性的)
+func SeedParam(params map[string]interface{}) *int {
+	v, ok := params["seed"].(float64)
+	if !ok { return nil }
+	seed := int(v)
+	return &seed
+}
+
+// Stub 是不联网的确定性实现，用于测试与离线调试。Reply 为空时回显最后一条消息 (补全回显 prompt)；
+// 向量由文本哈希展开为 Dim 维单位向量，logprob 按字符给出固定值 -1
+type Stub struct {
+	Reply func(req model.VLLMRequest) string
+	Dim   int
+}
+
+func (s *Stub) WithContext(ctx context.Context) LLM { return s }
+
+func (s *Stub) CallChatCompletion(baseURL string, req model.VLLMRequest) (*model.VLLMResponse, error) {
+	var content string
+	if s.Reply != nil {
+		content = s.Reply(req)
+	} else if len(req.Messages) > 0 {
+		content = req.Messages[len(req.Messages)-1].Content
+	}
+	resp := &model.VLLMResponse{Choices: make([]model.ChatChoice, 1)}
+	resp.Choices[0].Message.Content, resp.Choices[0].FinishReason = content, "stop"
+	return resp, nil
+}
+
+func (s *Stub) CallCompletion(baseURL string, req model.CompletionRequest) (*model.CompletionResponse, error) {
+	chat, err := s.CallChatCompletion(baseURL, model.VLLMRequest{Model: req.Model, Messages: []model.Message{{Role: "user", Content: req.Prompt}}})
+	if err != nil { return nil, err }
+	resp := &model.CompletionResponse{Choices: make([]struct {
+		Text         string                    `json:"text"`
+		Logprobs     *model.CompletionLogprobs `json:"logprobs"`
+		FinishReason string                    `json:"finish_reason"`
+	}, 1)}
+	resp.Choices[0].Text, resp.Choices[0].FinishReason = chat.Choices[0].Message.Content, "stop"
+	return resp, nil
+}
+
+func (s *Stub) CallEmbeddings(baseURL string, req model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	dim := s.Dim
+	if dim <= 0 { dim = 16 }
+	resp := &model.EmbeddingResponse{Data: make([]struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	}, len(req.Input))}
+	for i, text := range req.Input {
+		h := fnv.New64a()
+		h.Write([]byte(text))
+		seed := h.Sum64()
+		vec, norm := make([]float64, dim), 0.0
+		for j := range vec {
+			seed = seed*6364136223846793005 + 1442695040888963407
+			vec[j] = float64(int64(seed>>11))/float64(1<<52) - 1
+			norm += vec[j] * vec[j]
+		}
+		for j := range vec { vec[j] /= math.Sqrt(norm) }
+		resp.Data[i].Index, resp.Data[i].Embedding = i, vec
+	}
+	return resp, nil
+}
+
+func (s *Stub) CallLogprobs(baseURL string, req model.LogprobsRequest) ([]model.TokenLogprob, error) {
+	out := make([]model.TokenLogprob, 0, utf8.RuneCountInString(req.Text))
+	for i, r := range []rune(req.Text) {
+		t := model.TokenLogprob{Token: string(r), Offset: i}
+		if i > 0 { lp := -1.0; t.Logprob = &lp }
+		out = append(out, t)
+	}
+	return out, nil
+}
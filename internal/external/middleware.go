@@ -0,0 +1,220 @@
+package external
+
+import (
+	"graunt/internal/model"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Call 描述一次经过中间件的模型调用，Request 为对应方法的请求结构体
+type Call struct {
+	Method  string // chat / completion / embeddings / logprobs
+	BaseURL string
+	Request interface{}
+	OnDelta func(string) error // 非空时对话以流式调用，见 Streaming
+}
+
+const (
+	MethodChat       = "chat"
+	MethodCompletion = "completion"
+	MethodEmbeddings = "embeddings"
+	MethodLogprobs   = "logprobs"
+)
+
+// Invoker 执行一次调用，返回对应方法的响应 (*model.VLLMResponse、*model.CompletionResponse、*model.EmbeddingResponse 或 []model.TokenLogprob)
+type Invoker func(ctx context.Context, call Call) (interface{}, error)
+
+// Middleware 在下一层 Invoker 之外附加逻辑
+type Middleware func(next Invoker) Invoker
+
+// Wrap 用中间件包装任意 LLM 实现，第一个中间件在最外层：
+//
+//	llm := external.Wrap(client, external.Cache(store), external.RateLimit(20, 40), external.Instrument(metrics))
+func Wrap(llm LLM, mws ...Middleware) LLM {
+	invoke := terminal(llm)
+	for i := len(mws) - 1; i >= 0; i-- { invoke = mws[i](invoke) }
+	return &wrapped{invoke: invoke}
+}
+
+// terminal 把调用分派到被包装实现的对应方法；被包装的也是 Wrap 的结果时直接接上其中间件链，以保留 OnDelta。
+// 对话响应没有 choice 时返回 ErrNoChoices，保证 LLM 接口的约定对任意被包装实现成立
+func terminal(llm LLM) Invoker {
+	if w, ok := llm.(*wrapped); ok { return w.invoke }
+	return func(ctx context.Context, call Call) (interface{}, error) {
+		l := llm.WithContext(ctx)
+		switch req := call.Request.(type) {
+		case model.VLLMRequest:
+			var resp *model.VLLMResponse
+			var err error
+			if call.OnDelta != nil { resp, err = streamChat(l, call.BaseURL, req, call.OnDelta) } else { resp, err = l.CallChatCompletion(call.BaseURL, req) }
+			if err != nil { return nil, err }
+			if len(resp.Choices) == 0 { return nil, ErrNoChoices }
+			return resp, nil
+		case model.CompletionRequest: return l.CallCompletion(call.BaseURL, req)
+		case model.EmbeddingRequest: return l.CallEmbeddings(call.BaseURL, req)
+		case model.LogprobsRequest: return l.CallLogprobs(call.BaseURL, req)
+		}
+		return nil, fmt.Errorf("unsupported llm request type %T", call.Request)
+	}
+}
+
+type wrapped struct {
+	invoke Invoker
+	ctx    context.Context
+}
+
+func (w *wrapped) WithContext(ctx context.Context) LLM { return &wrapped{invoke: w.invoke, ctx: ctx} }
+
+func (w *wrapped) do(method, baseURL string, req interface{}) (interface{}, error) {
+	ctx := w.ctx
+	if ctx == nil { ctx = context.Background() }
+	return w.invoke(ctx, Call{Method: method, BaseURL: baseURL, Request: req})
+}
+
+func (w *wrapped) CallChatCompletion(baseURL string, req model.VLLMRequest) (*model.VLLMResponse, error) {
+	out, err := w.do(MethodChat, baseURL, req)
+	if err != nil { return nil, err }
+	return out.(*model.VLLMResponse), nil
+}
+
+func (w *wrapped) CallCompletion(baseURL string, req model.CompletionRequest) (*model.CompletionResponse, error) {
+	out, err := w.do(MethodCompletion, baseURL, req)
+	if err != nil { return nil, err }
+	return out.(*model.CompletionResponse), nil
+}
+
+func (w *wrapped) CallEmbeddings(baseURL string, req model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	out, err := w.do(MethodEmbeddings, baseURL, req)
+	if err != nil { return nil, err }
+	return out.(*model.EmbeddingResponse), nil
+}
+
+func (w *wrapped) CallLogprobs(baseURL string, req model.LogprobsRequest) ([]model.TokenLogprob, error) {
+	out, err := w.do(MethodLogprobs, baseURL, req)
+	if err != nil { return nil, err }
+	return out.([]model.TokenLogprob), nil
+}
+
+// Retry 按 RetryPolicy 重试暂时性故障，用于本身不重试的实现 (VLLMClient 已内置重试)
+func Retry(p RetryPolicy) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call Call) (out interface{}, err error) {
+			err = p.Do(ctx, func(ctx context.Context) error {
+				var e error
+				out, e = next(ctx, call)
+				return e
+			})
+			return out, err
+		}
+	}
+}
+
+// RateLimit 以令牌桶限制调用速率 (每秒 rps 次，允许 burst 次突发)，等待期间 context 结束则放弃调用
+func RateLimit(rps float64, burst int) Middleware {
+	if burst < 1 { burst = 1 }
+	l := &limiter{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call Call) (interface{}, error) {
+			if err := l.wait(ctx); err != nil { return nil, err }
+			return next(ctx, call)
+		}
+	}
+}
+
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait 预支一个令牌，令牌不足时等待到补足为止；取消时归还
+func (l *limiter) wait(ctx context.Context) error {
+	if l.rate <= 0 { return nil }
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	var d time.Duration
+	if l.tokens < 0 { d = time.Duration(-l.tokens / l.rate * float64(time.Second)) }
+	l.mu.Unlock()
+	if d == 0 { return nil }
+	t := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Metrics 按方法统计调用次数、错误与耗时，由 Instrument 中间件写入
+type Metrics struct {
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+type MethodStats struct {
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	Transient int64   `json:"transient_errors"`
+	Inflight  int64   `json:"inflight"`
+	TotalMs   float64 `json:"total_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+}
+
+func NewMetrics() *Metrics { return &Metrics{methods: make(map[string]*MethodStats)} }
+
+func (m *Metrics) method(name string) *MethodStats {
+	s, ok := m.methods[name]
+	if !ok { s = &MethodStats{}; m.methods[name] = s }
+	return s
+}
+
+// Stats 返回各方法统计的副本
+func (m *Metrics) Stats() map[string]MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]MethodStats, len(m.methods))
+	for name, st := range m.methods {
+		s := *st
+		if done := s.Calls - s.Inflight; done > 0 { s.AvgMs = s.TotalMs / float64(done) }
+		out[name] = s
+	}
+	return out
+}
+
+// Instrument 把每次调用的结果与耗时记入 m
+func Instrument(m *Metrics) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call Call) (interface{}, error) {
+			m.mu.Lock()
+			s := m.method(call.Method)
+			s.Calls++
+			s.Inflight++
+			m.mu.Unlock()
+			start := time.Now()
+			out, err := next(ctx, call)
+			ms := float64(time.Since(start).Microseconds()) / 1000
+			m.mu.Lock()
+			s.Inflight--
+			s.TotalMs += ms
+			s.MaxMs = max(s.MaxMs, ms)
+			if err != nil {
+				s.Errors++
+				if IsTransient(err) { s.Transient++ }
+			}
+			m.mu.Unlock()
+			return out, err
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PoolConfig 是服务端的后端池配置 (YAML 或 JSON)：
+//
+//	health_interval: 10s     # 主动探测 /v1/models 的间隔
+//	eject_after: 3           # 连续暂时性失败多少次后摘除
+//	eject_duration: 30s      # 首次摘除时长，连续摘除时翻倍，最长 10 倍
+//	backends:
+//	  qwen2.5-72b-instruct:
+//	    - http://10.0.0.11:8000
+//	    - http://10.0.0.12:8000
+type PoolConfig struct {
+	Backends       map[string][]string `yaml:"backends" json:"backends"`
+	HealthInterval time.Duration       `yaml:"-" json:"-"`
+	EjectAfter     int                 `yaml:"eject_after" json:"eject_after"`
+	EjectDuration  time.Duration       `yaml:"-" json:"-"`
+}
+
+// LoadPoolConfig 读取后端池配置文件
+func LoadPoolConfig(path string) (PoolConfig, error) {
+	var raw struct {
+		PoolConfig     `yaml:",inline"`
+		HealthInterval string `yaml:"health_interval"`
+		EjectDuration  string `yaml:"eject_duration"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil { return PoolConfig{}, err }
+	if err := yaml.Unmarshal(data, &raw); err != nil { return PoolConfig{}, fmt.Errorf("%s: %v", path, err) }
+	cfg := raw.PoolConfig
+	if raw.HealthInterval != "" {
+		if cfg.HealthInterval, err = time.ParseDuration(raw.HealthInterval); err != nil { return cfg, fmt.Errorf("%s: health_interval: %v", path, err) }
+	}
+	if raw.EjectDuration != "" {
+		if cfg.EjectDuration, err = time.ParseDuration(raw.EjectDuration); err != nil { return cfg, fmt.Errorf("%s: eject_duration: %v", path, err) }
+	}
+	for name, urls := range cfg.Backends {
+		if len(urls) == 0 { return cfg, fmt.Errorf("%s: model '%s' has no endpoints", path, name) }
+	}
+	return cfg, nil
+}
+
+// Endpoint 是一个 vLLM 副本
+type Endpoint struct {
+	URL          string
+	inflight     int
+	healthy      bool
+	failures     int // 连续暂时性失败次数
+	ejections    int // 连续被摘除的次数，决定下次摘除时长
+	ejectedUntil time.Time
+	requests     int64
+	errors       int64
+	lastError    string
+	lastCheck    time.Time
+}
+
+// EndpointStatus 是 GET /api/backends 返回的副本状态
+type EndpointStatus struct {
+	URL          string     `json:"url"`
+	Healthy      bool       `json:"healthy"`
+	Inflight     int        `json:"inflight"`
+	Failures     int        `json:"consecutive_failures"`
+	EjectedUntil *time.Time `json:"ejected_until,omitempty"`
+	Requests     int64      `json:"requests"`
+	Errors       int64      `json:"errors"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastCheck    *time.Time `json:"last_check,omitempty"`
+}
+
+// Pool 把模型名映射到多个副本，按在途请求数最少的原则选择副本。
+// 连续 EjectAfter 次暂时性失败或健康检查失败的副本被摘除，摘除期满且健康检查通过后重新加入；
+// 某模型的副本全部不可用时退化为在全部副本间路由，避免探测抖动导致整体不可用。
+type Pool struct {
+	mu             sync.Mutex
+	backends       map[string][]*Endpoint
+	healthInterval time.Duration
+	ejectAfter     int
+	ejectDuration  time.Duration
+	http           *http.Client
+}
+
+func NewPool(cfg PoolConfig) *Pool {
+	p := &Pool{
+		backends:       make(map[string][]*Endpoint, len(cfg.Backends)),
+		healthInterval: cfg.HealthInterval,
+		ejectAfter:     cfg.EjectAfter,
+		ejectDuration:  cfg.EjectDuration,
+		http:           &http.Client{Transport: sharedTransport, Timeout: 5 * time.Second},
+	}
+	if p.healthInterval <= 0 { p.healthInterval = 10 * time.Second }
+	if p.ejectAfter <= 0 { p.ejectAfter = 3 }
+	if p.ejectDuration <= 0 { p.ejectDuration = 30 * time.Second }
+	for name, urls := range cfg.Backends {
+		for _, u := range urls {
+			p.backends[name] = append(p.backends[name], &Endpoint{URL: strings.TrimRight(u, "/"), healthy: true})
+		}
+	}
+	return p
+}
+
+// Models 返回池中配置的模型名
+func (p *Pool) Models() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, 0, len(p.backends))
+	for m := range p.backends { out = append(out, m) }
+	sort.Strings(out)
+	return out
+}
+
+func (e *Endpoint) available(now time.Time) bool { return e.healthy && !now.Before(e.ejectedUntil) }
+
+// Acquire 为 model 选一个副本并计入在途请求；tried 中的副本只在没有其他可用副本时才会再次选中。
+// 调用结束后必须以调用结果调用 Release。
+func (p *Pool) Acquire(model string, tried map[string]bool) (*Endpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	eps, ok := p.backends[model]
+	if !ok { return nil, fmt.Errorf("model '%s' is not in the backend pool and no vllm_base_url was given", model) }
+	now := time.Now()
+	var best []*Endpoint
+	bestLoad := -1
+	for pass := 0; pass < 3 && len(best) == 0; pass++ {
+		for _, e := range eps {
+			switch pass {
+			case 0:
+				if !e.available(now) || tried[e.URL] { continue }
+			case 1:
+				if !e.available(now) { continue }
+			}
+			if bestLoad < 0 || e.inflight < bestLoad { best, bestLoad = best[:0], e.inflight }
+			if e.inflight == bestLoad { best = append(best, e) }
+		}
+	}
+	e := best[rand.Intn(len(best))]
+	e.inflight++
+	e.requests++
+	return e, nil
+}
+
+// Release 归还副本并记录结果：暂时性错误累计失败次数，达到阈值即摘除；请求本身的错误 (4xx 等) 与调用方取消不计入副本健康
+func (p *Pool) Release(e *Endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.inflight--
+	switch {
+	case err == nil:
+		e.failures, e.ejections = 0, 0
+	case IsTransient(err):
+		e.errors++
+		e.failures++
+		e.lastError = err.Error()
+		if e.failures >= p.ejectAfter { p.eject(e, time.Now()) }
+	}
+}
+
+func (p *Pool) eject(e *Endpoint, now time.Time) {
+	d := p.ejectDuration * time.Duration(1<<min(e.ejections, 4))
+	if d > 10*p.ejectDuration { d = 10 * p.ejectDuration }
+	e.ejectedUntil = now.Add(d)
+	e.healthy = false // 期满后须通过一次健康检查才重新加入
+	e.ejections++
+	e.failures = 0
+}
+
+// Start 启动后台健康检查，ctx 结束时停止
+func (p *Pool) Start(ctx context.Context) {
+	go func() {
+		t := time.NewTicker(p.healthInterval)
+		defer t.Stop()
+		p.checkAll(ctx)
+		for {
+			select {
+			case <-ctx.Done(): return
+			case <-t.C: p.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+func (p *Pool) checkAll(ctx context.Context) {
+	p.mu.Lock()
+	var eps []*Endpoint
+	now := time.Now()
+	for _, list := range p.backends {
+		for _, e := range list {
+			// 摘除期内不探测，期满后由探测结果决定是否重新加入
+			if now.Before(e.ejectedUntil) { continue }
+			eps = append(eps, e)
+		}
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range eps {
+		wg.Add(1)
+		go func(e *Endpoint) {
+			defer wg.Done()
+			err := p.probe(ctx, e.URL)
+			if ctx.Err() != nil { return }
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			now := time.Now()
+			e.lastCheck = now
+			if err == nil {
+				e.healthy = true
+				return
+			}
+			e.lastError = "health check: " + err.Error()
+			if e.healthy { p.eject(e, now) }
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/v1/models", nil)
+	if err != nil { return err }
+	resp, err := p.http.Do(req)
+	if err != nil { return err }
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { return fmt.Errorf("GET /v1/models returned %d", resp.StatusCode) }
+	return nil
+}
+
+// Status 返回各模型副本的当前状态
+func (p *Pool) Status() map[string][]EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make(map[string][]EndpointStatus, len(p.backends))
+	for name, eps := range p.backends {
+		for _, e := range eps {
+			st := EndpointStatus{URL: e.URL, Healthy: e.available(now), Inflight: e.inflight, Failures: e.failures, Requests: e.requests, Errors: e.errors, LastError: e.lastError}
+			if now.Before(e.ejectedUntil) { t := e.ejectedUntil; st.EjectedUntil = &t }
+			if !e.lastCheck.IsZero() { t := e.lastCheck; st.LastCheck = &t }
+			out[name] = append(out[name], st)
+		}
+	}
+	return out
+}
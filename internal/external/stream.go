@@ -0,0 +1,244 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"graunt/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// StreamingLLM 是可选接口，实现后对话调用可以按 SSE 流式返回。onDelta 返回 *StopError 时停止生成，
+// 返回已生成的部分并以 Reason 作为 finish_reason；返回其他错误时调用失败
+type StreamingLLM interface {
+	CallChatCompletionStream(baseURL string, req model.VLLMRequest, onDelta func(delta string) error) (*model.VLLMResponse, error)
+}
+
+func (c *VLLMClient) CallChatCompletionStream(baseURL string, req model.VLLMRequest, onDelta func(string) error) (*model.VLLMResponse, error) {
+	return c.ChatCompletionStream(c.context(), baseURL, req, onDelta)
+}
+
+// ChatCompletionStream 以 stream 模式调用 /v1/chat/completions，每收到一段内容调用 onDelta。
+// 还没有输出内容时的暂时性故障照常重试，输出开始后中断则返回 *StreamError。Timeout 在流式调用中是两段数据之间的最长间隔
+func (c *VLLMClient) ChatCompletionStream(ctx context.Context, baseURL string, req model.VLLMRequest, onDelta func(string) error) (*model.VLLMResponse, error) {
+	req.Stream = true
+	var resp *model.VLLMResponse
+	err := c.call(ctx, baseURL, req.Model, "/v1/chat/completions", req, func(ctx context.Context, url string, body []byte) error {
+		var err error
+		resp, err = c.stream(ctx, url, body, onDelta)
+		return err
+	})
+	if err != nil { return nil, err }
+	return resp, nil
+}
+
+// chatChunk 是流式响应中的一个 data 事件
+type chatChunk struct {
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		Logprobs *struct {
+			Content []interface{} `json:"content"`
+		} `json:"logprobs"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *model.Usage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func (c *VLLMClient) stream(ctx context.Context, url string, body []byte, onDelta func(string) error) (*model.VLLMResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// 超过 Timeout 没有收到数据时取消本次尝试
+	var idle atomic.Bool
+	var timer *time.Timer
+	if c.Timeout > 0 {
+		timer = time.AfterFunc(c.Timeout, func() { idle.Store(true); cancel() })
+		defer timer.Stop()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil { return nil, err }
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	var content strings.Builder
+	var logprobs []interface{}
+	var usage *model.Usage
+	choice := model.ChatChoice{}
+	fail := func(err error) error {
+		if idle.Load() { err = fmt.Errorf("no data for %v: %w", c.Timeout, context.DeadlineExceeded) }
+		if content.Len() > 0 { return &StreamError{Chars: utf8.RuneCountInString(content.String()), Err: err} }
+		return err
+	}
+	done := func() *model.VLLMResponse {
+		choice.Message.Content = content.String()
+		if logprobs != nil { choice.Logprobs = map[string]interface{}{"content": logprobs} }
+		return &model.VLLMResponse{Choices: []model.ChatChoice{choice}, Usage: usage}
+	}
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil { return nil, fail(err) }
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { return nil, apiError(resp) }
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if timer != nil { timer.Reset(c.Timeout) }
+		if data, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "data:"); ok {
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" { return done(), nil }
+			var chunk chatChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil { return nil, fail(&DecodeError{Err: err}) }
+			if chunk.Error != nil {
+				code := chunk.Error.Code
+				if code == 0 { code = http.StatusInternalServerError }
+				return nil, fail(&APIError{StatusCode: code, Body: chunk.Error.Message})
+			}
+			if chunk.Usage != nil { usage = chunk.Usage }
+			for _, ch := range chunk.Choices {
+				if ch.Index != 0 { continue }
+				if ch.Logprobs != nil { logprobs = append(logprobs, ch.Logprobs.Content...) }
+				if ch.FinishReason != nil { choice.FinishReason = *ch.FinishReason }
+				if ch.Delta.Content == "" { continue }
+				content.WriteString(ch.Delta.Content)
+				if err := onDelta(ch.Delta.Content); err != nil {
+					var stop *StopError
+					if errors.As(err, &stop) {
+						choice.FinishReason = stop.Reason
+						return done(), nil
+					}
+					return nil, fail(err)
+				}
+			}
+		}
+		if err == io.EOF {
+			// 服务端没有发送 [DONE]：有 finish_reason 时视为正常结束
+			if choice.FinishReason != "" { return done(), nil }
+			return nil, fail(io.ErrUnexpectedEOF)
+		}
+		if err != nil { return nil, fail(err) }
+	}
+}
+
+// streamChat 以流式调用对话接口；l 不支持流式时退化为普通调用，并把完整内容作为一段交给 onDelta
+func streamChat(l LLM, baseURL string, req model.VLLMRequest, onDelta func(string) error) (*model.VLLMResponse, error) {
+	if s, ok := l.(StreamingLLM); ok { return s.CallChatCompletionStream(baseURL, req, onDelta) }
+	resp, err := l.CallChatCompletion(baseURL, req)
+	if err != nil { return nil, err }
+	if len(resp.Choices) == 0 { return nil, ErrNoChoices }
+	if err := onDelta(resp.Choices[0].Message.Content); err != nil {
+		var stop *StopError
+		if !errors.As(err, &stop) { return nil, err }
+		resp.Choices[0].FinishReason = stop.Reason
+	}
+	return resp, nil
+}
+
+func (w *wrapped) CallChatCompletionStream(baseURL string, req model.VLLMRequest, onDelta func(string) error) (*model.VLLMResponse, error) {
+	ctx := w.ctx
+	if ctx == nil { ctx = context.Background() }
+	out, err := w.invoke(ctx, Call{Method: MethodChat, BaseURL: baseURL, Request: req, OnDelta: onDelta})
+	if err != nil { return nil, err }
+	return out.(*model.VLLMResponse), nil
+}
+
+// Streaming 提前终止时使用的 finish_reason
+const (
+	StopMaxChars   = "max_chars"
+	StopRepetition = "repetition"
+)
+
+// StopConditions 是流式生成中的提前终止条件，零值不做限制
+type StopConditions struct {
+	MaxChars   int // 生成内容超过该字符数时截断
+	RepeatSpan int // 末尾同一片段连续重复至少 4 次、总长达到该字节数时视为退化生成，截到第一次出现为止
+}
+
+// check 检查当前累计的内容，触发时返回应保留的前缀长度 (字节) 与原因
+func (s StopConditions) check(text string, chars int) (int, string) {
+	if s.MaxChars > 0 && chars > s.MaxChars {
+		n := 0
+		for i := range text {
+			if n == s.MaxChars { return i, StopMaxChars }
+			n++
+		}
+	}
+	if s.RepeatSpan > 0 {
+		if keep, ok := repeatedTail(text, s.RepeatSpan); ok { return keep, StopRepetition }
+	}
+	return 0, ""
+}
+
+// repeatedTail 查找长度不超过 span/4 字节、在文本末尾连续重复的片段，重复总长达到 span 时返回第一次出现的结束位置
+func repeatedTail(text string, span int) (int, bool) {
+	if len(text) < span { return 0, false }
+	for l := 1; l <= span/4; l++ {
+		unit := text[len(text)-l:]
+		start, count := len(text)-l, 1
+		for start >= l && text[start-l:start] == unit {
+			start -= l
+			count++
+			if count*l >= span && count >= 4 {
+				// 继续向前找到重复开始的位置
+				for start >= l && text[start-l:start] == unit { start -= l }
+				keep := start + l
+				for keep > 0 && keep < len(text) && !utf8.RuneStart(text[keep]) { keep-- }
+				return keep, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// StreamSink 接收 Streaming 中间件的输出，seq 区分同一请求中的多次对话调用 (从 1 开始)
+type StreamSink interface {
+	Delta(seq int, text string) error
+	Stopped(seq int, reason string)
+}
+
+// Streaming 把经过的对话调用改为流式调用，生成的内容按段交给 sink。
+// 触发 stop 时提前结束，返回的内容截到保留部分，finish_reason 为 "max_chars" 或 "repetition"；
+// 已经发出的片段不会撤回，客户端应以最终结果为准
+func Streaming(stop StopConditions, sink StreamSink) Middleware {
+	var calls atomic.Int64
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call Call) (interface{}, error) {
+			if call.Method != MethodChat { return next(ctx, call) }
+			seq := int(calls.Add(1))
+			var b strings.Builder
+			chars, keep, reason := 0, 0, ""
+			call.OnDelta = func(d string) error {
+				prev := b.Len()
+				b.WriteString(d)
+				chars += utf8.RuneCountInString(d)
+				if keep, reason = stop.check(b.String(), chars); reason != "" {
+					if keep > prev {
+						if err := sink.Delta(seq, b.String()[prev:keep]); err != nil { return err }
+					}
+					return &StopError{Reason: reason}
+				}
+				return sink.Delta(seq, d)
+			}
+			out, err := next(ctx, call)
+			if err != nil || reason == "" { return out, err }
+			sink.Stopped(seq, reason)
+			resp := &model.VLLMResponse{Choices: append([]model.ChatChoice(nil), out.(*model.VLLMResponse).Choices...)}
+			resp.Choices[0].Message.Content = b.String()[:keep]
+			resp.Choices[0].FinishReason = reason
+			return resp, nil
+		}
+	}
+}
@@ -3,34 +3,227 @@ package external
 import (
 	"bytes"
 	"graunt/internal/model"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-type VLLMClient struct{}
+// sharedTransport 为所有客户端共用的连接池，避免每次调用新建 TCP 连接
+var sharedTransport = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+	MaxIdleConns:          512,
+	MaxIdleConnsPerHost:   64,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
 
-func NewVLLMClient() *VLLMClient { return &VLLMClient{} }
+// VLLMClient 是 LLM 的 vLLM (及其他 OpenAI 兼容服务) 实现。每次尝试有独立的 Timeout，暂时性故障按 RetryPolicy 重试。
+// WithContext 返回绑定到调用方 context 的副本，算法接口不带 context，处理函数与任务以此让请求取消传到上游调用。
+// 配置了 Pool 时，未给出 baseURL 的调用按 req.Model 在池中选副本，每次重试优先换一个副本。
+type VLLMClient struct {
+	RetryPolicy
+	HTTP    *http.Client
+	Timeout time.Duration // 单次尝试的超时
+	Pool    *Pool         // 可选，服务端配置的多副本后端池
+	ctx     context.Context
+}
 
+func NewVLLMClient() *VLLMClient {
+	return &VLLMClient{
+		RetryPolicy: DefaultRetryPolicy(),
+		HTTP:        &http.Client{Transport: sharedTransport},
+		Timeout:     120 * time.Second,
+	}
+}
+
+// WithContext 返回共享配置与连接池、但绑定到 ctx 的客户端副本
+func (c *VLLMClient) WithContext(ctx context.Context) LLM {
+	cp := *c
+	cp.ctx = ctx
+	return &cp
+}
+
+func (c *VLLMClient) context() context.Context {
+	if c.ctx != nil { return c.ctx }
+	return context.Background()
+}
+
+// CallChatCompletion 使用客户端绑定的 context 调用 ChatCompletion
 func (c *VLLMClient) CallChatCompletion(baseURL string, req model.VLLMRequest) (*model.VLLMResponse, error) {
-	if baseURL == "" { return nil, errors.New("vllm base url is empty") }
-	
-	body, _ := json.Marshal(req)
-	httpReq, _ := http.NewRequest("POST", baseURL+"/v1/chat/completions", bytes.NewBuffer(body))
-	httpReq.Header.Set("Content-Type", "application/json")
+	return c.ChatCompletion(c.context(), baseURL, req)
+}
+
+func (c *VLLMClient) CallCompletion(baseURL string, req model.CompletionRequest) (*model.CompletionResponse, error) {
+	return c.Completion(c.context(), baseURL, req)
+}
+
+func (c *VLLMClient) CallEmbeddings(baseURL string, req model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	return c.Embeddings(c.context(), baseURL, req)
+}
+
+func (c *VLLMClient) CallLogprobs(baseURL string, req model.LogprobsRequest) ([]model.TokenLogprob, error) {
+	return c.Logprobs(c.context(), baseURL, req)
+}
+
+// ChatCompletion 调用 /v1/chat/completions，暂时性故障自动重试；返回的响应至少有一个 choice
+func (c *VLLMClient) ChatCompletion(ctx context.Context, baseURL string, req model.VLLMRequest) (*model.VLLMResponse, error) {
+	var resp model.VLLMResponse
+	err := c.call(ctx, baseURL, req.Model, "/v1/chat/completions", req, func(ctx context.Context, url string, body []byte) error {
+		resp = model.VLLMResponse{}
+		return c.post(ctx, url, body, &resp)
+	})
+	if err != nil { return nil, err }
+	if len(resp.Choices) == 0 { return nil, ErrNoChoices }
+	return &resp, nil
+}
+
+// Completion 调用 /v1/completions；返回的响应至少有一个 choice
+func (c *VLLMClient) Completion(ctx context.Context, baseURL string, req model.CompletionRequest) (*model.CompletionResponse, error) {
+	var resp model.CompletionResponse
+	err := c.call(ctx, baseURL, req.Model, "/v1/completions", req, func(ctx context.Context, url string, body []byte) error {
+		resp = model.CompletionResponse{}
+		return c.post(ctx, url, body, &resp)
+	})
+	if err != nil { return nil, err }
+	if len(resp.Choices) == 0 { return nil, ErrNoChoices }
+	return &resp, nil
+}
+
+// Embeddings 调用 /v1/embeddings，返回的向量按输入顺序排列
+func (c *VLLMClient) Embeddings(ctx context.Context, baseURL string, req model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	var resp model.EmbeddingResponse
+	err := c.call(ctx, baseURL, req.Model, "/v1/embeddings", req, func(ctx context.Context, url string, body []byte) error {
+		resp = model.EmbeddingResponse{}
+		return c.post(ctx, url, body, &resp)
+	})
+	if err != nil { return nil, err }
+	if len(resp.Data) != len(req.Input) { return nil, fmt.Errorf("vllm returned %d embeddings for %d inputs", len(resp.Data), len(req.Input)) }
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	return &resp, nil
+}
 
-	resp, err := (&http.Client{}).Do(httpReq)
+// Logprobs 用 echo 模式的 /v1/completions 给文本逐 token 打分：生成 1 个 token，丢弃偏移落在原文之后的部分
+func (c *VLLMClient) Logprobs(ctx context.Context, baseURL string, req model.LogprobsRequest) ([]model.TokenLogprob, error) {
+	top := req.TopLogprobs
+	resp, err := c.Completion(ctx, baseURL, model.CompletionRequest{Model: req.Model, Prompt: req.Text, MaxTokens: 1, Logprobs: &top, Echo: true})
 	if err != nil { return nil, err }
+	lp := resp.Choices[0].Logprobs
+	if lp == nil || len(lp.TokenLogprobs) != len(lp.Tokens) || len(lp.TextOffset) != len(lp.Tokens) {
+		return nil, &DecodeError{Err: errors.New("completion has no echoed prompt logprobs")}
+	}
+	n := utf8.RuneCountInString(req.Text)
+	out := make([]model.TokenLogprob, 0, len(lp.Tokens))
+	for i, tok := range lp.Tokens {
+		if lp.TextOffset[i] >= n { break }
+		t := model.TokenLogprob{Token: tok, Offset: lp.TextOffset[i], Logprob: lp.TokenLogprobs[i]}
+		if i < len(lp.TopLogprobs) && top > 0 { t.Top = lp.TopLogprobs[i] }
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// call 编码 req 并按 RetryPolicy 重试 attempt；每次尝试选定副本后以完整 URL (地址+path) 调用 attempt
+func (c *VLLMClient) call(ctx context.Context, baseURL, model, path string, req interface{}, attempt func(ctx context.Context, url string, body []byte) error) error {
+	if baseURL == "" && c.Pool == nil { return ErrEmptyBaseURL }
+	body, err := json.Marshal(req)
+	if err != nil { return fmt.Errorf("encode vllm request: %v", err) }
+	tried := make(map[string]bool)
+	return c.Do(ctx, func(ctx context.Context) error {
+		url, release, err := c.endpoint(baseURL, model, tried)
+		if err != nil { return err }
+		err = attempt(ctx, url+path, body)
+		release(err)
+		return err
+	})
+}
+
+// endpoint 返回本次尝试使用的地址：显式给出的 baseURL 优先，否则从 Pool 中按模型选副本
+func (c *VLLMClient) endpoint(baseURL, model string, tried map[string]bool) (string, func(error), error) {
+	if baseURL != "" { return strings.TrimRight(baseURL, "/"), func(error) {}, nil }
+	if model == "" { return "", nil, errors.New("model is required when vllm_base_url is omitted") }
+	e, err := c.Pool.Acquire(model, tried)
+	if err != nil { return "", nil, err }
+	tried[e.URL] = true
+	return e.URL, func(err error) { c.Pool.Release(e, err) }, nil
+}
+
+// post 发送一次请求并把 200 响应解码到 out
+func (c *VLLMClient) post(ctx context.Context, url string, body []byte, out interface{}) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil { return err }
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil { return err }
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { return apiError(resp) }
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil { return &DecodeError{Err: err} }
+	return nil
+}
+
+func apiError(resp *http.Response) *APIError {
+	bts, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return &APIError{StatusCode: resp.StatusCode, Body: string(bts), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// RetryPolicy 描述暂时性故障的重试方式：带抖动的指数退避，服务端给出 Retry-After 时按其等待 (不超过 MaxRetryAfter)
+type RetryPolicy struct {
+	MaxRetries    int // 失败后的最多重试次数
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 3, BaseBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, MaxRetryAfter: 60 * time.Second}
+}
+
+// Do 执行 call，暂时性错误时等待后重试，直到成功、遇到永久错误、次数用尽或 ctx 结束
+func (p RetryPolicy) Do(ctx context.Context, call func(context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := call(ctx)
+		if err == nil { return nil }
+		if ctx.Err() != nil { return ctx.Err() }
+		if !IsTransient(err) { return err }
+		if attempt >= p.MaxRetries { return &RetryError{Attempts: attempt + 1, Err: err} }
 
-	if resp.StatusCode != http.StatusOK {
-		bts, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("api error: %s", string(bts))
+		wait := p.backoff(attempt)
+		var api *APIError
+		if errors.As(err, &api) && api.RetryAfter > 0 {
+			if p.MaxRetryAfter > 0 && api.RetryAfter > p.MaxRetryAfter { return &RetryError{Attempts: attempt + 1, Err: err} }
+			wait = api.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait { return &RetryError{Attempts: attempt + 1, Err: err} }
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
 	}
+}
 
-	var vllmResp model.VLLMResponse
-	json.NewDecoder(resp.Body).Decode(&vllmResp)
-	return &vllmResp, nil
-}
\ No newline at end of file
+// backoff 为 full jitter 指数退避：在 [0, min(MaxBackoff, BaseBackoff*2^attempt)] 内均匀取值
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseBackoff << attempt
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) { d = p.MaxBackoff }
+	if d <= 0 { return 0 }
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
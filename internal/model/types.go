@@ -1,5 +1,10 @@
 package model
 
+import (
+	"graunt/pkg/textclf"
+	"time"
+)
+
 type DynamicRequest struct {
 	Algorithm   string                 `json:"algorithm"`     // 调用的算法名
 	Text        string                 `json:"text"`          // 目标文本 (Filter/Rewrite使用)
@@ -15,6 +20,158 @@ type PipelineFilterRequest struct {
 	Params     map[string]interface{} `json:"params"`
 }
 
+type BatchDocument struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type FilterVerdict struct {
+	Algorithm string                 `json:"algorithm"`
+	Passed    bool                   `json:"passed"`
+	Reason    string                 `json:"reason"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type BatchFilterResult struct {
+	ID       string                 `json:"id"`
+	Line     int                    `json:"line"`
+	Passed   bool                   `json:"passed"`
+	Reason   string                 `json:"reason"`
+	Filters  []FilterVerdict        `json:"filters,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // 各过滤器附加的文档元数据 (如 lang)
+	Error    string                 `json:"error,omitempty"`
+}
+
+type JobRequest struct {
+	Kind         string                 `json:"kind"`                    // filter / rewrite / distill / synthetic
+	Algorithm    string                 `json:"algorithm"`               // 调用的算法名
+	Params       map[string]interface{} `json:"params"`                  // 动态参数字典
+	Model        string                 `json:"model"`                   // 外部模型名称
+	VLLMBaseURL  string                 `json:"vllm_base_url"`           // vLLM 地址
+	Inputs       []BatchDocument        `json:"inputs,omitempty"`        // 内联数据集
+	InputPath    string                 `json:"input_path,omitempty"`    // 服务端 JSONL 数据集路径 (可 .gz/.zst)，相对 GRAUNT_DATA_DIR/inputs
+	Workers      int                    `json:"workers,omitempty"`       // 单任务并发度
+	Pipeline     *PipelineSpec          `json:"pipeline,omitempty"`      // kind=pipeline 时的多阶段定义
+	PipelinePath string                 `json:"pipeline_path,omitempty"` // 或服务端 YAML/JSON 流水线文件，相对 GRAUNT_DATA_DIR/inputs
+}
+
+type JobFailure struct {
+	Index int    `json:"index"`
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+type Job struct {
+	ID         string       `json:"id"`
+	Status     string       `json:"status"`
+	Request    JobRequest   `json:"request"`
+	Total      int          `json:"total"`
+	Processed  int          `json:"processed"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Failures   []JobFailure `json:"failures,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+type JobRecordResult struct {
+	Index  int         `json:"index"`
+	ID     string      `json:"id"`
+	Output interface{} `json:"output,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type PipelineStage struct {
+	Name        string                 `json:"name,omitempty"`
+	Kind        string                 `json:"kind"` // filter / rewrite / distill / synthetic
+	Algorithm   string                 `json:"algorithm"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Model       string                 `json:"model,omitempty"`         // 覆盖全局 model
+	VLLMBaseURL string                 `json:"vllm_base_url,omitempty"` // 覆盖全局 vllm_base_url
+}
+
+type PipelineSpec struct {
+	Name        string          `json:"name"`
+	Model       string          `json:"model,omitempty"`
+	VLLMBaseURL string          `json:"vllm_base_url,omitempty"`
+	Stages      []PipelineStage `json:"stages"`
+}
+
+type StageTrace struct {
+	Stage     string                 `json:"stage"`
+	Kind      string                 `json:"kind"`
+	Algorithm string                 `json:"algorithm"`
+	Action    string                 `json:"action"` // kept / dropped / changed / unchanged / generated / error
+	Reason    string                 `json:"reason,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Output    interface{}            `json:"output,omitempty"`
+}
+
+type PipelineRecordResult struct {
+	ID        string                 `json:"id"`
+	Kept      bool                   `json:"kept"`
+	Text      string                 `json:"text"`
+	DroppedBy string                 `json:"dropped_by,omitempty"`
+	ChangedBy []string               `json:"changed_by,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Stages    []StageTrace           `json:"stages"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+type PipelineRunRequest struct {
+	Pipeline PipelineSpec `json:"pipeline"`
+	ID       string       `json:"id"`
+	Text     string       `json:"text"`
+}
+
+type LMTrainRequest struct {
+	Name      string `json:"name"`                 // 模型名，输出到 <lm 目录>/<name>/<lang>.lm
+	Source    string `json:"source"`               // reference / expert / jsonl
+	InputPath string `json:"input_path,omitempty"` // source=jsonl 时的服务端路径 (可 .gz/.zst)，相对 GRAUNT_DATA_DIR/inputs
+	Order     int    `json:"order,omitempty"`      // n-gram 阶数，默认 3
+	Lang      string `json:"lang,omitempty"`       // 指定时全部文档训练为一个模型，为空时按 langid 分语种训练
+	MinTokens int    `json:"min_tokens,omitempty"` // token 数不足的语种不输出模型
+}
+
+// ClassifierTrainRequest 训练内容分类器；样本来自 JSONL 及可选的数据仓库，例如专家数据标为 hq、随机抓取标为 cc
+type ClassifierTrainRequest struct {
+	Name           string            `json:"name"`                      // 模型名，输出到 <分类器目录>/<name>.clf
+	InputPath      string            `json:"input_path,omitempty"`      // {"text","label"} JSONL (可 .gz/.zst)
+	InputLabel     string            `json:"input_label,omitempty"`     // JSONL 行缺 label 时使用
+	Examples       []textclf.Example `json:"examples,omitempty"`        // 内联样本
+	ExpertLabel    string            `json:"expert_label,omitempty"`    // 非空时把专家数据以该标签加入
+	ReferenceLabel string            `json:"reference_label,omitempty"` // 非空时把参考数据以该标签加入
+	ValidFraction  float64           `json:"valid_fraction,omitempty"`  // 留出验证集比例，默认 0.1
+	textclf.Options
+}
+
+type ClassifierEvalRequest struct {
+	InputPath  string            `json:"input_path,omitempty"`
+	InputLabel string            `json:"input_label,omitempty"`
+	Examples   []textclf.Example `json:"examples,omitempty"`
+}
+
+type DecontamIngestRequest struct {
+	Benchmark string   `json:"benchmark"`           // 基准名，如 gsm8k / mmlu / ceval / humaneval；同名基准会被替换
+	Path      string   `json:"path"`                // 服务端 JSONL 或 CSV 文件 (可 .gz/.zst)，相对 GRAUNT_DATA_DIR/inputs
+	Fields    []string `json:"fields,omitempty"`    // 拼成题目文本的字段，默认除编号外的全部字符串字段
+	NoHeader  bool     `json:"no_header,omitempty"` // CSV 无表头 (如 MMLU)
+}
+
+// PIIReidentifyRequest 用保险库把 pii_mask 伪名化输出还原为原文，仅供持有口令的审计方使用
+type PIIReidentifyRequest struct {
+	Vault      string   `json:"vault"`
+	Passphrase string   `json:"passphrase"`
+	Texts      []string `json:"texts"`
+}
+
+type FilterStateMergeRequest struct {
+	Path   string                 `json:"path"`   // 其他节点导出的快照文件，相对 GRAUNT_DATA_DIR/inputs
+	Params map[string]interface{} `json:"params"` // 例如 minhash_threshold / minhash_namespace
+}
+
 type QAPair struct {
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
@@ -32,6 +189,26 @@ type PretrainClusterRequest struct {
 	K     int      `json:"k"`
 }
 
+type PretrainFilterRequest struct {
+	Text             string  `json:"text"`
+	EntropyThreshold float64 `json:"entropy_threshold"`
+	NGramN           int     `json:"ngram_n"`
+	NGramThreshold   float64 `json:"ngram_threshold"`
+}
+
+type PretrainRewriteRequest struct {
+	Text         string `json:"text"`
+	TeacherModel string `json:"teacher_model"`
+	VLLMBaseURL  string `json:"vllm_base_url"`
+}
+
+type PretrainDistillRequest struct {
+	Prompt      string `json:"prompt"`
+	Model       string `json:"model"`
+	VLLMBaseURL string `json:"vllm_base_url"`
+	DistillType string `json:"distill_type"` // text / logits
+}
+
 type RLHFKnownEvalRequest struct {
 	UserID        string `json:"user_id"`
 	QuestionID    string `json:"question_id"`
@@ -52,6 +229,8 @@ type VLLMRequest struct {
 	Temperature float64   `json:"temperature"`
 	Logprobs    bool      `json:"logprobs,omitempty"`
 	TopLogprobs int       `json:"top_logprobs,omitempty"`
+	Seed        *int      `json:"seed,omitempty"` // 固定种子时采样结果可复现
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 type Message struct {
@@ -60,10 +239,74 @@ type Message struct {
 }
 
 type VLLMResponse struct {
+	Choices []ChatChoice `json:"choices"`
+	Usage   *Usage       `json:"usage,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type ChatChoice struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+}
+
+// CompletionRequest 对应 /v1/completions；Echo 与 Logprobs 一起使用可取得 prompt 各 token 的 logprob
+type CompletionRequest struct {
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens"`
+	Temperature float64  `json:"temperature"`
+	Logprobs    *int     `json:"logprobs,omitempty"`
+	Echo        bool     `json:"echo,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+type CompletionResponse struct {
 	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-		Logprobs interface{} `json:"logprobs"`
+		Text         string              `json:"text"`
+		Logprobs     *CompletionLogprobs `json:"logprobs"`
+		FinishReason string              `json:"finish_reason"`
 	} `json:"choices"`
-}
\ No newline at end of file
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// CompletionLogprobs 是 completions 接口的逐 token logprob，首个 prompt token 没有上文，logprob 为 null
+type CompletionLogprobs struct {
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []*float64           `json:"token_logprobs"`
+	TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+	TextOffset    []int                `json:"text_offset"`
+}
+
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// LogprobsRequest 请求对一段给定文本逐 token 打分 (不生成新内容)
+type LogprobsRequest struct {
+	Model       string `json:"model"`
+	Text        string `json:"text"`
+	TopLogprobs int    `json:"top_logprobs,omitempty"`
+}
+
+type TokenLogprob struct {
+	Token   string             `json:"token"`
+	Offset  int                `json:"offset"`  // 在文本中的字符偏移
+	Logprob *float64           `json:"logprob"` // 首个 token 为 null
+	Top     map[string]float64 `json:"top,omitempty"`
+}
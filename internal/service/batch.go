@@ -0,0 +1,254 @@
+package service
+
+import (
+	"graunt/internal/external"
+	"graunt/internal/model"
+	"graunt/pkg/algorithm"
+	"graunt/pkg/jsonl"
+	"graunt/pkg/nlp"
+	"graunt/pkg/textnorm"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+type BatchFilterOptions struct {
+	Filters []algorithm.FilterAlgorithm
+	Params  map[string]interface{}
+	Workers int
+}
+
+type BatchStats struct {
+	Total   int `json:"total"`
+	Kept    int `json:"kept"`
+	Dropped int `json:"dropped"`
+	Errors  int `json:"errors"`
+}
+
+func ResolveFilters(names []string) ([]algorithm.FilterAlgorithm, error) {
+	filters := make([]algorithm.FilterAlgorithm, 0, len(names))
+	for _, name := range names {
+		algo, err := GetFilter(name)
+		if err != nil { return nil, err }
+		filters = append(filters, algo)
+	}
+	return filters, nil
+}
+
+// filterText 在参数给出 normalize (true 或步骤列表) 时返回规范化后的文本供过滤器打分，原文不变
+func filterText(text string, params map[string]interface{}) (string, error) {
+	steps := params["normalize"]
+	switch v := steps.(type) {
+	case nil:
+		return text, nil
+	case bool:
+		if !v { return text, nil }
+		steps = nil
+	case string, []interface{}, []string:
+	default:
+		return text, nil
+	}
+	dict, _ := params["normalize_t2s_dict"].(string)
+	opts, err := textnorm.ParseOptions(steps, dict)
+	if err != nil { return text, err }
+	out := nlp.Analyze(text).Memo("normalize:"+opts.Key()+":"+dict, func() interface{} {
+		out, _ := textnorm.Normalize(text, opts)
+		return out
+	})
+	return out.(string), nil
+}
+
+// ValidateFilters 对实现了 ValidatingFilter 的过滤器检查一次参数，在处理任何文档之前调用
+func ValidateFilters(filters []algorithm.FilterAlgorithm, params map[string]interface{}) error {
+	for _, algo := range filters {
+		if vf, ok := algo.(algorithm.ValidatingFilter); ok {
+			if err := vf.Validate(params); err != nil { return fmt.Errorf("%s: %v", algo.Name(), err) }
+		}
+	}
+	return nil
+}
+
+// ValidateRewrite 对实现了 ValidatingRewrite 的改写算法检查一次参数，在处理任何文档之前调用
+func ValidateRewrite(algo algorithm.RewriteAlgorithm, params map[string]interface{}) error {
+	if vr, ok := algo.(algorithm.ValidatingRewrite); ok {
+		if err := vr.Validate(params); err != nil { return fmt.Errorf("%s: %v", algo.Name(), err) }
+	}
+	return nil
+}
+
+// evaluateFilter 调用过滤器，实现了 MetadataFilter 的同时返回其附加的元数据；
+// 参数或资源错误以 error 返回，不作为丢弃判定
+func evaluateFilter(algo algorithm.FilterAlgorithm, text string, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	text, err := filterText(text, params)
+	if err != nil { return false, "", nil, err }
+	if cf, ok := algo.(algorithm.CheckedFilter); ok { return cf.EvaluateChecked(text, params) }
+	if mf, ok := algo.(algorithm.MetadataFilter); ok {
+		keep, reason, meta := mf.EvaluateWithMetadata(text, params)
+		return keep, reason, meta, nil
+	}
+	keep, reason := algo.Evaluate(text, params)
+	return keep, reason, nil, nil
+}
+
+// RewriteText 调用改写算法，实现了 MetadataRewrite 的同时返回其附加的元数据
+func RewriteText(algo algorithm.RewriteAlgorithm, text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	if mr, ok := algo.(algorithm.MetadataRewrite); ok { return mr.RewriteWithMetadata(text, params, llm) }
+	out, err := algo.Rewrite(text, params, llm)
+	return out, nil, err
+}
+
+// withMetadata 返回合并了文档元数据的参数副本，显式给出的同名参数优先
+func withMetadata(params, meta map[string]interface{}) map[string]interface{} {
+	if len(meta) == 0 { return params }
+	out := make(map[string]interface{}, len(params)+len(meta))
+	for k, v := range meta { out[k] = v }
+	for k, v := range params { out[k] = v }
+	return out
+}
+
+func mergeMetadata(dst, src map[string]interface{}) map[string]interface{} {
+	if len(src) == 0 { return dst }
+	if dst == nil { dst = make(map[string]interface{}, len(src)) }
+	for k, v := range src { dst[k] = v }
+	return dst
+}
+
+// EvaluateFilters 依次执行过滤链，遇到丢弃或错误时停止；错误以 "<过滤器名>: ..." 的形式返回
+func EvaluateFilters(text string, filters []algorithm.FilterAlgorithm, params map[string]interface{}) (bool, string, []model.FilterVerdict, map[string]interface{}, error) {
+	verdicts := make([]model.FilterVerdict, 0, len(filters))
+	var meta map[string]interface{}
+	for _, algo := range filters {
+		keep, reason, m, err := evaluateFilter(algo, text, withMetadata(params, meta))
+		if err != nil { return false, "", verdicts, meta, fmt.Errorf("%s: %v", algo.Name(), err) }
+		meta = mergeMetadata(meta, m)
+		verdicts = append(verdicts, model.FilterVerdict{Algorithm: algo.Name(), Passed: keep, Reason: reason, Metadata: m})
+		if !keep { return false, "Failed at " + algo.Name() + ": " + reason, verdicts, meta, nil }
+	}
+	return true, "Passed all filters", verdicts, meta, nil
+}
+
+// evaluateBatchLine 在 worker goroutine 中运行，过滤器 panic 时记为该行的错误，不能拖垮整个服务
+func evaluateBatchLine(rec jsonlRecord, opts BatchFilterOptions) (res model.BatchFilterResult) {
+	res = model.BatchFilterResult{Line: rec.Line}
+	var doc model.BatchDocument
+	if err := json.Unmarshal(rec.Raw, &doc); err != nil {
+		res.Reason, res.Error = "invalid document", err.Error()
+		return res
+	}
+	res.ID = doc.ID
+	if res.ID == "" { res.ID = strconv.Itoa(rec.Line) }
+	defer func() {
+		if p := recover(); p != nil { res.Passed, res.Filters, res.Metadata, res.Reason, res.Error = false, nil, nil, "filter error", fmt.Sprintf("panic: %v", p) }
+	}()
+	var err error
+	res.Passed, res.Reason, res.Filters, res.Metadata, err = EvaluateFilters(doc.Text, opts.Filters, opts.Params)
+	if err != nil { res.Reason, res.Error = "filter error", err.Error() }
+	return res
+}
+
+type jsonlRecord struct {
+	Seq  int
+	Line int
+	Raw  []byte
+}
+
+// streamOrdered 逐行读取 JSONL，交给 workers 个 goroutine 并发执行 fn，并按输入顺序回调 emit。
+// 在途记录不超过 2*workers，因此内存占用与输入大小无关；skip 跳过前若干条非空记录 (用于断点续跑)。
+func streamOrdered[R any](ctx context.Context, in io.Reader, workers, skip int, fn func(jsonlRecord) R, emit func(r R, more bool) error) error {
+	if workers <= 0 { workers = runtime.NumCPU() }
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type task struct {
+		rec jsonlRecord
+		res chan R
+	}
+	tasks := make(chan task, workers)
+	pending := make(chan chan R, workers*2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks { t.res <- fn(t.rec) }
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(tasks)
+		sc := jsonl.NewScanner(in)
+		line, seq := 0, 0
+		for sc.Scan() {
+			line++
+			raw := bytes.TrimSpace(sc.Bytes())
+			if len(raw) == 0 { continue }
+			seq++
+			if seq <= skip { continue }
+			t := task{rec: jsonlRecord{Seq: seq - 1, Line: line, Raw: append([]byte(nil), raw...)}, res: make(chan R, 1)}
+			select {
+			case pending <- t.res:
+			case <-ctx.Done(): readErr <- ctx.Err(); return
+			}
+			select {
+			case tasks <- t:
+			case <-ctx.Done(): readErr <- ctx.Err(); return
+			}
+		}
+		readErr <- sc.Err()
+	}()
+
+	var err error
+	for res := range pending {
+		var r R
+		select {
+		case r = <-res:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil { break }
+		if err = emit(r, len(pending) > 0); err != nil { break }
+	}
+	parentErr := ctx.Err()
+	cancel()
+	wg.Wait()
+	rerr := <-readErr
+
+	if err != nil { return err }
+	if parentErr != nil { return parentErr }
+	return rerr
+}
+
+// StreamFilterBatch 逐行读取 JSONL 文档，经有界 worker 池过滤后按输入顺序写回结果。
+func StreamFilterBatch(ctx context.Context, in io.Reader, out io.Writer, opts BatchFilterOptions) (BatchStats, error) {
+	var stats BatchStats
+	var writeErr error
+	bw := bufio.NewWriter(out)
+	enc := json.NewEncoder(bw)
+
+	err := streamOrdered(ctx, in, opts.Workers, 0,
+		func(rec jsonlRecord) model.BatchFilterResult { return evaluateBatchLine(rec, opts) },
+		func(r model.BatchFilterResult, more bool) error {
+			stats.Total++
+			switch {
+			case r.Error != "": stats.Errors++
+			case r.Passed: stats.Kept++
+			default: stats.Dropped++
+			}
+			if writeErr = enc.Encode(r); writeErr != nil { return writeErr }
+			if !more { writeErr = bw.Flush() }
+			return writeErr
+		})
+	if err != nil && err != writeErr && ctx.Err() == nil {
+		enc.Encode(model.BatchFilterResult{Reason: "stream aborted", Error: err.Error()})
+	}
+	bw.Flush()
+	return stats, err
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"graunt/internal/model"
+	"graunt/internal/store"
+	"graunt/pkg/textclf"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+type ClassifierTrainResult struct {
+	Name       string           `json:"name"`
+	Path       string           `json:"path"`
+	Labels     []string         `json:"labels"`
+	Train      int              `json:"train_examples"`
+	Validation *textclf.Metrics `json:"validation,omitempty"`
+}
+
+func classifierPath(dir, name string) (string, error) {
+	if !modelNamePattern.MatchString(name) || name == "." || name == ".." { return "", fmt.Errorf("invalid model name '%s'", name) }
+	return filepath.Join(dir, name+textclf.Ext), nil
+}
+
+func classifierExamples(inputPath, inputLabel string, inline []textclf.Example) ([]textclf.Example, error) {
+	examples := append([]textclf.Example(nil), inline...)
+	for i, ex := range examples {
+		if ex.Label == "" { return nil, fmt.Errorf("examples[%d]: missing label", i) }
+	}
+	if inputPath != "" {
+		path, err := ResolveInputPath(inputPath)
+		if err != nil { return nil, err }
+		more, err := textclf.ReadExamples(path, inputLabel)
+		if err != nil { return nil, err }
+		examples = append(examples, more...)
+	}
+	return examples, nil
+}
+
+// TrainClassifier 训练 fastText 式分类器写入 dir/<name>.clf，并在留出的验证集上报告精确率/召回率
+func TrainClassifier(dir string, req model.ClassifierTrainRequest) (ClassifierTrainResult, error) {
+	path, err := classifierPath(dir, req.Name)
+	if err != nil { return ClassifierTrainResult{}, err }
+	if err := req.Options.Validate(); err != nil { return ClassifierTrainResult{}, err }
+	examples, err := classifierExamples(req.InputPath, req.InputLabel, req.Examples)
+	if err != nil { return ClassifierTrainResult{}, err }
+	if req.ExpertLabel != "" {
+		for _, qa := range store.GlobalDataStore.GetExpertData() { examples = append(examples, textclf.Example{Text: qa.Question + "\n" + qa.Answer, Label: req.ExpertLabel}) }
+	}
+	if req.ReferenceLabel != "" {
+		for _, qa := range store.GlobalDataStore.GetReferenceData() { examples = append(examples, textclf.Example{Text: qa.Question + "\n" + qa.Answer, Label: req.ReferenceLabel}) }
+	}
+	if len(examples) == 0 { return ClassifierTrainResult{}, errors.New("no training examples") }
+
+	if req.ValidFraction == 0 { req.ValidFraction = 0.1 }
+	train, valid := textclf.Split(examples, req.ValidFraction, req.Seed)
+	m := textclf.Train(train, req.Options)
+	if len(m.Labels) < 2 { return ClassifierTrainResult{}, errors.New("at least two labels are required") }
+	if err := m.Save(path); err != nil { return ClassifierTrainResult{}, err }
+
+	res := ClassifierTrainResult{Name: req.Name, Path: path, Labels: m.Labels, Train: len(train)}
+	if len(valid) > 0 { metrics := m.Evaluate(valid); res.Validation = &metrics }
+	return res, nil
+}
+
+// EvaluateClassifier 在给定的带标签样本上评估已保存的分类器
+func EvaluateClassifier(dir, name string, req model.ClassifierEvalRequest) (textclf.Metrics, error) {
+	path, err := classifierPath(dir, name)
+	if err != nil { return textclf.Metrics{}, err }
+	m, err := textclf.Cached(path)
+	if err != nil { return textclf.Metrics{}, err }
+	examples, err := classifierExamples(req.InputPath, req.InputLabel, req.Examples)
+	if err != nil { return textclf.Metrics{}, err }
+	if len(examples) == 0 { return textclf.Metrics{}, errors.New("no evaluation examples") }
+	return m.Evaluate(examples), nil
+}
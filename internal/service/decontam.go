@@ -0,0 +1,63 @@
+package service
+
+import (
+	"graunt/internal/external"
+	"graunt/internal/model"
+	"graunt/pkg/algorithm"
+	"graunt/pkg/decontam"
+	"errors"
+	"strings"
+)
+
+const decontamFilterName = "decontam"
+
+type indexedFilter interface {
+	algorithm.FilterAlgorithm
+	Index() *decontam.Index
+}
+
+// ContaminationError 表示合成输出与评测基准重叠而被丢弃
+type ContaminationError struct {
+	Reason   string
+	Metadata map[string]interface{}
+}
+
+func (e *ContaminationError) Error() string { return "synthetic output is contaminated: " + e.Reason }
+
+func decontamFilter() (indexedFilter, error) {
+	algo, err := GetFilter(decontamFilterName)
+	if err != nil { return nil, err }
+	f, ok := algo.(indexedFilter)
+	if !ok { return nil, errors.New("filter 'decontam' does not expose a benchmark index") }
+	return f, nil
+}
+
+// IngestBenchmark 把基准文件导入 decontam 过滤器的 n-gram 索引
+func IngestBenchmark(req model.DecontamIngestRequest) (decontam.IngestStats, error) {
+	if req.Benchmark == "" || req.Path == "" { return decontam.IngestStats{}, errors.New("benchmark and path are required") }
+	f, err := decontamFilter()
+	if err != nil { return decontam.IngestStats{}, err }
+	path, err := ResolveInputPath(req.Path)
+	if err != nil { return decontam.IngestStats{}, err }
+	return f.Index().Ingest(req.Benchmark, path, decontam.ReadOptions{Fields: req.Fields, NoHeader: req.NoHeader})
+}
+
+// CheckContamination 用 decontam 过滤器检查任意输出 (字符串或结构化结果) 中的全部文本，参数同该过滤器
+func CheckContamination(output interface{}, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	f, err := decontamFilter()
+	if err != nil { return false, "", nil, err }
+	if err := ValidateFilters([]algorithm.FilterAlgorithm{f}, params); err != nil { return false, "", nil, err }
+	return evaluateFilter(f, strings.Join(decontam.Strings(output), "\n"), params)
+}
+
+// Synthesize 调用合成算法；params["decontaminate"] 为 true 时检查输出，
+// 命中基准时按 decontam_mode 丢弃 (返回 *ContaminationError) 或仅在元数据中标记
+func Synthesize(algo algorithm.SyntheticAlgorithm, prompt string, params map[string]interface{}, llm external.LLM) (interface{}, map[string]interface{}, error) {
+	out, err := algo.Synthesize(prompt, params, llm)
+	if err != nil { return nil, nil, err }
+	if check, _ := params["decontaminate"].(bool); !check { return out, nil, nil }
+	keep, reason, meta, err := CheckContamination(out, params)
+	if err != nil { return nil, nil, err }
+	if !keep { return nil, meta, &ContaminationError{Reason: reason, Metadata: meta} }
+	return out, meta, nil
+}
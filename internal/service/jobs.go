@@ -0,0 +1,409 @@
+package service
+
+import (
+	"graunt/internal/external"
+	"graunt/internal/model"
+	"graunt/pkg/algorithm"
+	"graunt/pkg/jsonl"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+const (
+	maxJobFailures   = 100
+	jobSaveInterval  = time.Second
+	defaultJobWorker = 4
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// JobManager 在后台执行长耗时的数据集任务。每个任务的状态、输入和逐条结果都落盘在 dir 下，
+// 服务重启后未完成的任务会从结果文件的断点继续执行。
+type JobManager struct {
+	mu    sync.RWMutex
+	dir   string
+	llm   external.LLM
+	jobs  map[string]*jobEntry
+	slots chan struct{}
+}
+
+type jobEntry struct {
+	mu       sync.Mutex
+	job      model.Job
+	cancel   context.CancelFunc
+	lastSave time.Time
+}
+
+func NewJobManager(dir string, llm external.LLM, concurrency int) (*JobManager, error) {
+	if concurrency <= 0 { concurrency = 2 }
+	if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+	m := &JobManager{dir: dir, llm: llm, jobs: make(map[string]*jobEntry), slots: make(chan struct{}, concurrency)}
+	return m, m.recover()
+}
+
+func (m *JobManager) path(id, suffix string) string { return filepath.Join(m.dir, id+suffix) }
+
+func (m *JobManager) recover() error {
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.json"))
+	if err != nil { return err }
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil { return err }
+		var job model.Job
+		if err := json.Unmarshal(raw, &job); err != nil { return fmt.Errorf("corrupt job state %s: %v", f, err) }
+		e := &jobEntry{job: job}
+		m.jobs[job.ID] = e
+		if job.Status == JobQueued || job.Status == JobRunning {
+			e.job.Status = JobQueued
+			m.start(e)
+		}
+	}
+	return nil
+}
+
+func ValidateAlgorithm(kind, name string) error {
+	var err error
+	switch kind {
+	case "filter": _, err = GetFilter(name)
+	case "rewrite": _, err = GetRewrite(name)
+	case "distill": _, err = GetDistill(name)
+	case "synthetic": _, err = GetSynthetic(name)
+	default: err = fmt.Errorf("unknown algorithm kind '%s'", kind)
+	}
+	return err
+}
+
+// validateJobAlgorithm 在 filter / rewrite 任务处理任何文档之前检查一次参数与所需资源
+func validateJobAlgorithm(kind, name string, params map[string]interface{}) error {
+	switch kind {
+	case "filter":
+		algo, err := GetFilter(name)
+		if err != nil { return err }
+		return ValidateFilters([]algorithm.FilterAlgorithm{algo}, params)
+	case "rewrite":
+		algo, err := GetRewrite(name)
+		if err != nil { return err }
+		return ValidateRewrite(algo, params)
+	}
+	return nil
+}
+
+// executeAlgorithm 以统一方式调用四类已注册算法，filter 的结果以 FilterVerdict 返回
+func executeAlgorithm(kind, name, input string, params map[string]interface{}, llm external.LLM) (interface{}, error) {
+	switch kind {
+	case "filter":
+		algo, err := GetFilter(name)
+		if err != nil { return nil, err }
+		keep, reason, meta, err := evaluateFilter(algo, input, params)
+		if err != nil { return nil, err }
+		return model.FilterVerdict{Algorithm: name, Passed: keep, Reason: reason, Metadata: meta}, nil
+	case "rewrite":
+		algo, err := GetRewrite(name)
+		if err != nil { return nil, err }
+		return algo.Rewrite(input, params, llm)
+	case "distill":
+		algo, err := GetDistill(name)
+		if err != nil { return nil, err }
+		return algo.Distill(input, params, llm)
+	case "synthetic":
+		algo, err := GetSynthetic(name)
+		if err != nil { return nil, err }
+		out, _, err := Synthesize(algo, input, params, llm)
+		return out, err
+	}
+	return nil, fmt.Errorf("unknown algorithm kind '%s'", kind)
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func countRecords(path string) (int, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return 0, err }
+	defer in.Close()
+	sc := jsonl.NewScanner(in)
+	n := 0
+	for sc.Scan() { if len(bytes.TrimSpace(sc.Bytes())) > 0 { n++ } }
+	return n, sc.Err()
+}
+
+func (m *JobManager) Submit(req model.JobRequest) (model.Job, error) {
+	if req.Kind == "pipeline" {
+		var p *Pipeline
+		var err error
+		switch {
+		case req.Pipeline != nil: p, err = CompilePipeline(*req.Pipeline)
+		case req.PipelinePath != "":
+			var path string
+			if path, err = ResolveInputPath(req.PipelinePath); err == nil { p, err = LoadPipelineFile(path) }
+		default: err = errors.New("pipeline job requires a pipeline spec")
+		}
+		if err != nil { return model.Job{}, err }
+		req.Pipeline = &p.Spec
+	} else if err := ValidateAlgorithm(req.Kind, req.Algorithm); err != nil {
+		return model.Job{}, err
+	} else if err := validateJobAlgorithm(req.Kind, req.Algorithm, req.Params); err != nil {
+		return model.Job{}, err
+	}
+	if err := checkSecretParams(req.Params); err != nil { return model.Job{}, err }
+	if len(req.Inputs) == 0 && req.InputPath == "" { return model.Job{}, errors.New("job has no inputs") }
+
+	job := model.Job{ID: newJobID(), Status: JobQueued, Request: req, CreatedAt: time.Now()}
+	if len(req.Inputs) > 0 {
+		job.Request.Inputs = nil
+		job.Request.InputPath = m.path(job.ID, ".input.jsonl")
+		if err := writeJSONL(job.Request.InputPath, req.Inputs); err != nil { return model.Job{}, err }
+		job.Total = len(req.Inputs)
+	} else {
+		path, err := ResolveInputPath(req.InputPath)
+		if err != nil { return model.Job{}, err }
+		if _, err := os.Stat(path); err != nil { return model.Job{}, err }
+		job.Request.InputPath = path
+	}
+
+	e := &jobEntry{job: job}
+	if err := m.save(e); err != nil { return model.Job{}, err }
+	m.mu.Lock()
+	m.jobs[job.ID] = e
+	m.mu.Unlock()
+	m.start(e)
+	return e.snapshot(), nil
+}
+
+func writeJSONL(path string, docs []model.BatchDocument) error {
+	f, err := os.Create(path)
+	if err != nil { return err }
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil { f.Close(); return err }
+	}
+	if err := bw.Flush(); err != nil { f.Close(); return err }
+	return f.Close()
+}
+
+func (m *JobManager) Get(id string) (model.Job, error) {
+	m.mu.RLock()
+	e, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok { return model.Job{}, ErrJobNotFound }
+	return e.snapshot(), nil
+}
+
+func (m *JobManager) List() []model.Job {
+	m.mu.RLock()
+	jobs := make([]model.Job, 0, len(m.jobs))
+	for _, e := range m.jobs { jobs = append(jobs, e.snapshot()) }
+	m.mu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Cancel 请求取消任务；正在执行的单条记录完成后任务才会进入 canceled 状态
+func (m *JobManager) Cancel(id string) (model.Job, error) {
+	m.mu.RLock()
+	e, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok { return model.Job{}, ErrJobNotFound }
+	e.mu.Lock()
+	if e.cancel != nil { e.cancel() }
+	e.mu.Unlock()
+	return e.snapshot(), nil
+}
+
+// OpenResults 返回任务结果文件 (JSONL)，运行中的任务返回已完成的部分结果
+func (m *JobManager) OpenResults(id string) (io.ReadCloser, model.Job, error) {
+	job, err := m.Get(id)
+	if err != nil { return nil, job, err }
+	f, err := os.Open(m.path(id, ".results.jsonl"))
+	if os.IsNotExist(err) { return io.NopCloser(bytes.NewReader(nil)), job, nil }
+	return f, job, err
+}
+
+func (e *jobEntry) snapshot() model.Job {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	job := e.job
+	job.Failures = append([]model.JobFailure(nil), e.job.Failures...)
+	return job
+}
+
+func (m *JobManager) save(e *jobEntry) error {
+	job := e.snapshot()
+	raw, err := json.MarshalIndent(job, "", "  ")
+	if err != nil { return err }
+	tmp := m.path(job.ID, ".json.tmp")
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil { return err }
+	e.mu.Lock()
+	e.lastSave = time.Now()
+	e.mu.Unlock()
+	return os.Rename(tmp, m.path(job.ID, ".json"))
+}
+
+func (m *JobManager) start(e *jobEntry) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+	go func() {
+		defer cancel()
+		m.run(ctx, e)
+	}()
+}
+
+func (m *JobManager) finish(e *jobEntry, status string, err error) {
+	now := time.Now()
+	e.mu.Lock()
+	e.job.Status, e.job.FinishedAt = status, &now
+	if err != nil { e.job.Error = err.Error() }
+	e.mu.Unlock()
+	m.save(e)
+}
+
+// loadResults 校验结果文件：截断崩溃时写了一半的尾行，并据此重建进度计数
+func loadResults(path string, job *model.Job) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil { return err }
+	defer f.Close()
+
+	job.Processed, job.Succeeded, job.Failed, job.Failures = 0, 0, 0, nil
+	br := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF { break }
+		if err != nil { return err }
+		offset += int64(len(line))
+		var r model.JobRecordResult
+		if err := json.Unmarshal(line, &r); err != nil { r.Error = "corrupt result line: " + err.Error() }
+		job.Processed++
+		if r.Error != "" {
+			job.Failed++
+			if len(job.Failures) < maxJobFailures { job.Failures = append(job.Failures, model.JobFailure{Index: r.Index, ID: r.ID, Error: r.Error}) }
+		} else {
+			job.Succeeded++
+		}
+	}
+	return f.Truncate(offset)
+}
+
+func (m *JobManager) run(ctx context.Context, e *jobEntry) {
+	select {
+	case m.slots <- struct{}{}:
+	case <-ctx.Done(): m.finish(e, JobCanceled, nil); return
+	}
+	defer func() { <-m.slots }()
+
+	resultsPath := m.path(e.job.ID, ".results.jsonl")
+	e.mu.Lock()
+	now := time.Now()
+	e.job.Status = JobRunning
+	if e.job.StartedAt == nil { e.job.StartedAt = &now }
+	err := loadResults(resultsPath, &e.job)
+	req, skip, total := e.job.Request, e.job.Processed, e.job.Total
+	e.mu.Unlock()
+	if err != nil { m.finish(e, JobFailed, err); return }
+	m.save(e)
+
+	in, err := jsonl.Open(req.InputPath)
+	if err != nil { m.finish(e, JobFailed, err); return }
+	defer in.Close()
+	// 服务端数据集的记录数在执行时另起一遍统计，不阻塞提交；统计完成前 total 为 0
+	if total == 0 {
+		go func() {
+			n, err := countRecords(req.InputPath)
+			if err != nil { return }
+			e.mu.Lock()
+			e.job.Total = n
+			e.mu.Unlock()
+		}()
+	}
+	out, err := os.OpenFile(resultsPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil { m.finish(e, JobFailed, err); return }
+	defer out.Close()
+
+	params := make(map[string]interface{}, len(req.Params)+2)
+	for k, v := range req.Params { params[k] = v }
+	params["model"], params["vllm_base_url"] = req.Model, req.VLLMBaseURL
+	if params["llm_cache"] == false { ctx = external.BypassCache(ctx) }
+	llm := m.llm.WithContext(ctx) // 取消任务时中断进行中的模型调用
+	exec := func(doc model.BatchDocument) (interface{}, error) {
+		return executeAlgorithm(req.Kind, req.Algorithm, doc.Text, params, llm)
+	}
+	// 续跑的任务同样先检查，索引或模型已不可用时整个任务失败，而不是逐条丢弃
+	if err := validateJobAlgorithm(req.Kind, req.Algorithm, params); err != nil { m.finish(e, JobFailed, err); return }
+	if req.Kind == "pipeline" {
+		p, err := CompilePipeline(*req.Pipeline)
+		if err != nil { m.finish(e, JobFailed, err); return }
+		exec = func(doc model.BatchDocument) (interface{}, error) { return p.Run(doc, llm) }
+	}
+	workers := req.Workers
+	if workers <= 0 { workers = defaultJobWorker }
+
+	bw := bufio.NewWriter(out)
+	enc := json.NewEncoder(bw)
+	err = streamOrdered(ctx, in, workers, skip,
+		func(rec jsonlRecord) model.JobRecordResult { return runRecord(exec, rec) },
+		func(r model.JobRecordResult, more bool) error {
+			if err := enc.Encode(r); err != nil { return err }
+			e.mu.Lock()
+			e.job.Processed++
+			if r.Error != "" {
+				e.job.Failed++
+				if len(e.job.Failures) < maxJobFailures { e.job.Failures = append(e.job.Failures, model.JobFailure{Index: r.Index, ID: r.ID, Error: r.Error}) }
+			} else {
+				e.job.Succeeded++
+			}
+			due := time.Since(e.lastSave) >= jobSaveInterval
+			e.mu.Unlock()
+			if due {
+				if err := bw.Flush(); err != nil { return err }
+				return m.save(e)
+			}
+			return nil
+		})
+	if ferr := bw.Flush(); err == nil { err = ferr }
+
+	switch {
+	case ctx.Err() != nil: m.finish(e, JobCanceled, nil)
+	case err != nil: m.finish(e, JobFailed, err)
+	default: m.finish(e, JobSucceeded, nil)
+	}
+}
+
+func runRecord(exec func(model.BatchDocument) (interface{}, error), rec jsonlRecord) (res model.JobRecordResult) {
+	res.Index = rec.Seq
+	var doc model.BatchDocument
+	if err := json.Unmarshal(rec.Raw, &doc); err != nil { res.Error = "invalid document: " + err.Error(); return }
+	res.ID = doc.ID
+	defer func() {
+		if p := recover(); p != nil { res.Output, res.Error = nil, fmt.Sprintf("panic: %v", p) }
+	}()
+	out, err := exec(doc)
+	res.Output = out
+	if err != nil { res.Error = err.Error() }
+	return
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"graunt/internal/model"
+	"graunt/internal/store"
+	"graunt/pkg/lm"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+var modelNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type LMTrainResult struct {
+	Name   string         `json:"name"`
+	Dir    string         `json:"dir"`
+	Models []lm.ModelInfo `json:"models"`
+}
+
+// TrainLanguageModels 用数据仓库中的参考/专家数据或 JSONL 文件训练 Kneser-Ney 模型，写入 dir/<name>/<lang>.lm
+func TrainLanguageModels(dir string, req model.LMTrainRequest) (LMTrainResult, error) {
+	if !modelNamePattern.MatchString(req.Name) || req.Name == "." || req.Name == ".." { return LMTrainResult{}, fmt.Errorf("invalid model name '%s'", req.Name) }
+	if req.Order <= 0 { req.Order = 3 }
+	t := lm.NewSetTrainer(req.Order, req.Lang)
+	switch req.Source {
+	case "reference":
+		for _, qa := range store.GlobalDataStore.GetReferenceData() { t.Add(qa.Question + "\n" + qa.Answer) }
+	case "expert":
+		for _, qa := range store.GlobalDataStore.GetExpertData() { t.Add(qa.Question + "\n" + qa.Answer) }
+	case "jsonl":
+		if req.InputPath == "" { return LMTrainResult{}, errors.New("input_path is required for source 'jsonl'") }
+		path, err := ResolveInputPath(req.InputPath)
+		if err != nil { return LMTrainResult{}, err }
+		if _, err := t.AddJSONL(path); err != nil { return LMTrainResult{}, err }
+	default:
+		return LMTrainResult{}, fmt.Errorf("unknown source '%s'", req.Source)
+	}
+	res := LMTrainResult{Name: req.Name, Dir: filepath.Join(dir, req.Name)}
+	models, err := t.Save(res.Dir, req.MinTokens)
+	if err != nil { return res, err }
+	if len(models) == 0 { return res, errors.New("no training text") }
+	res.Models = models
+	return res, nil
+}
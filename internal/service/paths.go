@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// InputDir 是请求中服务端文件路径 (input_path、pipeline_path、状态快照等) 的根目录，由 main 设为 GRAUNT_DATA_DIR/inputs。
+// 请求只能读取该目录下的文件，避免通过 API 读取服务器上的任意文件
+var InputDir = filepath.Join("data", "inputs")
+
+var ErrPathOutsideInputDir = errors.New("path is outside the input directory")
+
+// ResolveInputPath 把请求给出的路径解析到 InputDir 下：相对路径相对 InputDir，绝对路径须位于 InputDir 内，
+// 含 ".." 的路径一律拒绝；路径存在时按解析符号链接后的真实位置再检查一次
+func ResolveInputPath(p string) (string, error) {
+	if p == "" { return "", errors.New("empty path") }
+	for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+		if part == ".." { return "", fmt.Errorf("%s: %w", p, ErrPathOutsideInputDir) }
+	}
+	root, err := filepath.Abs(InputDir)
+	if err != nil { return "", err }
+	path := p
+	if !filepath.IsAbs(path) { path = filepath.Join(root, path) }
+	path = filepath.Clean(path)
+	if !within(root, path) { return "", fmt.Errorf("%s: %w", p, ErrPathOutsideInputDir) }
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil { realRoot = root }
+		if !within(realRoot, real) { return "", fmt.Errorf("%s: %w", p, ErrPathOutsideInputDir) }
+	}
+	return path, nil
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
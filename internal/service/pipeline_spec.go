@@ -0,0 +1,170 @@
+package service
+
+import (
+	"graunt/internal/external"
+	"graunt/internal/model"
+	"graunt/pkg/algorithm"
+	"graunt/pkg/rewrite"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	StageKept      = "kept"
+	StageDropped   = "dropped"
+	StageChanged   = "changed"
+	StageUnchanged = "unchanged"
+	StageGenerated = "generated"
+	StageError     = "error"
+)
+
+// Pipeline 是已按注册中心校验过的多阶段流水线，可并发地对单条记录执行
+type Pipeline struct {
+	Spec   model.PipelineSpec
+	stages []compiledStage
+}
+
+type compiledStage struct {
+	spec      model.PipelineStage
+	params    map[string]interface{}
+	filter    algorithm.FilterAlgorithm
+	rewrite   algorithm.RewriteAlgorithm
+	distill   algorithm.DistillAlgorithm
+	synthetic algorithm.SyntheticAlgorithm
+}
+
+// ParsePipelineSpec 解析 YAML 或 JSON (JSON 是 YAML 子集) 格式的流水线定义。
+// 经过一次 JSON 往返，使数字参数与 HTTP 接口一样统一为 float64，并拒绝未知字段。
+func ParsePipelineSpec(data []byte) (model.PipelineSpec, error) {
+	var spec model.PipelineSpec
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil { return spec, fmt.Errorf("invalid pipeline spec: %v", err) }
+	normalized, err := json.Marshal(raw)
+	if err != nil { return spec, fmt.Errorf("invalid pipeline spec: %v", err) }
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil { return spec, fmt.Errorf("invalid pipeline spec: %v", err) }
+	return spec, nil
+}
+
+func LoadPipelineFile(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	spec, err := ParsePipelineSpec(data)
+	if err != nil { return nil, err }
+	return CompilePipeline(spec)
+}
+
+// checkSecretParams 拒绝携带口令的参数：流水线定义与任务参数会落盘并原样返回给客户端
+func checkSecretParams(params map[string]interface{}) error {
+	if _, ok := params[rewrite.PassphraseParam]; ok {
+		return fmt.Errorf("%s must not be sent in params; set %s or %s on the server", rewrite.PassphraseParam, rewrite.PassphraseEnv, rewrite.PassphraseFileEnv)
+	}
+	return nil
+}
+
+// CompilePipeline 对照注册中心校验所有阶段，一次性返回全部错误
+func CompilePipeline(spec model.PipelineSpec) (*Pipeline, error) {
+	if len(spec.Stages) == 0 { return nil, errors.New("pipeline has no stages") }
+	p := &Pipeline{Spec: spec}
+	p.Spec.Stages = append([]model.PipelineStage(nil), spec.Stages...)
+	seen := make(map[string]bool)
+	var errs []error
+	for i, st := range spec.Stages {
+		if st.Name == "" { st.Name = st.Algorithm }
+		if seen[st.Name] { st.Name = fmt.Sprintf("%s#%d", st.Name, i) }
+		seen[st.Name] = true
+		p.Spec.Stages[i] = st
+
+		if err := checkSecretParams(st.Params); err != nil { errs = append(errs, fmt.Errorf("stage %d (%s): %v", i, st.Name, err)); continue }
+		cs := compiledStage{spec: st, params: make(map[string]interface{}, len(st.Params)+2)}
+		for k, v := range st.Params { cs.params[k] = v }
+		cs.params["model"], cs.params["vllm_base_url"] = spec.Model, spec.VLLMBaseURL
+		if st.Model != "" { cs.params["model"] = st.Model }
+		if st.VLLMBaseURL != "" { cs.params["vllm_base_url"] = st.VLLMBaseURL }
+
+		var err error
+		switch st.Kind {
+		case "filter":
+			if cs.filter, err = GetFilter(st.Algorithm); err == nil { err = ValidateFilters([]algorithm.FilterAlgorithm{cs.filter}, cs.params) }
+		case "rewrite":
+			if cs.rewrite, err = GetRewrite(st.Algorithm); err == nil { err = ValidateRewrite(cs.rewrite, cs.params) }
+		case "distill": cs.distill, err = GetDistill(st.Algorithm)
+		case "synthetic": cs.synthetic, err = GetSynthetic(st.Algorithm)
+		default: err = fmt.Errorf("unknown algorithm kind '%s'", st.Kind)
+		}
+		if err != nil { errs = append(errs, fmt.Errorf("stage %d (%s): %v", i, st.Name, err)); continue }
+		p.stages = append(p.stages, cs)
+	}
+	if len(errs) > 0 { return nil, errors.Join(errs...) }
+	return p, nil
+}
+
+// Run 让一条记录依次经过各阶段：filter 决定去留，rewrite 替换文本，
+// distill/synthetic 的字符串输出替换文本，其余结构化输出记录在阶段轨迹里。
+// filter 附加的元数据 (如 lang) 会合并进后续各阶段的参数。
+func (p *Pipeline) Run(doc model.BatchDocument, llm external.LLM) (model.PipelineRecordResult, error) {
+	res := model.PipelineRecordResult{ID: doc.ID, Kept: true, Text: doc.Text, Stages: make([]model.StageTrace, 0, len(p.stages))}
+	for _, st := range p.stages {
+		trace := model.StageTrace{Stage: st.spec.Name, Kind: st.spec.Kind, Algorithm: st.spec.Algorithm}
+		var out interface{}
+		var meta map[string]interface{}
+		var err error
+		switch {
+		case st.filter != nil:
+			keep, reason, meta, err := evaluateFilter(st.filter, res.Text, withMetadata(st.params, res.Metadata))
+			if err != nil {
+				trace.Action, trace.Reason = StageError, err.Error()
+				res.Kept, res.DroppedBy, res.Error = false, st.spec.Name, err.Error()
+				res.Stages = append(res.Stages, trace)
+				return res, fmt.Errorf("stage %s: %v", st.spec.Name, err)
+			}
+			res.Metadata = mergeMetadata(res.Metadata, meta)
+			trace.Action, trace.Reason, trace.Metadata = StageKept, reason, meta
+			if !keep {
+				trace.Action = StageDropped
+				res.Kept, res.DroppedBy = false, st.spec.Name
+				res.Stages = append(res.Stages, trace)
+				return res, nil
+			}
+			res.Stages = append(res.Stages, trace)
+			continue
+		case st.rewrite != nil: out, meta, err = RewriteText(st.rewrite, res.Text, withMetadata(st.params, res.Metadata), llm)
+		case st.distill != nil: out, err = st.distill.Distill(res.Text, withMetadata(st.params, res.Metadata), llm)
+		case st.synthetic != nil: out, meta, err = Synthesize(st.synthetic, res.Text, withMetadata(st.params, res.Metadata), llm)
+		}
+		var contaminated *ContaminationError
+		if errors.As(err, &contaminated) {
+			trace.Action, trace.Reason, trace.Metadata = StageDropped, contaminated.Reason, contaminated.Metadata
+			res.Metadata = mergeMetadata(res.Metadata, contaminated.Metadata)
+			res.Kept, res.DroppedBy = false, st.spec.Name
+			res.Stages = append(res.Stages, trace)
+			return res, nil
+		}
+		if err != nil {
+			trace.Action, trace.Reason = StageError, err.Error()
+			res.Kept, res.DroppedBy, res.Error = false, st.spec.Name, err.Error()
+			res.Stages = append(res.Stages, trace)
+			return res, fmt.Errorf("stage %s: %v", st.spec.Name, err)
+		}
+		res.Metadata = mergeMetadata(res.Metadata, meta)
+		trace.Metadata = meta
+		if text, ok := out.(string); ok {
+			trace.Action = StageUnchanged
+			if text != res.Text {
+				trace.Action = StageChanged
+				res.Text = text
+				res.ChangedBy = append(res.ChangedBy, st.spec.Name)
+			}
+		} else {
+			trace.Action, trace.Output = StageGenerated, out
+		}
+		res.Stages = append(res.Stages, trace)
+	}
+	return res, nil
+}
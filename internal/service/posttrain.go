@@ -3,6 +3,7 @@ package service
 import (
 	"graunt/internal/external"
 	"graunt/internal/model"
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -33,7 +34,7 @@ func (s *PosttrainService) EvolInstruct(req model.EvolInstructRequest) (string,
 	}
 
 	resp, err := s.VLLMClient.CallChatCompletion(req.VLLMBaseURL, vllmReq)
-	if err != nil || len(resp.Choices) == 0 { return "", fmt.Errorf("evol failed") }
+	if err != nil { return "", fmt.Errorf("evol failed: %w", err) }
 	return resp.Choices[0].Message.Content, nil
 }
 
@@ -72,8 +73,8 @@ func (s *PosttrainService) GenerateDPOPairs(req model.DPOConstructRequest) (*mod
 
 	wg.Wait()
 
-	if errChosen != nil || errRejected != nil {
-		return nil, fmt.Errorf("failed to generate pairs")
+	if err := errors.Join(errChosen, errRejected); err != nil {
+		return nil, fmt.Errorf("failed to generate pairs: %w", err)
 	}
 
 	return &model.DPOPair{Prompt: req.Prompt, Chosen: chosen, Rejected: rejected}, nil
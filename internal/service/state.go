@@ -0,0 +1,67 @@
+package service
+
+import (
+	"graunt/pkg/algorithm"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+func statefulFilters() map[string]algorithm.StatefulFilter {
+	GlobalRegistry.mu.RLock(); defer GlobalRegistry.mu.RUnlock()
+	out := make(map[string]algorithm.StatefulFilter)
+	for name, algo := range GlobalRegistry.filters {
+		if sf, ok := algo.(algorithm.StatefulFilter); ok { out[name] = sf }
+	}
+	return out
+}
+
+func getStatefulFilter(name string) (algorithm.StatefulFilter, error) {
+	algo, err := GetFilter(name)
+	if err != nil { return nil, err }
+	sf, ok := algo.(algorithm.StatefulFilter)
+	if !ok { return nil, fmt.Errorf("filter algorithm '%s' has no persistent state", name) }
+	return sf, nil
+}
+
+// LoadFilterStates 启动时从 dir/<filter> 恢复所有有状态过滤器
+func LoadFilterStates(dir string) error {
+	for name, sf := range statefulFilters() {
+		if err := sf.LoadState(filepath.Join(dir, name)); err != nil { return fmt.Errorf("load %s state: %v", name, err) }
+	}
+	return nil
+}
+
+func SaveFilterState(dir, name string) error {
+	sf, err := getStatefulFilter(name)
+	if err != nil { return err }
+	return sf.SaveState(filepath.Join(dir, name))
+}
+
+func MergeFilterState(name, path string, params map[string]interface{}) (interface{}, error) {
+	sf, err := getStatefulFilter(name)
+	if err != nil { return nil, err }
+	if path, err = ResolveInputPath(path); err != nil { return nil, err }
+	if params == nil { params = make(map[string]interface{}) }
+	return sf.MergeState(path, params)
+}
+
+// SaveFilterStates 把所有有状态过滤器写入 dir，单个失败不影响其余过滤器
+func SaveFilterStates(dir string) error {
+	var errs []error
+	for name, sf := range statefulFilters() {
+		if err := sf.SaveState(filepath.Join(dir, name)); err != nil { errs = append(errs, fmt.Errorf("save %s state: %v", name, err)) }
+	}
+	return errors.Join(errs...)
+}
+
+// AutosaveFilterStates 周期性地把有状态过滤器写入 dir
+func AutosaveFilterStates(dir string, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := SaveFilterStates(dir); err != nil { log.Printf("autosave failed: %v", err) }
+		}
+	}()
+}
@@ -2,25 +2,59 @@ package main
 
 import (
 	"graunt/internal/api"
+	"graunt/internal/external"
 	"graunt/internal/service"
 	
+	"graunt/pkg/decontam"
 	"graunt/pkg/distill"
 	"graunt/pkg/filter"
 	"graunt/pkg/rewrite"
 	"graunt/pkg/synthetic"
+	"graunt/pkg/textnorm"
 	
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 func main() {
+	dataDir := os.Getenv("GRAUNT_DATA_DIR")
+	if dataDir == "" { dataDir = "data" }
+	lmDir := filepath.Join(dataDir, "lm")
+	clfDir := filepath.Join(dataDir, "classifiers")
+	vaultDir := filepath.Join(dataDir, "pii_vaults")
+	substrDir := filepath.Join(dataDir, "exactsubstr")
+	langidDir := filepath.Join(dataDir, "langid")
+	service.InputDir = filepath.Join(dataDir, "inputs")
+	textnorm.DictDir = filepath.Join(dataDir, "t2s")
+
 	service.RegisterFilter(&filter.EntropyFilter{})
 	service.RegisterFilter(&filter.NGramFilter{})
 	service.RegisterFilter(filter.NewMinHashFilter())
+	service.RegisterFilter(filter.NewSimHashFilter())
 	service.RegisterFilter(&filter.ReadabilityFilter{})
+	service.RegisterFilter(filter.NewExactSubstrFilter(substrDir))
+	service.RegisterFilter(filter.NewLangIDFilter(langidDir))
+	for _, h := range filter.HeuristicFilters() { service.RegisterFilter(h) }
+	service.RegisterFilter(&filter.GopherQualityFilter{})
+	service.RegisterFilter(filter.NewPerplexityFilter(lmDir))
+	service.RegisterFilter(filter.NewClassifierFilter(clfDir))
+	service.RegisterFilter(filter.NewDecontamFilter(decontam.DefaultN))
+	service.RegisterFilter(filter.NewSecretsFilter())
 	service.RegisterRewrite(&rewrite.TextbookRewrite{})
-	service.RegisterRewrite(&rewrite.PIIMaskRewrite{})
+	service.RegisterRewrite(rewrite.NewPIIMaskRewrite(vaultDir))
+	service.RegisterRewrite(rewrite.NewExactSubstrRewrite(substrDir))
+	service.RegisterRewrite(rewrite.NewLineCleanRewrite())
+	service.RegisterRewrite(rewrite.NewSecretsRedactRewrite())
+	service.RegisterRewrite(rewrite.NewExtractRewrite())
+	service.RegisterRewrite(rewrite.NewNormalizeRewrite())
 	service.RegisterDistill(&distill.StandardDistill{})
 	service.RegisterSynthetic(&synthetic.FewshotSynthetic{})
 	service.RegisterSynthetic(&synthetic.EvolInstruct{})
@@ -29,11 +63,80 @@ func main() {
 
 	mux := http.NewServeMux()
 	handler := api.NewAPIHandler()
+
+	// 后端池：GRAUNT_LLM_BACKENDS 指定配置文件，默认 data/backends.yaml (不存在时不启用)
+	backends := os.Getenv("GRAUNT_LLM_BACKENDS")
+	if backends == "" {
+		if _, err := os.Stat(filepath.Join(dataDir, "backends.yaml")); err == nil { backends = filepath.Join(dataDir, "backends.yaml") }
+	}
+	if backends != "" {
+		cfg, err := external.LoadPoolConfig(backends)
+		if err != nil { log.Fatalf("Backend pool config failed: %v", err) }
+		handler.VLLMClient.Pool = external.NewPool(cfg)
+		handler.VLLMClient.Pool.Start(context.Background())
+	}
+
+	// 模型调用链：响应缓存 → 限速 → 统计 → VLLMClient，缓存命中不占用限速额度
+	var mws []external.Middleware
+	// 响应缓存默认开启 (GRAUNT_LLM_CACHE=off 关闭)，保存在 data/llm_cache，默认保留 7 天、最多 1 GiB
+	if os.Getenv("GRAUNT_LLM_CACHE") != "off" {
+		ttl, maxMB := 7*24*time.Hour, int64(1024)
+		if v := os.Getenv("GRAUNT_LLM_CACHE_TTL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil { log.Fatalf("Invalid GRAUNT_LLM_CACHE_TTL: %q", v) }
+			ttl = d
+		}
+		if v := os.Getenv("GRAUNT_LLM_CACHE_MAX_MB"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 { log.Fatalf("Invalid GRAUNT_LLM_CACHE_MAX_MB: %q", v) }
+			maxMB = n
+		}
+		store, err := external.OpenDiskCache(filepath.Join(dataDir, "llm_cache"), ttl, maxMB<<20)
+		if err != nil { log.Fatalf("LLM cache init failed: %v", err) }
+		handler.LLMCache = external.NewResponseCache(store)
+		handler.LLMCache.CacheSampled = os.Getenv("GRAUNT_LLM_CACHE_SAMPLED") == "1"
+		mws = append(mws, handler.LLMCache.Middleware())
+	}
+	// GRAUNT_LLM_RPS 限制对模型服务的总调用速率
+	if v := os.Getenv("GRAUNT_LLM_RPS"); v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil || rps <= 0 { log.Fatalf("Invalid GRAUNT_LLM_RPS: %q", v) }
+		mws = append(mws, external.RateLimit(rps, int(rps)+1))
+	}
+	handler.LLM = external.Wrap(handler.VLLMClient, append(mws, external.Instrument(handler.LLMMetrics))...)
+
+	// 过滤器状态须在任务管理器恢复未完成任务之前载入，否则续跑的任务会在空状态上执行
+	handler.StateDir = filepath.Join(dataDir, "filters")
+	if err := service.LoadFilterStates(handler.StateDir); err != nil { log.Fatalf("Filter state restore failed: %v", err) }
+	service.AutosaveFilterStates(handler.StateDir, 5*time.Minute)
+
+	jobs, err := service.NewJobManager(filepath.Join(dataDir, "jobs"), handler.LLM, 2)
+	if err != nil { log.Fatalf("Job manager init failed: %v", err) }
+	handler.Jobs = jobs
+
+	handler.LMDir = lmDir
+	handler.ClfDir = clfDir
+	handler.VaultDir = vaultDir
 	handler.RegisterRoutes(mux)
 
 	port := ":8080"
+	srv := &http.Server{Addr: port, Handler: mux}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
 	fmt.Printf("Data Flywheel Super-Registry API started on http://localhost%s\n", port)
-	if err := http.ListenAndServe(port, mux); err != nil {
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed: %v", err)
 	}
+	<-drained
+	// 退出前保存过滤器状态，避免丢失最近一次自动保存之后的数据
+	if err := service.SaveFilterStates(handler.StateDir); err != nil { log.Printf("Filter state save failed: %v", err) }
 }
\ No newline at end of file
@@ -11,15 +11,55 @@ type FilterAlgorithm interface {
 
 type RewriteAlgorithm interface {
 	Name() string
-	Rewrite(text string, params map[string]interface{}, vllm *external.VLLMClient) (string, error)
+	Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error)
 }
 
 type DistillAlgorithm interface {
 	Name() string
-	Distill(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error)
+	Distill(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error)
 }
 
 type SyntheticAlgorithm interface {
 	Name() string
-	Synthesize(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error)
-}
\ No newline at end of file
+	Synthesize(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error)
+}
+
+// StatsProvider 是可选接口，实现后可通过 GET /api/filters/{name}/stats 查看算法内部状态
+type StatsProvider interface {
+	Stats() interface{}
+}
+
+// StatefulFilter 是可选接口，实现后过滤器状态可落盘、重启后恢复，并合并其他节点导出的快照
+type StatefulFilter interface {
+	SaveState(dir string) error
+	LoadState(dir string) error
+	MergeState(path string, params map[string]interface{}) (interface{}, error)
+}
+
+// MetadataFilter 是可选接口，过滤器在判定的同时为文档附加元数据 (如语种)。
+// 元数据写入结果，并合并进同一文档后续阶段的参数，后续算法可据此 (如 params["lang"]) 切换行为
+type MetadataFilter interface {
+	EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{})
+}
+
+// ValidatingFilter 是可选接口，在批处理、流水线或任务开始前检查一次参数与所需资源 (索引、模型)，
+// 返回错误时整批拒绝，而不是把每篇文档都判为丢弃
+type ValidatingFilter interface {
+	Validate(params map[string]interface{}) error
+}
+
+// CheckedFilter 是可选接口，单篇文档无法判定 (如按语种选用的模型加载失败) 时返回 error，
+// 计入批处理与任务的错误数，不作为丢弃判定
+type CheckedFilter interface {
+	EvaluateChecked(text string, params map[string]interface{}) (bool, string, map[string]interface{}, error)
+}
+
+// ValidatingRewrite 是可选接口，作用同 ValidatingFilter
+type ValidatingRewrite interface {
+	Validate(params map[string]interface{}) error
+}
+
+// MetadataRewrite 是可选接口，改写算法在返回新文本的同时附加元数据 (如删除的字符数)，合并方式同 MetadataFilter
+type MetadataRewrite interface {
+	RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error)
+}
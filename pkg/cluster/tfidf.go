@@ -1,8 +1,8 @@
 package cluster
 
 import (
+	"graunt/pkg/nlp"
 	"math"
-	"strings"
 )
 
 type Vector map[string]float64
@@ -14,7 +14,7 @@ func BuildTFIDF(texts []string) []Vector {
 
 	for i, text := range texts {
 		tfList[i] = make(map[string]float64)
-		words := strings.Fields(strings.ToLower(text))
+		words := nlp.Analyze(text).Tokens()
 		totalWords := float64(len(words))
 		
 		wordSet := make(map[string]bool)
@@ -0,0 +1,168 @@
+package decontam
+
+import (
+	"graunt/pkg/nlp"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultN 沿用 GPT-3 / PaLM 去污染的 13-gram
+const DefaultN = 13
+
+// Item 是一条基准题目；Grams 为其去重后的 n-gram 数，Len 为 gram 的 token 数 (短题小于 N，旧快照中为 0)
+type Item struct {
+	Benchmark string
+	ID        string
+	Grams     int
+	Len       int
+}
+
+type Match struct {
+	Benchmark string  `json:"benchmark"`
+	ID        string  `json:"id"`
+	Overlap   int     `json:"overlap"`  // 命中的 n-gram 数
+	Coverage  float64 `json:"coverage"` // 命中数占该题 n-gram 数的比例
+}
+
+// Index 把基准题目的 token n-gram 哈希映射到题目。token 由 nlp.Tokens 给出 (小写，中日韩按字)，
+// 不足 N 个 token 但不少于 MinTokens 的短题以整题作为一个 gram，查询时按相同长度匹配
+type Index struct {
+	N         int
+	MinTokens int
+	mu        sync.RWMutex
+	items     []Item
+	grams     map[uint64][]int32
+	lengths   map[int]bool
+	version   uint64 // 每次修改递增，与 saved 比较判断是否需要落盘
+	saved     uint64
+}
+
+func NewIndex(n int) *Index {
+	if n <= 0 { n = DefaultN }
+	return &Index{N: n, MinTokens: min(8, n), grams: make(map[uint64][]int32), lengths: map[int]bool{n: true}}
+}
+
+func hashGram(toks []string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(toks, " ")))
+	return h.Sum64()
+}
+
+func gramsOf(toks []string, n int) []uint64 {
+	if len(toks) < n { return nil }
+	out := make([]uint64, 0, len(toks)-n+1)
+	for i := 0; i+n <= len(toks); i++ { out = append(out, hashGram(toks[i:i+n])) }
+	return out
+}
+
+// entry 是切分好、待写入索引的一条题目
+type entry struct {
+	item  Item
+	grams map[uint64]bool
+}
+
+// prepare 切分题目并计算 n-gram，不访问索引内容，可以在锁外调用；过短的题目返回 false
+func (x *Index) prepare(benchmark, id, text string) (entry, bool) {
+	toks := nlp.Tokens(text)
+	n := x.N
+	if len(toks) < n { n = len(toks) }
+	if n < x.MinTokens { return entry{}, false }
+	uniq := make(map[uint64]bool)
+	for _, g := range gramsOf(toks, n) { uniq[g] = true }
+	return entry{item: Item{Benchmark: benchmark, ID: id, Grams: len(uniq), Len: n}, grams: uniq}, true
+}
+
+// insert 写入一条题目，调用方持有写锁
+func (x *Index) insert(e entry) {
+	ref := int32(len(x.items))
+	x.items = append(x.items, e.item)
+	for g := range e.grams { x.grams[g] = append(x.grams[g], ref) }
+	x.lengths[e.item.Len] = true
+}
+
+// Add 索引一条题目，过短的题目被跳过并返回 false
+func (x *Index) Add(benchmark, id, text string) bool {
+	e, ok := x.prepare(benchmark, id, text)
+	if !ok { return false }
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.insert(e)
+	x.version++
+	return true
+}
+
+// Remove 删除某个基准的全部题目，返回删除条数
+func (x *Index) Remove(benchmark string) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	removed := x.remove(benchmark)
+	if removed > 0 { x.version++ }
+	return removed
+}
+
+// remove 删除某个基准的题目并重建 lengths，调用方持有写锁
+func (x *Index) remove(benchmark string) int {
+	remap := make([]int32, len(x.items))
+	kept := x.items[:0]
+	removed := 0
+	for i, it := range x.items {
+		if it.Benchmark == benchmark { remap[i] = -1; removed++; continue }
+		remap[i] = int32(len(kept))
+		kept = append(kept, it)
+	}
+	if removed == 0 { return 0 }
+	x.items = kept
+	for g, refs := range x.grams {
+		out := refs[:0]
+		for _, r := range refs {
+			if remap[r] >= 0 { out = append(out, remap[r]) }
+		}
+		if len(out) == 0 { delete(x.grams, g) } else { x.grams[g] = out }
+	}
+	// 旧快照的题目没有记录 Len，无法判断哪些长度仍在使用，保留原有 lengths
+	lengths := map[int]bool{x.N: true}
+	for _, it := range x.items {
+		if it.Len == 0 { return removed }
+		lengths[it.Len] = true
+	}
+	x.lengths = lengths
+	return removed
+}
+
+// Check 返回与文本共享 n-gram 的题目，按命中数降序；benchmarks 非空时只看其中的基准
+func (x *Index) Check(text string, benchmarks map[string]bool) []Match {
+	toks := nlp.Tokens(text)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	hits := make(map[int32]int)
+	for n := range x.lengths {
+		seen := make(map[uint64]bool)
+		for _, g := range gramsOf(toks, n) {
+			if seen[g] { continue }
+			seen[g] = true
+			for _, r := range x.grams[g] { hits[r]++ }
+		}
+	}
+	out := make([]Match, 0, len(hits))
+	for r, c := range hits {
+		it := x.items[r]
+		if len(benchmarks) > 0 && !benchmarks[it.Benchmark] { continue }
+		out = append(out, Match{Benchmark: it.Benchmark, ID: it.ID, Overlap: c, Coverage: float64(c) / float64(it.Grams)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Overlap != out[j].Overlap { return out[i].Overlap > out[j].Overlap }
+		return out[i].Benchmark+out[i].ID < out[j].Benchmark+out[j].ID
+	})
+	return out
+}
+
+// Benchmarks 返回各基准已索引的题目数
+func (x *Index) Benchmarks() map[string]int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	out := make(map[string]int)
+	for _, it := range x.items { out[it.Benchmark]++ }
+	return out
+}
@@ -0,0 +1,131 @@
+package decontam
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const indexVersion = 1
+
+type snapshot struct {
+	Version   int
+	N         int
+	MinTokens int
+	Items     []Item
+	Grams     map[uint64][]int32
+	Lengths   []int
+}
+
+// Dirty 报告自上次 Save/Load 以来索引是否有变化
+func (x *Index) Dirty() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.version != x.saved
+}
+
+// Save 以 zstd 压缩的 gob 格式原子写入索引
+func (x *Index) Save(path string) error {
+	x.mu.RLock()
+	version := x.version
+	snap := snapshot{Version: indexVersion, N: x.N, MinTokens: x.MinTokens, Items: x.items, Grams: x.grams}
+	for n := range x.lengths { snap.Lengths = append(snap.Lengths, n) }
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { x.mu.RUnlock(); return err }
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".decontam-*")
+	if err != nil { x.mu.RUnlock(); return err }
+	zw, err := zstd.NewWriter(tmp)
+	if err == nil {
+		if err = gob.NewEncoder(zw).Encode(snap); err == nil { err = zw.Close() } else { zw.Close() }
+	}
+	x.mu.RUnlock()
+	if err != nil { tmp.Close(); os.Remove(tmp.Name()); return err }
+	if err := tmp.Close(); err != nil { os.Remove(tmp.Name()); return err }
+	if err := os.Rename(tmp.Name(), path); err != nil { return err }
+	x.mu.Lock()
+	x.saved = version
+	x.mu.Unlock()
+	return nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	var snap snapshot
+	f, err := os.Open(path)
+	if err != nil { return snap, err }
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil { return snap, err }
+	defer zr.Close()
+	if err := gob.NewDecoder(zr).Decode(&snap); err != nil { return snap, fmt.Errorf("%s: invalid decontamination index: %v", path, err) }
+	if snap.Version != indexVersion { return snap, fmt.Errorf("%s: unsupported decontamination index version %d", path, snap.Version) }
+	if err := snap.validate(); err != nil { return snap, fmt.Errorf("%s: invalid decontamination index: %v", path, err) }
+	return snap, nil
+}
+
+// validate 检查快照内部引用，避免损坏的文件在 Merge 或之后的 Check 中越界
+func (snap snapshot) validate() error {
+	if snap.N <= 0 { return fmt.Errorf("n-gram size %d", snap.N) }
+	for _, n := range snap.Lengths {
+		if n <= 0 || n > snap.N { return fmt.Errorf("gram length %d outside 1-%d", n, snap.N) }
+	}
+	for i, it := range snap.Items {
+		if it.Grams <= 0 { return fmt.Errorf("item %d (%s/%s) has no n-grams", i, it.Benchmark, it.ID) }
+	}
+	for _, refs := range snap.Grams {
+		for _, r := range refs {
+			if r < 0 || int(r) >= len(snap.Items) { return fmt.Errorf("gram refers to item %d of %d", r, len(snap.Items)) }
+		}
+	}
+	return nil
+}
+
+// Load 用快照替换索引内容
+func (x *Index) Load(path string) error {
+	snap, err := readSnapshot(path)
+	if err != nil { return err }
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.N, x.MinTokens, x.items, x.grams = snap.N, snap.MinTokens, snap.Items, snap.Grams
+	if x.grams == nil { x.grams = make(map[uint64][]int32) }
+	x.lengths = map[int]bool{x.N: true}
+	for _, n := range snap.Lengths { x.lengths[n] = true }
+	x.version++
+	x.saved = x.version
+	return nil
+}
+
+type MergeStats struct {
+	Added   map[string]int `json:"added"`   // 新并入的基准及题目数
+	Skipped []string       `json:"skipped"` // 本地已有而跳过的基准
+}
+
+// Merge 并入其他节点导出的索引快照，本地已存在的基准保持不变
+func (x *Index) Merge(path string) (MergeStats, error) {
+	stats := MergeStats{Added: make(map[string]int)}
+	snap, err := readSnapshot(path)
+	if err != nil { return stats, err }
+	if snap.N != x.N { return stats, fmt.Errorf("%s: n-gram size %d does not match local index (%d)", path, snap.N, x.N) }
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	local := make(map[string]bool)
+	for _, it := range x.items { local[it.Benchmark] = true }
+	skipped := make(map[string]bool)
+	remap := make([]int32, len(snap.Items))
+	for i, it := range snap.Items {
+		if local[it.Benchmark] { remap[i] = -1; skipped[it.Benchmark] = true; continue }
+		remap[i] = int32(len(x.items))
+		x.items = append(x.items, it)
+		stats.Added[it.Benchmark]++
+	}
+	for g, refs := range snap.Grams {
+		for _, r := range refs {
+			if remap[r] >= 0 { x.grams[g] = append(x.grams[g], remap[r]) }
+		}
+	}
+	for _, n := range snap.Lengths { x.lengths[n] = true }
+	for b := range skipped { stats.Skipped = append(stats.Skipped, b) }
+	if len(stats.Added) > 0 { x.version++ }
+	return stats, nil
+}
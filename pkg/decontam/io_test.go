@@ -0,0 +1,24 @@
+package decontam
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeRejectsBadRefs(t *testing.T) {
+	dir := t.TempDir()
+	for name, snap := range map[string]snapshot{
+		"ref past items": {N: 13, Items: []Item{{Benchmark: "b", ID: "1", Grams: 1, Len: 13}}, Grams: map[uint64][]int32{1: {5}}},
+		"negative ref":   {N: 13, Items: []Item{{Benchmark: "b", ID: "1", Grams: 1, Len: 13}}, Grams: map[uint64][]int32{1: {-1}}},
+		"zero n":         {N: 0},
+		"zero grams":     {N: 13, Items: []Item{{Benchmark: "b", ID: "1"}}},
+	} {
+		src := NewIndex(13)
+		src.N, src.items, src.grams = snap.N, snap.Items, snap.Grams
+		if src.grams == nil { src.grams = map[uint64][]int32{} }
+		path := filepath.Join(dir, "bad.dci")
+		if err := src.Save(path); err != nil { t.Fatal(err) }
+		if _, err := NewIndex(13).Merge(path); err == nil { t.Errorf("%s: Merge accepted the snapshot", name) }
+		if err := NewIndex(13).Load(path); err == nil { t.Errorf("%s: Load accepted the snapshot", name) }
+	}
+}
@@ -0,0 +1,155 @@
+package decontam
+
+import (
+	"graunt/pkg/jsonl"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// idFields 按优先级作为题目编号，不计入题目文本
+var idFields = []string{"id", "task_id", "question_id", "idx", "_id"}
+
+type Record struct {
+	ID   string
+	Text string
+}
+
+type ReadOptions struct {
+	Fields   []string // 拼成题目文本的字段；为空时使用除编号外的全部字符串字段 (含嵌套)
+	NoHeader bool     // CSV 无表头时 (如 MMLU) 以列序号 "0","1",... 作为字段名
+}
+
+// ReadFile 读取 JSONL (GSM8K / HumanEval 等) 或 CSV (MMLU / C-Eval 等) 基准文件，可 .gz/.zst 压缩
+func ReadFile(path string, opts ReadOptions) ([]Record, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return nil, err }
+	defer in.Close()
+	base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".zst"), ".zstd")
+	if strings.HasSuffix(strings.ToLower(base), ".csv") { return readCSV(in, path, opts) }
+	return readJSONL(in, path, opts)
+}
+
+func readJSONL(in io.Reader, path string, opts ReadOptions) ([]Record, error) {
+	sc := jsonl.NewScanner(in)
+	var out []Record
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 { continue }
+		var row map[string]interface{}
+		if err := json.Unmarshal(raw, &row); err != nil { return out, fmt.Errorf("%s:%d: %v", path, line, err) }
+		out = append(out, buildRecord(row, line, opts.Fields))
+	}
+	return out, sc.Err()
+}
+
+func readCSV(in io.Reader, path string, opts ReadOptions) ([]Record, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	var header []string
+	var out []Record
+	for line := 1; ; line++ {
+		cols, err := r.Read()
+		if err == io.EOF { break }
+		if err != nil { return out, fmt.Errorf("%s: %v", path, err) }
+		if header == nil && !opts.NoHeader { header = cols; continue }
+		row := make(map[string]interface{}, len(cols))
+		for i, c := range cols {
+			key := strconv.Itoa(i)
+			if i < len(header) { key = header[i] }
+			row[key] = c
+		}
+		out = append(out, buildRecord(row, line, opts.Fields))
+	}
+	return out, nil
+}
+
+func buildRecord(row map[string]interface{}, line int, fields []string) Record {
+	rec := Record{ID: "#" + strconv.Itoa(line)}
+	for _, k := range idFields {
+		if v, ok := row[k]; ok && v != nil { rec.ID = fmt.Sprint(v); break }
+	}
+	var parts []string
+	if len(fields) > 0 {
+		for _, k := range fields { parts = collectStrings(row[k], parts) }
+	} else {
+		keys := make([]string, 0, len(row))
+		for k := range row { keys = append(keys, k) }
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !isIDField(k) { parts = collectStrings(row[k], parts) }
+		}
+	}
+	rec.Text = strings.Join(parts, "\n")
+	return rec
+}
+
+func isIDField(k string) bool {
+	for _, f := range idFields {
+		if k == f { return true }
+	}
+	return false
+}
+
+// collectStrings 递归收集 JSON 值中的全部字符串，供嵌套的选项列表或结构化合成输出使用
+func collectStrings(v interface{}, out []string) []string {
+	switch x := v.(type) {
+	case string:
+		if strings.TrimSpace(x) != "" { out = append(out, x) }
+	case []interface{}:
+		for _, e := range x { out = collectStrings(e, out) }
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x { keys = append(keys, k) }
+		sort.Strings(keys)
+		for _, k := range keys { out = collectStrings(x[k], out) }
+	}
+	return out
+}
+
+// Strings 返回任意可 JSON 序列化的值 (如合成算法的结构化输出) 中的全部字符串
+func Strings(v interface{}) []string {
+	if s, ok := v.(string); ok { return []string{s} }
+	raw, err := json.Marshal(v)
+	if err != nil { return nil }
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil { return nil }
+	return collectStrings(generic, nil)
+}
+
+type IngestStats struct {
+	Benchmark string `json:"benchmark"`
+	Records   int    `json:"records"`
+	Indexed   int    `json:"indexed"`
+	Replaced  int    `json:"replaced"` // 同名基准原有的题目数
+	TooShort  int    `json:"too_short"`
+}
+
+// Ingest 读取基准文件并索引其题目，同名基准先被整体替换。切分在锁外完成，替换在一次写锁内进行，
+// 查询不会看到只替换了一部分的基准
+func (x *Index) Ingest(benchmark, path string, opts ReadOptions) (IngestStats, error) {
+	stats := IngestStats{Benchmark: benchmark}
+	recs, err := ReadFile(path, opts)
+	if err != nil { return stats, err }
+	stats.Records = len(recs)
+	entries := make([]entry, 0, len(recs))
+	for _, rec := range recs {
+		e, ok := x.prepare(benchmark, rec.ID, rec.Text)
+		if !ok { stats.TooShort++; continue }
+		entries = append(entries, e)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	stats.Replaced = x.remove(benchmark)
+	for _, e := range entries { x.insert(e) }
+	stats.Indexed = len(entries)
+	if stats.Replaced > 0 || stats.Indexed > 0 { x.version++ }
+	return stats, nil
+}
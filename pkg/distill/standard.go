@@ -10,12 +10,13 @@ type StandardDistill struct{}
 
 func (d *StandardDistill) Name() string { return "standard_distill" }
 
-func (d *StandardDistill) Distill(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error) {
+func (d *StandardDistill) Distill(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error) {
 	distillType := "sft"
 	if val, ok := params["distill_type"].(string); ok { distillType = val }
 
 	req := model.VLLMRequest{
 		Model:       params["model"].(string),
+		Seed:        external.SeedParam(params),
 		Messages:    []model.Message{{Role: "user", Content: prompt}},
 		MaxTokens:   1024,
 		Temperature: 0.7,
@@ -26,8 +27,8 @@ func (d *StandardDistill) Distill(prompt string, params map[string]interface{},
 		req.TopLogprobs = 5
 	}
 
-	resp, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), req)
-	if err != nil || len(resp.Choices) == 0 { return nil, fmt.Errorf("distill failed") }
+	resp, err := llm.CallChatCompletion(params["vllm_base_url"].(string), req)
+	if err != nil { return nil, fmt.Errorf("distill failed: %w", err) }
 
 	if distillType == "logits" {
 		return resp.Choices[0].Logprobs, nil
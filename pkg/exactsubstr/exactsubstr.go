@@ -0,0 +1,229 @@
+package exactsubstr
+
+import (
+	"graunt/pkg/jsonl"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 文档之间用 0xff 分隔，该字节不会出现在合法 UTF-8 中
+const separator = 0xff
+
+// DocSpans 记录一篇文档中与语料其他位置 (或自身其他位置) 完全重复的字节区间
+type DocSpans struct {
+	Hash     string   `json:"hash"`
+	ID       string   `json:"id"`
+	Length   int      `json:"length"`
+	DupBytes int      `json:"dup_bytes"`
+	Spans    [][2]int `json:"spans"`
+	Doc      int      `json:"-"`
+}
+
+// Builder 把整个语料拼接成一个字节串，构造后缀数组后找出所有长度不小于 MinLen 的重复子串。
+// 与 ExactSubstr 一样，重复子串的所有出现位置都会被标记。内存占用约为语料大小的 13 倍。
+type Builder struct {
+	MinLen int
+	text   []byte
+	starts []int
+	ids    []string
+}
+
+func NewBuilder(minLen int) *Builder { return &Builder{MinLen: minLen} }
+
+func TextHash(text string) string {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func (b *Builder) Add(id, text string) error {
+	if len(b.text)+len(text)+1 > math.MaxInt32 { return errors.New("corpus exceeds 2GiB, split it into smaller groups") }
+	b.starts = append(b.starts, len(b.text))
+	b.ids = append(b.ids, id)
+	b.text = append(b.text, text...)
+	b.text = append(b.text, separator)
+	return nil
+}
+
+// AddShard 读取 JSONL 分片 ({"id","text"} 每行一条，可 .gz/.zst 压缩)
+func (b *Builder) AddShard(path string) (int, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return 0, err }
+	defer in.Close()
+	sc := jsonl.NewScanner(in)
+	n, line := 0, 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 { continue }
+		var doc struct {
+			ID   string `json:"id"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil { return n, fmt.Errorf("%s:%d: %v", path, line, err) }
+		if doc.ID == "" { doc.ID = fmt.Sprintf("%s:%d", path, line) }
+		if err := b.Add(doc.ID, doc.Text); err != nil { return n, err }
+		n++
+	}
+	return n, sc.Err()
+}
+
+func (b *Builder) Len() int { return len(b.ids) }
+
+func (b *Builder) Doc(i int) (id, text string) {
+	end := len(b.text)
+	if i+1 < len(b.starts) { end = b.starts[i+1] }
+	return b.ids[i], string(b.text[b.starts[i] : end-1])
+}
+
+// Build 返回所有含重复区间的文档
+func (b *Builder) Build() []DocSpans {
+	n := len(b.text)
+	b.starts = append(b.starts[:len(b.ids)], n)
+	if n == 0 { return nil }
+
+	sa := suffixArray(b.text)
+	rank := make([]int32, n)
+	for r, p := range sa { rank[p] = int32(r) }
+
+	// Kasai 算法求相邻后缀的 LCP，超过阈值的两侧区间都用差分数组标记。
+	// 公共前缀在文档分隔符处截止，否则会越过分隔符把下一篇文档的开头也标为重复
+	cover := make([]int32, n+1)
+	k := 0
+	for i := 0; i < n; i++ {
+		r := rank[i]
+		if r == 0 { k = 0; continue }
+		j := int(sa[r-1])
+		for i+k < n && j+k < n && b.text[i+k] == b.text[j+k] && b.text[i+k] != separator { k++ }
+		if k >= b.MinLen {
+			cover[i]++; cover[i+k]--
+			cover[j]++; cover[j+k]--
+		}
+		if k > 0 { k-- }
+	}
+	sa, rank = nil, nil
+	var acc int32
+	for p := 0; p < n; p++ { acc += cover[p]; cover[p] = acc }
+
+	var out []DocSpans
+	for d := range b.ids {
+		start, end := b.starts[d], b.starts[d+1]-1
+		text := b.text[start:end]
+		var spans [][2]int
+		dup := 0
+		for p := start; p < end; {
+			if cover[p] <= 0 { p++; continue }
+			q := p
+			for q < end && cover[q] > 0 { q++ }
+			s, e := p-start, q-start
+			for s < e && !utf8.RuneStart(text[s]) { s++ }
+			for e > s && e < len(text) && !utf8.RuneStart(text[e]) { e-- }
+			if e-s >= b.MinLen { spans = append(spans, [2]int{s, e}); dup += e - s }
+			p = q
+		}
+		if len(spans) > 0 {
+			out = append(out, DocSpans{Hash: TextHash(string(text)), ID: b.ids[d], Length: len(text), DupBytes: dup, Spans: spans, Doc: d})
+		}
+	}
+	return out
+}
+
+// Cut 删除 spans 覆盖的字节区间
+func Cut(text string, spans [][2]int) string {
+	sorted := append([][2]int(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	var sb strings.Builder
+	last := 0
+	for _, sp := range sorted {
+		s, e := sp[0], sp[1]
+		if s < last { s = last }
+		if e > len(text) { e = len(text) }
+		if s >= e { continue }
+		sb.WriteString(text[last:s])
+		last = e
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func WriteIndex(path string, docs []DocSpans) error {
+	f, err := os.Create(path)
+	if err != nil { return err }
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, d := range docs {
+		if err := enc.Encode(d); err != nil { f.Close(); return err }
+	}
+	if err := bw.Flush(); err != nil { f.Close(); return err }
+	return f.Close()
+}
+
+// Index 以文本哈希查找文档的重复区间，过滤/改写阶段只拿得到文本而没有文档 id
+type Index struct {
+	docs map[string]DocSpans
+}
+
+func LoadIndex(path string) (*Index, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return nil, err }
+	defer in.Close()
+	idx := &Index{docs: make(map[string]DocSpans)}
+	sc := jsonl.NewScanner(in)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 { continue }
+		var d DocSpans
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+		idx.docs[d.Hash] = d
+	}
+	return idx, sc.Err()
+}
+
+func (idx *Index) Lookup(text string) (DocSpans, bool) {
+	d, ok := idx.docs[TextHash(text)]
+	return d, ok
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// IndexPath 校验索引名并返回 dir 下的索引文件，索引名不能含目录
+func IndexPath(dir, name string) (string, error) {
+	if !namePattern.MatchString(name) || name == "." || name == ".." { return "", fmt.Errorf("invalid exact substring index name '%s'", name) }
+	if dir == "" { return "", errors.New("exact substring index dir is not configured") }
+	return filepath.Join(dir, name), nil
+}
+
+type cachedIndex struct {
+	index   *Index
+	modTime time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cachedIndex)
+)
+
+// CachedIndex 按路径缓存已加载的索引，供 filter 与 rewrite 共享；文件修改时间变化 (重新生成) 后重新加载
+func CachedIndex(path string) (*Index, error) {
+	st, err := os.Stat(path)
+	if err != nil { return nil, err }
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := cache[path]; ok && c.modTime.Equal(st.ModTime()) { return c.index, nil }
+	idx, err := LoadIndex(path)
+	if err != nil { return nil, err }
+	cache[path] = cachedIndex{idx, st.ModTime()}
+	return idx, nil
+}
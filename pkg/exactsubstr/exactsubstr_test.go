@@ -0,0 +1,79 @@
+package exactsubstr
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func randomText(rng *rand.Rand, n int, alphabet string) []byte {
+	b := make([]byte, n)
+	for i := range b { b[i] = alphabet[rng.Intn(len(alphabet))] }
+	return b
+}
+
+func TestSuffixArrayMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 300; iter++ {
+		text := randomText(rng, rng.Intn(200), "ab\xffc")
+		want := make([]int32, len(text))
+		for i := range want { want[i] = int32(i) }
+		sort.Slice(want, func(i, j int) bool { return bytes.Compare(text[want[i]:], text[want[j]:]) < 0 })
+		if got := suffixArray(text); !reflect.DeepEqual(got, want) { t.Fatalf("%q: sa = %v, want %v", text, got, want) }
+	}
+}
+
+// naiveSpans 逐对比较全部后缀：公共前缀 (不跨文档分隔符) 达到 minLen 的位置都算重复
+func naiveSpans(text []byte, starts []int, minLen int) map[int][][2]int {
+	n := len(text)
+	covered := make([]bool, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j { continue }
+			k := 0
+			for i+k < n && j+k < n && text[i+k] == text[j+k] && text[i+k] != separator { k++ }
+			if k >= minLen {
+				for p := i; p < i+k; p++ { covered[p] = true }
+			}
+		}
+	}
+	out := make(map[int][][2]int)
+	for d := range starts[:len(starts)-1] {
+		start, end := starts[d], starts[d+1]-1
+		for p := start; p < end; {
+			if !covered[p] { p++; continue }
+			q := p
+			for q < end && covered[q] { q++ }
+			if q-p >= minLen { out[d] = append(out[d], [2]int{p - start, q - start}) }
+			p = q
+		}
+	}
+	return out
+}
+
+func TestBuildMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for iter := 0; iter < 200; iter++ {
+		b := NewBuilder(2 + rng.Intn(5))
+		for d := rng.Intn(5) + 1; d > 0; d-- { b.Add("", string(randomText(rng, rng.Intn(40), "abc"))) }
+		got := make(map[int][][2]int)
+		for _, d := range b.Build() { got[d.Doc] = d.Spans }
+		if want := naiveSpans(b.text, b.starts, b.MinLen); !reflect.DeepEqual(got, want) {
+			t.Fatalf("minLen %d, text %q:\ngot  %v\nwant %v", b.MinLen, b.text, got, want)
+		}
+	}
+}
+
+// 重复片段恰好位于文档末尾时，不能越过分隔符把下一篇的开头也标为重复
+func TestBuildStopsAtSeparator(t *testing.T) {
+	b := NewBuilder(4)
+	b.Add("a", "unique one: repeated")
+	b.Add("b", "xy")
+	b.Add("c", "other two: repeated")
+	b.Add("d", "xz")
+	for _, d := range b.Build() {
+		if d.ID == "b" || d.ID == "d" { t.Errorf("doc %s marked as duplicated: %v", d.ID, d.Spans) }
+	}
+}
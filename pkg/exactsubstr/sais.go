@@ -0,0 +1,94 @@
+package exactsubstr
+
+// sais 用 SA-IS 算法在 O(n) 时间内构造后缀数组。
+// s 的取值范围为 [0,k)，且必须以唯一的最小值 0 (哨兵) 结尾。
+func sais(s []int32, k int) []int32 {
+	n := len(s)
+	sa := make([]int32, n)
+	if n == 1 { return sa }
+
+	isS := make([]bool, n)
+	isS[n-1] = true
+	for i := n - 2; i >= 0; i-- { isS[i] = s[i] < s[i+1] || (s[i] == s[i+1] && isS[i+1]) }
+	isLMS := func(i int) bool { return i > 0 && isS[i] && !isS[i-1] }
+
+	counts := make([]int32, k)
+	for _, c := range s { counts[c]++ }
+	buckets := func(end bool) []int32 {
+		b := make([]int32, k)
+		var sum int32
+		for c := range counts {
+			sum += counts[c]
+			if end { b[c] = sum } else { b[c] = sum - counts[c] }
+		}
+		return b
+	}
+	induce := func(lms []int32) {
+		for i := range sa { sa[i] = -1 }
+		b := buckets(true)
+		for i := len(lms) - 1; i >= 0; i-- {
+			j := lms[i]
+			b[s[j]]--
+			sa[b[s[j]]] = j
+		}
+		b = buckets(false)
+		for i := 0; i < n; i++ {
+			if j := sa[i] - 1; sa[i] > 0 && !isS[j] {
+				sa[b[s[j]]] = j
+				b[s[j]]++
+			}
+		}
+		b = buckets(true)
+		for i := n - 1; i >= 0; i-- {
+			if j := sa[i] - 1; sa[i] > 0 && isS[j] {
+				b[s[j]]--
+				sa[b[s[j]]] = j
+			}
+		}
+	}
+
+	var lms []int32
+	for i := 1; i < n; i++ { if isLMS(i) { lms = append(lms, int32(i)) } }
+	induce(lms)
+
+	lmsEqual := func(a, b int) bool {
+		for i := 0; ; i++ {
+			if s[a+i] != s[b+i] || isS[a+i] != isS[b+i] { return false }
+			if i > 0 && (isLMS(a+i) || isLMS(b+i)) { return isLMS(a+i) && isLMS(b+i) }
+		}
+	}
+
+	// 为排好序的 LMS 子串命名，LMS 位置两两间隔至少为 2，可按 p/2 存放名字
+	names := make([]int32, n/2+1)
+	for i := range names { names[i] = -1 }
+	name, prev := int32(-1), -1
+	for _, p := range sa {
+		if !isLMS(int(p)) { continue }
+		if prev < 0 || !lmsEqual(prev, int(p)) { name++ }
+		names[p/2] = name
+		prev = int(p)
+	}
+
+	reduced := make([]int32, len(lms))
+	for i, p := range lms { reduced[i] = names[p/2] }
+	var sa1 []int32
+	if int(name)+1 == len(lms) {
+		sa1 = make([]int32, len(lms))
+		for i, c := range reduced { sa1[c] = int32(i) }
+	} else {
+		sa1 = sais(reduced, int(name)+1)
+	}
+
+	sorted := make([]int32, len(lms))
+	for i, r := range sa1 { sorted[i] = lms[r] }
+	induce(sorted)
+	return sa
+}
+
+// suffixArray 返回字节串 text 的后缀数组 (不含哨兵)
+func suffixArray(text []byte) []int32 {
+	s := make([]int32, len(text)+1)
+	for i, c := range text { s[i] = int32(c) + 1 }
+	sa := sais(s, 257)
+	return sa[1:]
+}
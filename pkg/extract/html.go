@@ -0,0 +1,377 @@
+package extract
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 与 Readability 相同的思路：丢弃脚本、导航等噪声节点，按段落文本量、逗号数与 class/id 提示给祖先容器打分，
+// 链接密度高的容器降权，取得分最高的容器 (连同得分相近的兄弟节点) 作为正文
+var (
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story|rich_media|answer|RichText`)
+	negativeHint = regexp.MustCompile(`(?i)comment|combx|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|shoutbox|sidebar|sponsor|shopping|tags|tool|widget|nav|menu|breadcrumb|share|social|advert|banner|cookie|popup|modal|subscribe|login|signup|recommend`)
+	junkTags     = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Svg: true, atom.Form: true,
+		atom.Nav: true, atom.Footer: true, atom.Aside: true, atom.Button: true, atom.Input: true, atom.Select: true,
+		atom.Textarea: true, atom.Template: true, atom.Object: true, atom.Embed: true, atom.Canvas: true, atom.Head: true,
+	}
+	blockTags = map[atom.Atom]bool{
+		atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true, atom.Blockquote: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Ul: true, atom.Ol: true,
+		atom.Li: true, atom.Pre: true, atom.Table: true, atom.Hr: true, atom.Figure: true, atom.Figcaption: true, atom.Dl: true,
+		atom.Dt: true, atom.Dd: true, atom.Header: true, atom.Address: true, atom.Details: true, atom.Summary: true, atom.Center: true,
+	}
+	langClass = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([A-Za-z0-9_+#-]+)`)
+)
+
+// Page 是从 HTML 提取出的正文
+type Page struct {
+	Title    string
+	Markdown string
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key { return a.Val }
+	}
+	return ""
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a { return n }
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if f := findFirst(c, a); f != nil { return f }
+	}
+	return nil
+}
+
+func pageTitle(doc *html.Node) string {
+	var og string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta && (attr(n, "property") == "og:title" || attr(n, "name") == "twitter:title") && og == "" {
+			og = attr(n, "content")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling { walk(c) }
+	}
+	walk(doc)
+	if og != "" { return strings.TrimSpace(og) }
+	if t := findFirst(doc, atom.Title); t != nil { return strings.TrimSpace(collapse(rawText(t))) }
+	return ""
+}
+
+// clean 删除噪声节点与带负面 class/id 提示的容器
+func clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && junkTags[c.DataAtom]:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && c.DataAtom != atom.Body && c.DataAtom != atom.Article && c.DataAtom != atom.Main && isNegative(c):
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && (attr(c, "hidden") != "" || strings.Contains(strings.ReplaceAll(attr(c, "style"), " ", ""), "display:none")):
+			n.RemoveChild(c)
+		default:
+			clean(c)
+		}
+		c = next
+	}
+}
+
+func hints(n *html.Node) string { return attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "role") }
+
+func isNegative(n *html.Node) bool {
+	h := hints(n)
+	return negativeHint.MatchString(h) && !positiveHint.MatchString(h)
+}
+
+func classWeight(n *html.Node) float64 {
+	w := 0.0
+	h := hints(n)
+	if negativeHint.MatchString(h) { w -= 25 }
+	if positiveHint.MatchString(h) { w += 25 }
+	return w
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		return 10
+	case atom.Div, atom.Section:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+type scorer struct {
+	textLen map[*html.Node]int
+	linkLen map[*html.Node]int
+	score   map[*html.Node]float64
+}
+
+func (s *scorer) measure(n *html.Node) (int, int) {
+	if n.Type == html.TextNode { l := len([]rune(strings.TrimSpace(n.Data))); return l, 0 }
+	text, link := 0, 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t, l := s.measure(c)
+		text += t
+		link += l
+	}
+	if n.Type == html.ElementNode && n.DataAtom == atom.A { link = text }
+	s.textLen[n], s.linkLen[n] = text, link
+	return text, link
+}
+
+func (s *scorer) linkDensity(n *html.Node) float64 {
+	if s.textLen[n] == 0 { return 0 }
+	return float64(s.linkLen[n]) / float64(s.textLen[n])
+}
+
+func (s *scorer) init(n *html.Node) {
+	if _, ok := s.score[n]; !ok { s.score[n] = tagWeight(n) + classWeight(n) }
+}
+
+// scoreParagraphs 给每个段落的父节点加全分、祖父节点加半分
+func (s *scorer) scoreParagraphs(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling { s.scoreParagraphs(c) }
+	if n.Type != html.ElementNode { return }
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+	case atom.Div, atom.Section:
+		if hasBlockChild(n) { return }
+	default:
+		return
+	}
+	l := s.textLen[n]
+	if l < 25 || n.Parent == nil { return }
+	text := rawText(n)
+	points := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "、")+strings.Count(text, "。")) + math.Min(float64(l)/100, 3)
+	p := n.Parent
+	s.init(p)
+	s.score[p] += points
+	if gp := p.Parent; gp != nil && gp.Type == html.ElementNode {
+		s.init(gp)
+		s.score[gp] += points / 2
+	}
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockTags[c.DataAtom] { return true }
+	}
+	return false
+}
+
+// mainContent 返回正文节点列表 (最佳容器及值得保留的兄弟节点)
+func mainContent(body *html.Node) []*html.Node {
+	s := &scorer{textLen: map[*html.Node]int{}, linkLen: map[*html.Node]int{}, score: map[*html.Node]float64{}}
+	s.measure(body)
+	s.scoreParagraphs(body)
+	var best *html.Node
+	bestScore := 0.0
+	for n, sc := range s.score {
+		sc *= 1 - s.linkDensity(n)
+		s.score[n] = sc
+		if best == nil || sc > bestScore { best, bestScore = n, sc }
+	}
+	if best == nil || best.Parent == nil { return []*html.Node{body} }
+
+	threshold := math.Max(10, bestScore*0.2)
+	var out []*html.Node
+	for c := best.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c == best { out = append(out, c); continue }
+		if c.Type != html.ElementNode { continue }
+		if sc, ok := s.score[c]; ok && sc >= threshold { out = append(out, c); continue }
+		if c.DataAtom == atom.P && s.textLen[c] > 80 && s.linkDensity(c) < 0.25 { out = append(out, c) }
+	}
+	return out
+}
+
+// HTML 提取网页正文并转成 Markdown；full 为 true 时跳过正文识别，转换整个 body (用于知乎 content 等已是正文的片段)
+func HTML(src string, full bool) Page {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil { return Page{Markdown: collapse(src)} }
+	page := Page{Title: pageTitle(doc)}
+	body := findFirst(doc, atom.Body)
+	if body == nil { body = doc }
+	clean(body)
+	nodes := []*html.Node{body}
+	if !full { nodes = mainContent(body) }
+	var b strings.Builder
+	for _, n := range nodes { b.WriteString(renderBlock(n, 0)) }
+	page.Markdown = tidy(b.String())
+	return page
+}
+
+func rawText(n *html.Node) string {
+	if n.Type == html.TextNode { return n.Data }
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling { b.WriteString(rawText(c)) }
+	return b.String()
+}
+
+var spaceRun = regexp.MustCompile(`[ \t\r\n\f\v\x{00a0}\x{3000}]+`)
+
+func collapse(s string) string { return spaceRun.ReplaceAllString(s, " ") }
+
+// renderInline 渲染行内内容，空白折叠为一个空格，<br> 保留为换行
+func renderInline(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling { b.WriteString(inlineNode(c)) }
+	return b.String()
+}
+
+func inlineNode(c *html.Node) string {
+	switch {
+	case c.Type == html.TextNode:
+		return collapse(c.Data)
+	case c.Type != html.ElementNode, c.DataAtom == atom.Img:
+		return ""
+	case c.DataAtom == atom.Br:
+		return "\n"
+	case c.DataAtom == atom.Code || c.DataAtom == atom.Kbd || c.DataAtom == atom.Samp:
+		if code := strings.TrimSpace(collapse(rawText(c))); code != "" { return "`" + code + "`" }
+		return ""
+	case blockTags[c.DataAtom]:
+		return renderBlock(c, 0)
+	}
+	return renderInline(c)
+}
+
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines { lines[i] = strings.TrimSpace(l) }
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// renderBlock 把块级节点渲染为 Markdown，前后用空行分隔
+func renderBlock(n *html.Node, depth int) string {
+	if n.Type == html.TextNode { return collapse(n.Data) }
+	if n.Type != html.ElementNode && n.Type != html.DocumentNode { return "" }
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.Join(strings.Fields(renderInline(n)), " ")
+		if text == "" { return "" }
+		level, _ := strconv.Atoi(n.Data[1:])
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+	case atom.P, atom.Dt, atom.Dd, atom.Figcaption, atom.Summary, atom.Address:
+		return "\n\n" + trimLines(renderInline(n)) + "\n\n"
+	case atom.Pre:
+		return "\n\n" + codeBlock(n) + "\n\n"
+	case atom.Ul, atom.Ol:
+		return "\n\n" + renderList(n, depth) + "\n\n"
+	case atom.Table:
+		if md, ok := renderTable(n); ok { return "\n\n" + md + "\n\n" }
+	case atom.Blockquote:
+		inner := tidy(renderChildren(n, depth))
+		if inner == "" { return "" }
+		return "\n\n> " + strings.ReplaceAll(inner, "\n", "\n> ") + "\n\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.Img, atom.Br:
+		return ""
+	}
+	return "\n\n" + renderChildren(n, depth) + "\n\n"
+}
+
+// renderChildren 渲染容器的子节点：连续的行内节点合成一段
+func renderChildren(n *html.Node, depth int) string {
+	var b, inline strings.Builder
+	flush := func() {
+		if t := trimLines(inline.String()); t != "" { b.WriteString("\n\n" + t + "\n\n") }
+		inline.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (blockTags[c.DataAtom] || c.DataAtom == atom.Tbody || c.DataAtom == atom.Tr || c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+			flush()
+			b.WriteString(renderBlock(c, depth))
+			continue
+		}
+		inline.WriteString(inlineNode(c))
+	}
+	flush()
+	return b.String()
+}
+
+func codeBlock(n *html.Node) string {
+	lang := ""
+	if m := langClass.FindStringSubmatch(attr(n, "class")); m != nil { lang = m[1] }
+	if code := findFirst(n, atom.Code); code != nil && lang == "" {
+		if m := langClass.FindStringSubmatch(attr(code, "class")); m != nil { lang = m[1] }
+	}
+	text := strings.Trim(rawText(n), "\n")
+	fence := "```"
+	for strings.Contains(text, fence) { fence += "`" }
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+func renderList(n *html.Node, depth int) string {
+	var items []string
+	i := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil { i = start }
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li { continue }
+		marker := "- "
+		if n.DataAtom == atom.Ol { marker = strconv.Itoa(i) + ". "; i++ }
+		body := tidy(renderChildren(c, depth+1))
+		if body == "" { continue }
+		lines := strings.Split(body, "\n")
+		for j := 1; j < len(lines); j++ {
+			if lines[j] != "" { lines[j] = strings.Repeat(" ", len(marker)) + lines[j] }
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// renderTable 只把二维数据表转成 Markdown 表格；含嵌套表格或大段文字的布局表格按普通容器处理
+func renderTable(n *html.Node) (string, bool) {
+	var rows [][]string
+	var walk func(*html.Node) bool
+	walk = func(x *html.Node) bool {
+		for c := x.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode { continue }
+			switch c.DataAtom {
+			case atom.Table:
+				return false
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) { continue }
+					if findFirst(cell, atom.Table) != nil { return false }
+					text := strings.Join(strings.Fields(renderInline(cell)), " ")
+					if len([]rune(text)) > 200 { return false }
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+				if len(row) > 0 { rows = append(rows, row) }
+			default:
+				if !walk(c) { return false }
+			}
+		}
+		return true
+	}
+	if !walk(n) || len(rows) == 0 { return "", false }
+	cols := 0
+	for _, r := range rows { cols = max(cols, len(r)) }
+	if cols < 2 { return "", false }
+	var b strings.Builder
+	for i, r := range rows {
+		for len(r) < cols { r = append(r, "") }
+		b.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 { b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n") }
+	}
+	return strings.TrimRight(b.String(), "\n"), true
+}
@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	fenceLine    = regexp.MustCompile("^\\s*(```+|~~~+)")
+	listLine     = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s`)
+	bulletMarker = regexp.MustCompile(`^(\s*)[*+](\s+)`)
+	atxNoSpace   = regexp.MustCompile(`^(#{1,6})([^#\s])`)
+	atxClosing   = regexp.MustCompile(`\s+#+\s*$`)
+	setextH1     = regexp.MustCompile(`^=+\s*$`)
+	setextH2     = regexp.MustCompile(`^-{2,}\s*$`)
+	htmlComment  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>`)
+	innerSpaces  = regexp.MustCompile(`[ \t\x{00a0}]{2,}`)
+)
+
+// tidy 整理渲染结果：去掉行尾空白、合并连续空行，围栏代码块内原样保留
+func tidy(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var out []string
+	inFence, fence := false, ""
+	blank := 0
+	for _, line := range strings.Split(s, "\n") {
+		if m := fenceLine.FindStringSubmatch(line); m != nil && (!inFence || strings.HasPrefix(strings.TrimSpace(line), fence)) {
+			if !inFence { fence = m[1] }
+			inFence = !inFence
+			out = append(out, strings.TrimRight(line, " \t"))
+			blank = 0
+			continue
+		}
+		if inFence { out = append(out, line); continue }
+		line = strings.TrimRight(line, " \t\u00a0\u3000")
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > 1 { continue }
+			line = ""
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n")
+}
+
+// Markdown 规范化 Markdown 文本：setext 标题改为 ATX、补齐 # 后空格、列表符号统一为 -、去掉 HTML 注释与 <br>、
+// 解码实体、折叠行内多余空格，围栏代码块内不做改动
+func Markdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = htmlComment.ReplaceAllString(src, "")
+	lines := strings.Split(src, "\n")
+	var out []string
+	inFence, fence := false, ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := fenceLine.FindStringSubmatch(line); m != nil && (!inFence || strings.HasPrefix(strings.TrimSpace(line), fence)) {
+			if !inFence { fence = m[1] }
+			inFence = !inFence
+			out = append(out, strings.TrimSpace(line))
+			continue
+		}
+		if inFence { out = append(out, line); continue }
+		line = html.UnescapeString(htmlBreak.ReplaceAllString(line, "\n"))
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && i+1 < len(lines) && !listLine.MatchString(line) {
+			if setextH1.MatchString(lines[i+1]) { out = append(out, "# "+trimmed); i++; continue }
+			if setextH2.MatchString(lines[i+1]) && !strings.HasPrefix(trimmed, "#") { out = append(out, "## "+trimmed); i++; continue }
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			line = atxNoSpace.ReplaceAllString(trimmed, "$1 $2")
+			line = atxClosing.ReplaceAllString(line, "")
+		} else {
+			line = bulletMarker.ReplaceAllString(line, "$1-$2")
+			indentEnd := len(line) - len(strings.TrimLeft(line, " \t"))
+			line = line[:indentEnd] + innerSpaces.ReplaceAllString(line[indentEnd:], " ")
+		}
+		out = append(out, line)
+	}
+	return tidy(strings.Join(out, "\n"))
+}
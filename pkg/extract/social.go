@@ -0,0 +1,246 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Post 是帖子或评论；Replies 为直接回复
+type Post struct {
+	Title   string  `json:"title,omitempty"`
+	Author  string  `json:"author,omitempty"`
+	Body    string  `json:"body"`
+	Score   int     `json:"score"`
+	Replies []*Post `json:"replies,omitempty"`
+}
+
+// Turn 是对话中的一轮，Role 为 user / assistant
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+var removedBodies = map[string]bool{"[deleted]": true, "[removed]": true, "": true}
+
+// Reddit 解析帖子 permalink 的 .json (帖子 Listing + 评论 Listing 的数组) 或版块 Listing，返回其中的帖子
+func Reddit(raw []byte) ([]*Post, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil { return nil, err }
+	var listings []interface{}
+	switch x := v.(type) {
+	case []interface{}:
+		listings = x
+	case map[string]interface{}:
+		listings = []interface{}{x}
+	}
+	var posts []*Post
+	var comments []*Post
+	for i, l := range listings {
+		for _, child := range redditChildren(l) {
+			kind, _ := child["kind"].(string)
+			data, _ := child["data"].(map[string]interface{})
+			switch kind {
+			case "t3":
+				posts = append(posts, redditPost(data))
+			case "t1":
+				if c := redditComment(data); c != nil && i > 0 { comments = append(comments, c) }
+			}
+		}
+	}
+	if len(posts) == 0 { return nil, fmt.Errorf("no reddit posts found") }
+	if len(comments) > 0 { posts[0].Replies = comments }
+	return posts, nil
+}
+
+func redditChildren(listing interface{}) []map[string]interface{} {
+	m, _ := listing.(map[string]interface{})
+	if m == nil || m["kind"] != "Listing" { return nil }
+	data, _ := m["data"].(map[string]interface{})
+	children, _ := data["children"].([]interface{})
+	var out []map[string]interface{}
+	for _, c := range children {
+		if cm, ok := c.(map[string]interface{}); ok { out = append(out, cm) }
+	}
+	return out
+}
+
+func str(m map[string]interface{}, key string) string { s, _ := m[key].(string); return s }
+
+func num(m map[string]interface{}, key string) int { f, _ := m[key].(float64); return int(f) }
+
+// Reddit 的 markdown 字段对 & < > 做了实体转义
+func redditPost(d map[string]interface{}) *Post {
+	body := Markdown(html.UnescapeString(str(d, "selftext")))
+	if removedBodies[strings.TrimSpace(body)] {
+		body = ""
+		if u := str(d, "url"); u != "" && !strings.Contains(u, str(d, "permalink")) { body = u }
+	}
+	return &Post{Title: html.UnescapeString(str(d, "title")), Author: str(d, "author"), Body: body, Score: num(d, "score")}
+}
+
+func redditComment(d map[string]interface{}) *Post {
+	author := str(d, "author")
+	body := strings.TrimSpace(html.UnescapeString(str(d, "body")))
+	if removedBodies[body] || author == "AutoModerator" || d["stickied"] == true { return nil }
+	c := &Post{Author: author, Body: Markdown(body), Score: num(d, "score")}
+	for _, child := range redditChildren(d["replies"]) {
+		if child["kind"] != "t1" { continue }
+		data, _ := child["data"].(map[string]interface{})
+		if r := redditComment(data); r != nil { c.Replies = append(c.Replies, r) }
+	}
+	return c
+}
+
+// Zhihu 解析知乎 API 的回答、文章、问题、评论对象或 {"data": [...]} 分页列表；
+// 同一问题下的多个回答作为问题的回复，评论挂在所属回答下
+func Zhihu(raw []byte) ([]*Post, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil { return nil, err }
+	var items []interface{}
+	switch x := v.(type) {
+	case []interface{}:
+		items = x
+	case map[string]interface{}:
+		if data, ok := x["data"].([]interface{}); ok { items = data } else { items = []interface{}{x} }
+	}
+	var posts []*Post
+	questions := make(map[string]*Post)
+	var comments []*Post
+	for _, it := range items {
+		m, _ := it.(map[string]interface{})
+		if m == nil { continue }
+		if t, ok := m["target"].(map[string]interface{}); ok { m = t } // feed 条目把内容包在 target 里
+		switch str(m, "type") {
+		case "answer":
+			q, _ := m["question"].(map[string]interface{})
+			title := html.UnescapeString(str(q, "title"))
+			answer := &Post{Author: zhihuAuthor(m), Body: zhihuHTML(str(m, "content")), Score: num(m, "voteup_count")}
+			if answer.Body == "" { answer.Body = strings.TrimSpace(str(m, "excerpt")) }
+			p, ok := questions[title]
+			if !ok {
+				p = &Post{Title: title, Body: zhihuHTML(str(q, "detail"))}
+				questions[title] = p
+				posts = append(posts, p)
+			}
+			p.Replies = append(p.Replies, answer)
+		case "article":
+			posts = append(posts, &Post{Title: html.UnescapeString(str(m, "title")), Author: zhihuAuthor(m), Body: zhihuHTML(str(m, "content")), Score: num(m, "voteup_count")})
+		case "question":
+			title := html.UnescapeString(str(m, "title"))
+			if _, ok := questions[title]; !ok {
+				p := &Post{Title: title, Body: zhihuHTML(str(m, "detail"))}
+				questions[title] = p
+				posts = append(posts, p)
+			}
+		case "comment":
+			if c := zhihuComment(m); c != nil { comments = append(comments, c) }
+		}
+	}
+	if len(posts) == 0 && len(comments) > 0 { return []*Post{{Replies: comments}}, nil }
+	if len(posts) == 0 { return nil, fmt.Errorf("no zhihu answers, articles or questions found") }
+	if len(comments) > 0 {
+		target := posts[0]
+		if len(target.Replies) == 1 { target = target.Replies[0] }
+		target.Replies = append(target.Replies, comments...)
+	}
+	return posts, nil
+}
+
+func zhihuAuthor(m map[string]interface{}) string {
+	a, _ := m["author"].(map[string]interface{})
+	if member, ok := a["member"].(map[string]interface{}); ok { a = member }
+	return str(a, "name")
+}
+
+func zhihuHTML(s string) string {
+	if strings.TrimSpace(s) == "" { return "" }
+	return HTML(s, true).Markdown
+}
+
+func zhihuComment(m map[string]interface{}) *Post {
+	body := zhihuHTML(str(m, "content"))
+	if body == "" { return nil }
+	c := &Post{Author: zhihuAuthor(m), Body: body, Score: num(m, "like_count") + num(m, "vote_count")}
+	children, _ := m["child_comments"].([]interface{})
+	for _, ch := range children {
+		if cm, ok := ch.(map[string]interface{}); ok {
+			if r := zhihuComment(cm); r != nil { c.Replies = append(c.Replies, r) }
+		}
+	}
+	return c
+}
+
+// SpiderText 解析知乎爬虫保存的 "URL: ...\nTitle: ...\n----\n正文" 格式；不是该格式时 ok 为 false
+func SpiderText(text string) (url string, post *Post, ok bool) {
+	lines := strings.SplitN(text, "\n", 4)
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "URL: ") || !strings.HasPrefix(lines[1], "Title: ") || strings.Trim(lines[2], "-\r") != "" || len(lines[2]) < 3 {
+		return "", nil, false
+	}
+	body := ""
+	if len(lines) == 4 { body = Markdown(lines[3]) }
+	return strings.TrimSpace(lines[0][5:]), &Post{Title: strings.TrimSpace(lines[1][7:]), Body: body}, true
+}
+
+// Limits 控制展开哪些回复
+type Limits struct {
+	MaxReplies int // 最多保留的回复数 (对话中为轮数)，0 为不限
+	MinScore   int // 低于该分数的回复被丢弃
+}
+
+func (l Limits) keep(p *Post) bool { return p.Score >= l.MinScore && !removedBodies[p.Body] }
+
+func byScore(posts []*Post) []*Post {
+	out := append([]*Post(nil), posts...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// Document 把帖子及回复渲染为一篇 Markdown 文档：标题、正文，之后按分数深度优先列出回复，以分隔线隔开
+func Document(p *Post, lim Limits) string {
+	var parts []string
+	head := ""
+	if p.Title != "" { head = "# " + p.Title }
+	if p.Body != "" {
+		if head != "" { head += "\n\n" }
+		head += p.Body
+	}
+	if head != "" { parts = append(parts, head) }
+	n := 0
+	var walk func([]*Post)
+	walk = func(replies []*Post) {
+		for _, r := range byScore(replies) {
+			if lim.MaxReplies > 0 && n >= lim.MaxReplies { return }
+			if !lim.keep(r) { continue }
+			parts = append(parts, r.Body)
+			n++
+			walk(r.Replies)
+		}
+	}
+	walk(p.Replies)
+	return strings.Join(parts, "\n\n---\n\n")
+}
+
+// Conversation 把帖子转成多轮对话：首轮为标题加正文 (user)，之后沿每层分数最高的回复链交替为 assistant / user
+func Conversation(p *Post, lim Limits) []Turn {
+	first := p.Body
+	if p.Title != "" && first != "" { first = p.Title + "\n\n" + first } else if p.Title != "" { first = p.Title }
+	var turns []Turn
+	if first != "" { turns = append(turns, Turn{Role: "user", Content: first}) }
+	replies := p.Replies
+	for {
+		var next *Post
+		for _, r := range byScore(replies) {
+			if lim.keep(r) { next = r; break }
+		}
+		if next == nil { break }
+		role := "user"
+		if len(turns)%2 == 1 { role = "assistant" }
+		turns = append(turns, Turn{Role: role, Content: next.Body})
+		replies = next.Replies
+		if lim.MaxReplies > 0 && len(turns) > lim.MaxReplies { break }
+	}
+	return turns
+}
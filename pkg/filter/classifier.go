@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"graunt/pkg/nlp"
+	"graunt/pkg/textclf"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ClassifierFilter 用训练好的 fastText 式分类器打分 (如 DCLM 式的 hq / cc 质量分类器)。
+// classifier_model 为 Dir 下的模型名 (可带 .clf 后缀)；classifier_labels 为保留标签白名单，
+// 文档在白名单标签上的最高概率须不低于 classifier_threshold (默认 0.5)；未给白名单时按 top-1 标签的概率判断。
+// 预测结果以 <模型名>_label / <模型名>_prob 元数据传给后续阶段。
+type ClassifierFilter struct {
+	Dir string
+}
+
+func NewClassifierFilter(dir string) *ClassifierFilter { return &ClassifierFilter{Dir: dir} }
+func (f *ClassifierFilter) Name() string { return "classifier" }
+
+// modelNamePattern 与训练接口的模型名规则一致：只是文件名，不含目录，模型总在 Dir 之内
+var modelNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func validModelName(name string) bool { return modelNamePattern.MatchString(name) && name != "." && name != ".." }
+
+func (f *ClassifierFilter) modelPath(name string) (string, error) {
+	if !validModelName(name) { return "", fmt.Errorf("invalid classifier model name '%s'", name) }
+	if f.Dir == "" { return "", errors.New("classifier dir is not configured") }
+	if !strings.HasSuffix(name, textclf.Ext) { name += textclf.Ext }
+	return filepath.Join(f.Dir, name), nil
+}
+
+// model 解析并加载 classifier_model，同时返回模型文件的修改时间
+func (f *ClassifierFilter) model(params map[string]interface{}) (*textclf.Model, string, time.Time, error) {
+	name, _ := params["classifier_model"].(string)
+	if name == "" { return nil, "", time.Time{}, errors.New("classifier_model param is required") }
+	path, err := f.modelPath(name)
+	if err != nil { return nil, "", time.Time{}, err }
+	st, err := os.Stat(path)
+	if err != nil { return nil, "", time.Time{}, fmt.Errorf("failed to resolve classifier: %v", err) }
+	m, err := textclf.Cached(path)
+	if err != nil { return nil, "", time.Time{}, fmt.Errorf("failed to load classifier: %v", err) }
+	if len(m.Labels) == 0 { return nil, "", time.Time{}, fmt.Errorf("classifier %s has no labels", path) }
+	return m, path, st.ModTime(), nil
+}
+
+// Validate 在批处理开始前加载分类器
+func (f *ClassifierFilter) Validate(params map[string]interface{}) error {
+	_, _, _, err := f.model(params)
+	return err
+}
+
+func (f *ClassifierFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _ := f.EvaluateWithMetadata(text, params)
+	return keep, reason
+}
+
+func (f *ClassifierFilter) EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{}) {
+	keep, reason, meta, err := f.EvaluateChecked(text, params)
+	if err != nil { return false, err.Error(), nil }
+	return keep, reason, meta
+}
+
+func (f *ClassifierFilter) EvaluateChecked(text string, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	m, path, modTime, err := f.model(params)
+	if err != nil { return false, "", nil, err }
+	threshold := 0.5
+	if v, ok := params["classifier_threshold"].(float64); ok { threshold = v }
+	allowed := stringSet(params["classifier_labels"])
+
+	// 缓存键带上模型修改时间，重新训练后不会沿用旧模型的预测
+	key := fmt.Sprintf("classifier:%s@%d", path, modTime.UnixNano())
+	preds := nlp.Analyze(text).Memo(key, func() interface{} { return m.Predict(text) }).([]textclf.Prediction)
+	if len(preds) == 0 { return false, "", nil, fmt.Errorf("classifier %s returned no predictions", path) }
+	prefix := strings.TrimSuffix(filepath.Base(path), textclf.Ext)
+	meta := map[string]interface{}{prefix + "_label": preds[0].Label, prefix + "_prob": preds[0].Prob}
+
+	best := preds[0]
+	if len(allowed) > 0 {
+		best = textclf.Prediction{}
+		for _, p := range preds {
+			if allowed[p.Label] { best = p; break }
+		}
+		if best.Label == "" { return false, fmt.Sprintf("no allowed label in classifier %s", prefix), meta, nil }
+	}
+	if best.Prob < threshold { return false, fmt.Sprintf("%s: p(%s)=%f < %f (top=%s)", prefix, best.Label, best.Prob, threshold, preds[0].Label), meta, nil }
+	return true, fmt.Sprintf("%s: p(%s)=%f", prefix, best.Label, best.Prob), meta, nil
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"graunt/pkg/decontam"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const decontamFile = "index.dci"
+
+// maxReportedMatches 限制写入元数据的命中题目数
+const maxReportedMatches = 5
+
+// DecontamFilter 用基准题目 (GSM8K / MMLU / C-Eval / HumanEval 等) 的 n-gram 索引检查文档是否泄漏评测数据。
+// decontam_mode=drop (默认) 丢弃命中文档，flag 只标记；命中判定为与某题共享不少于 decontam_min_overlap (默认 1) 个 n-gram
+// 且覆盖率不低于 decontam_min_coverage (默认 0)；decontam_benchmarks 限定检查的基准。
+// 元数据 contaminated 标记是否命中，contamination 列出命中的基准与题目。
+type DecontamFilter struct {
+	index   *decontam.Index
+	mu      sync.Mutex
+	checked int
+	hits    map[string]int
+}
+
+type DecontamStats struct {
+	N          int            `json:"n"`
+	Benchmarks map[string]int `json:"benchmarks"` // 各基准已索引的题目数
+	Checked    int            `json:"checked"`
+	Hits       map[string]int `json:"hits"` // 各基准命中的文档数
+}
+
+func NewDecontamFilter(n int) *DecontamFilter {
+	return &DecontamFilter{index: decontam.NewIndex(n), hits: make(map[string]int)}
+}
+func (f *DecontamFilter) Name() string { return "decontam" }
+
+// Index 返回底层索引，供导入基准文件
+func (f *DecontamFilter) Index() *decontam.Index { return f.index }
+
+func (f *DecontamFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _ := f.EvaluateWithMetadata(text, params)
+	return keep, reason
+}
+
+// Validate 拒绝空索引与未导入的 decontam_benchmarks：否则每篇文档都不命中，检查形同虚设
+func (f *DecontamFilter) Validate(params map[string]interface{}) error {
+	indexed := f.index.Benchmarks()
+	if len(indexed) == 0 { return fmt.Errorf("decontamination index is empty; ingest benchmarks first") }
+	for b := range stringSet(params["decontam_benchmarks"]) {
+		if indexed[b] == 0 { return fmt.Errorf("benchmark '%s' is not indexed", b) }
+	}
+	return nil
+}
+
+func (f *DecontamFilter) EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{}) {
+	minOverlap, minCoverage := 1.0, 0.0
+	if v, ok := params["decontam_min_overlap"].(float64); ok { minOverlap = v }
+	if v, ok := params["decontam_min_coverage"].(float64); ok { minCoverage = v }
+	var matches []decontam.Match
+	for _, m := range f.index.Check(text, stringSet(params["decontam_benchmarks"])) {
+		if float64(m.Overlap) >= minOverlap && m.Coverage >= minCoverage { matches = append(matches, m) }
+	}
+
+	f.mu.Lock()
+	f.checked++
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if !seen[m.Benchmark] { seen[m.Benchmark] = true; f.hits[m.Benchmark]++ }
+	}
+	f.mu.Unlock()
+
+	if len(matches) == 0 { return true, "no benchmark overlap", map[string]interface{}{"contaminated": false} }
+	meta := map[string]interface{}{"contaminated": true, "contamination": matches[:min(len(matches), maxReportedMatches)]}
+	top := matches[0]
+	reason := fmt.Sprintf("overlaps %s item %s (%d %d-grams, coverage %f)", top.Benchmark, top.ID, top.Overlap, f.index.N, top.Coverage)
+	if mode, _ := params["decontam_mode"].(string); mode == "flag" { return true, "flagged: " + reason, meta }
+	return false, reason, meta
+}
+
+func (f *DecontamFilter) Stats() interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := DecontamStats{N: f.index.N, Benchmarks: f.index.Benchmarks(), Checked: f.checked, Hits: make(map[string]int, len(f.hits))}
+	for k, v := range f.hits { st.Hits[k] = v }
+	return st
+}
+
+func (f *DecontamFilter) SaveState(dir string) error {
+	if !f.index.Dirty() { return nil }
+	return f.index.Save(filepath.Join(dir, decontamFile))
+}
+
+func (f *DecontamFilter) LoadState(dir string) error {
+	path := filepath.Join(dir, decontamFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) { return nil }
+	return f.index.Load(path)
+}
+
+// MergeState 并入其他节点导出的索引，本地已有的基准保持不变
+func (f *DecontamFilter) MergeState(path string, params map[string]interface{}) (interface{}, error) {
+	return f.index.Merge(path)
+}
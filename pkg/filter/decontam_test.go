@@ -0,0 +1,12 @@
+package filter
+
+import "testing"
+
+func TestDecontamValidate(t *testing.T) {
+	f := NewDecontamFilter(0)
+	if err := f.Validate(nil); err == nil { t.Fatal("empty index accepted") }
+	f.Index().Add("gsm8k", "1", "Natalia sold clips to 48 of her friends in April, and then she sold half as many clips in May.")
+	if err := f.Validate(nil); err != nil { t.Fatal(err) }
+	if err := f.Validate(map[string]interface{}{"decontam_benchmarks": "gsm8k"}); err != nil { t.Fatal(err) }
+	if err := f.Validate(map[string]interface{}{"decontam_benchmarks": "gsm8k,mmlu"}); err == nil { t.Fatal("unindexed benchmark accepted") }
+}
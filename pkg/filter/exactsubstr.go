@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"graunt/pkg/exactsubstr"
+	"errors"
+	"fmt"
+)
+
+// ExactSubstrFilter 依据 cmd/exactsubstr 离线生成的索引，丢弃重复子串占比过高的文档。
+// exact_substr_index 为 Dir 下的索引文件名
+type ExactSubstrFilter struct {
+	Dir string
+}
+
+func NewExactSubstrFilter(dir string) *ExactSubstrFilter { return &ExactSubstrFilter{Dir: dir} }
+func (f *ExactSubstrFilter) Name() string                { return "exact_substr" }
+
+func (f *ExactSubstrFilter) index(params map[string]interface{}) (*exactsubstr.Index, error) {
+	name, _ := params["exact_substr_index"].(string)
+	if name == "" { return nil, errors.New("exact_substr_index param is required") }
+	path, err := exactsubstr.IndexPath(f.Dir, name)
+	if err != nil { return nil, err }
+	return exactsubstr.CachedIndex(path)
+}
+
+// Validate 在批处理开始前加载索引，缺少参数或索引无法读取时整批报错
+func (f *ExactSubstrFilter) Validate(params map[string]interface{}) error {
+	_, err := f.index(params)
+	return err
+}
+
+func (f *ExactSubstrFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _, err := f.EvaluateChecked(text, params)
+	if err != nil { return false, err.Error() }
+	return keep, reason
+}
+
+func (f *ExactSubstrFilter) EvaluateChecked(text string, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	idx, err := f.index(params)
+	if err != nil { return false, "", nil, err }
+	maxFraction := 0.0
+	if v, ok := params["exact_substr_max_fraction"].(float64); ok { maxFraction = v }
+	doc, ok := idx.Lookup(text)
+	if !ok || doc.Length == 0 { return true, "ok", nil, nil }
+
+	fraction := float64(doc.DupBytes) / float64(doc.Length)
+	if fraction > maxFraction { return false, fmt.Sprintf("duplicated substrings cover %f of text > %f (%d spans)", fraction, maxFraction, len(doc.Spans)), nil, nil }
+	return true, "ok", nil, nil
+}
@@ -0,0 +1,255 @@
+package filter
+
+import (
+	"graunt/pkg/nlp"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Gopher (Rae et al., 2021) 与 C4 (Raffel et al., 2020) 的启发式质量规则。
+// 每组规则注册为独立过滤器，阈值可由参数覆盖，默认值取论文公布的数值；
+// gopher_quality 依次应用 Gopher 的全部规则。
+
+// rule 是一条阈值判定，upper 为 true 时指标不得超过阈值，否则不得低于阈值
+type rule struct {
+	metric string
+	param  string
+	def    float64
+	upper  bool
+	value  func(d *heuristicDoc) float64
+}
+
+type HeuristicFilter struct {
+	name    string
+	applies func(d *heuristicDoc) bool // nil 表示对所有语种适用
+	rules   []rule
+}
+
+func (h *HeuristicFilter) Name() string { return h.name }
+
+// heuristicDoc 包装共享特征：中日韩文本没有空格分词，按字计词
+type heuristicDoc struct {
+	feats *nlp.Features
+	lang  string
+	cjk   bool
+}
+
+func newHeuristicDoc(text string, params map[string]interface{}) *heuristicDoc {
+	feats := nlp.Analyze(text)
+	lang := documentLang(feats, params)
+	return &heuristicDoc{feats: feats, lang: lang, cjk: lang == "zh" || lang == "ja" || lang == "ko"}
+}
+
+func (d *heuristicDoc) words() []string {
+	if d.cjk { return d.feats.Tokens() }
+	return d.feats.Words()
+}
+
+// metric 在共享特征上缓存指标，gopher_quality 与单独注册的规则之间不重复计算
+func (d *heuristicDoc) metric(name string, compute func() float64) float64 {
+	key := fmt.Sprintf("heuristic:%s:%t", name, d.cjk)
+	return d.feats.Memo(key, func() interface{} { return compute() }).(float64)
+}
+
+func (h *HeuristicFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	return h.evaluate(newHeuristicDoc(text, params), params)
+}
+
+func (h *HeuristicFilter) evaluate(d *heuristicDoc, params map[string]interface{}) (bool, string) {
+	if h.applies != nil && !h.applies(d) { return true, fmt.Sprintf("skipped: not applicable to lang=%s", d.lang) }
+	measured := make([]string, 0, len(h.rules))
+	for _, r := range h.rules {
+		threshold := r.def
+		if v, ok := params[r.param].(float64); ok { threshold = v }
+		v := r.value(d)
+		if r.upper && v > threshold { return false, fmt.Sprintf("%s %f > %s %f", r.metric, v, r.param, threshold) }
+		if !r.upper && v < threshold { return false, fmt.Sprintf("%s %f < %s %f", r.metric, v, r.param, threshold) }
+		measured = append(measured, fmt.Sprintf("%s=%f", r.metric, v))
+	}
+	return true, "ok: " + strings.Join(measured, ", ")
+}
+
+var stopWords = map[string]map[string]bool{
+	"en": {"the": true, "be": true, "to": true, "of": true, "and": true, "that": true, "have": true, "with": true},
+	"zh": {"的": true, "了": true, "是": true, "在": true, "和": true, "有": true, "我": true, "不": true, "这": true, "也": true, "就": true, "都": true},
+	"ja": {"の": true, "に": true, "は": true, "を": true, "た": true, "が": true, "で": true, "て": true, "と": true, "し": true},
+}
+
+func lineFraction(d *heuristicDoc, name string, match func(line string) bool) float64 {
+	return d.metric(name, func() float64 {
+		lines := d.feats.Lines()
+		if len(lines) == 0 { return 0 }
+		n := 0
+		for _, line := range lines { if match(line) { n++ } }
+		return float64(n) / float64(len(lines))
+	})
+}
+
+var (
+	wordCountFilter = &HeuristicFilter{name: "word_count", rules: []rule{
+		{"word_count", "word_count_min", 50, false, wordCount},
+		{"word_count", "word_count_max", 100000, true, wordCount},
+	}}
+
+	meanWordLengthFilter = &HeuristicFilter{name: "mean_word_length", applies: func(d *heuristicDoc) bool { return !d.cjk }, rules: []rule{
+		{"mean_word_length", "mean_word_length_min", 3, false, meanWordLength},
+		{"mean_word_length", "mean_word_length_max", 10, true, meanWordLength},
+	}}
+
+	symbolWordRatioFilter = &HeuristicFilter{name: "symbol_word_ratio", rules: []rule{
+		{"symbol_word_ratio", "symbol_word_ratio_max", 0.1, true, func(d *heuristicDoc) float64 {
+			return d.metric("symbol_word_ratio", func() float64 {
+				words := d.words()
+				if len(words) == 0 { return 0 }
+				text := d.feats.Text
+				symbols := strings.Count(text, "#") + strings.Count(text, "...") + strings.Count(text, "…")
+				return float64(symbols) / float64(len(words))
+			})
+		}},
+	}}
+
+	bulletLinesFilter = &HeuristicFilter{name: "bullet_lines", rules: []rule{
+		{"bullet_line_fraction", "bullet_lines_max", 0.9, true, func(d *heuristicDoc) float64 {
+			return lineFraction(d, "bullet_lines", func(line string) bool {
+				r, _ := utf8.DecodeRuneInString(line)
+				return strings.ContainsRune("•●-*‣◦·▪", r)
+			})
+		}},
+	}}
+
+	ellipsisLinesFilter = &HeuristicFilter{name: "ellipsis_lines", rules: []rule{
+		{"ellipsis_line_fraction", "ellipsis_lines_max", 0.3, true, func(d *heuristicDoc) float64 {
+			return lineFraction(d, "ellipsis_lines", func(line string) bool {
+				return strings.HasSuffix(line, "...") || strings.HasSuffix(line, "…")
+			})
+		}},
+	}}
+
+	alphaWordsFilter = &HeuristicFilter{name: "alpha_words", rules: []rule{
+		{"alpha_word_fraction", "alpha_words_min", 0.8, false, func(d *heuristicDoc) float64 {
+			return d.metric("alpha_words", func() float64 {
+				words := d.words()
+				if len(words) == 0 { return 0 }
+				n := 0
+				for _, w := range words { if strings.IndexFunc(w, unicode.IsLetter) >= 0 { n++ } }
+				return float64(n) / float64(len(words))
+			})
+		}},
+	}}
+
+	stopWordsFilter = &HeuristicFilter{name: "stop_words", applies: func(d *heuristicDoc) bool { return stopWords[d.lang] != nil }, rules: []rule{
+		{"stop_word_count", "stop_words_min", 2, false, func(d *heuristicDoc) float64 {
+			return d.metric("stop_words:"+d.lang, func() float64 {
+				set, n := stopWords[d.lang], 0
+				for _, w := range d.words() { if set[strings.ToLower(w)] { n++ } }
+				return float64(n)
+			})
+		}},
+	}}
+
+	duplicateLinesFilter = &HeuristicFilter{name: "duplicate_lines", rules: []rule{
+		{"duplicate_line_fraction", "duplicate_lines_max", 0.3, true, func(d *heuristicDoc) float64 {
+			return d.metric("duplicate_lines", func() float64 { c, _ := nlp.DuplicateFraction(d.feats.Lines()); return c })
+		}},
+		{"duplicate_line_char_fraction", "duplicate_line_chars_max", 0.2, true, func(d *heuristicDoc) float64 {
+			return d.metric("duplicate_line_chars", func() float64 { _, c := nlp.DuplicateFraction(d.feats.Lines()); return c })
+		}},
+	}}
+
+	duplicateParagraphsFilter = &HeuristicFilter{name: "duplicate_paragraphs", rules: []rule{
+		{"duplicate_paragraph_fraction", "duplicate_paragraphs_max", 0.3, true, func(d *heuristicDoc) float64 {
+			return d.metric("duplicate_paragraphs", func() float64 { c, _ := nlp.DuplicateFraction(d.feats.Paragraphs()); return c })
+		}},
+		{"duplicate_paragraph_char_fraction", "duplicate_paragraph_chars_max", 0.2, true, func(d *heuristicDoc) float64 {
+			return d.metric("duplicate_paragraph_chars", func() float64 { _, c := nlp.DuplicateFraction(d.feats.Paragraphs()); return c })
+		}},
+	}}
+
+	topNGramFilter = &HeuristicFilter{name: "top_ngram_fraction", rules: []rule{
+		topNGramRule(2, 0.20), topNGramRule(3, 0.18), topNGramRule(4, 0.16),
+	}}
+
+	dupNGramFilter = &HeuristicFilter{name: "dup_ngram_fraction", rules: []rule{
+		dupNGramRule(5, 0.15), dupNGramRule(6, 0.14), dupNGramRule(7, 0.13), dupNGramRule(8, 0.12), dupNGramRule(9, 0.11), dupNGramRule(10, 0.10),
+	}}
+
+	terminalPunctLinesFilter = &HeuristicFilter{name: "terminal_punct_lines", rules: []rule{
+		{"terminal_punct_line_fraction", "terminal_punct_lines_min", 0.12, false, func(d *heuristicDoc) float64 {
+			return lineFraction(d, "terminal_punct_lines", nlp.EndsWithTerminalPunct)
+		}},
+	}}
+
+	loremIpsumFilter = &HeuristicFilter{name: "lorem_ipsum", rules: []rule{
+		{"lorem_ipsum_count", "lorem_ipsum_max", 0, true, func(d *heuristicDoc) float64 {
+			return d.metric("lorem_ipsum", func() float64 { return float64(strings.Count(strings.ToLower(d.feats.Text), "lorem ipsum")) })
+		}},
+	}}
+
+	// C4 删除含 javascript 的行 (多为"请启用 JavaScript"之类的提示)，行级清理见改写阶段，这里按比例整篇丢弃
+	javascriptLinesFilter = &HeuristicFilter{name: "javascript_lines", rules: []rule{
+		{"javascript_line_fraction", "javascript_lines_max", 0.1, true, func(d *heuristicDoc) float64 {
+			return lineFraction(d, "javascript_lines", func(line string) bool { return strings.Contains(strings.ToLower(line), "javascript") })
+		}},
+	}}
+
+	// C4 丢弃任何含花括号的页面；langid 判定为代码时不适用
+	curlyBraceFilter = &HeuristicFilter{name: "curly_brace_ratio", applies: func(d *heuristicDoc) bool { return d.lang != "code" }, rules: []rule{
+		{"curly_brace_ratio", "curly_brace_ratio_max", 0, true, func(d *heuristicDoc) float64 {
+			return d.metric("curly_brace_ratio", func() float64 {
+				n := utf8.RuneCountInString(d.feats.Text)
+				if n == 0 { return 0 }
+				return float64(strings.Count(d.feats.Text, "{")+strings.Count(d.feats.Text, "}")) / float64(n)
+			})
+		}},
+	}}
+)
+
+func wordCount(d *heuristicDoc) float64 { return float64(len(d.words())) }
+
+func meanWordLength(d *heuristicDoc) float64 {
+	return d.metric("mean_word_length", func() float64 {
+		words := d.words()
+		if len(words) == 0 { return 0 }
+		total := 0
+		for _, w := range words { total += utf8.RuneCountInString(w) }
+		return float64(total) / float64(len(words))
+	})
+}
+
+func topNGramRule(n int, def float64) rule {
+	name := fmt.Sprintf("top_%dgram_char_fraction", n)
+	return rule{name, fmt.Sprintf("top_%dgram_max", n), def, true, func(d *heuristicDoc) float64 {
+		return d.metric(name, func() float64 { return nlp.TopNGramCharFraction(d.words(), n) })
+	}}
+}
+
+func dupNGramRule(n int, def float64) rule {
+	name := fmt.Sprintf("dup_%dgram_char_fraction", n)
+	return rule{name, fmt.Sprintf("dup_%dgram_max", n), def, true, func(d *heuristicDoc) float64 {
+		return d.metric(name, func() float64 { return nlp.DuplicateNGramCharFraction(d.words(), n) })
+	}}
+}
+
+var gopherRules = []*HeuristicFilter{
+	wordCountFilter, meanWordLengthFilter, symbolWordRatioFilter, bulletLinesFilter, ellipsisLinesFilter, alphaWordsFilter, stopWordsFilter,
+	duplicateLinesFilter, duplicateParagraphsFilter, topNGramFilter, dupNGramFilter,
+}
+
+// HeuristicFilters 返回全部单条规则过滤器，供逐个注册
+func HeuristicFilters() []*HeuristicFilter {
+	return append(append([]*HeuristicFilter(nil), gopherRules...), terminalPunctLinesFilter, loremIpsumFilter, javascriptLinesFilter, curlyBraceFilter)
+}
+
+// GopherQualityFilter 依次应用 Gopher 的全部质量与重复度规则，报告第一条失败的规则
+type GopherQualityFilter struct{}
+
+func (f *GopherQualityFilter) Name() string { return "gopher_quality" }
+func (f *GopherQualityFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	d := newHeuristicDoc(text, params)
+	for _, h := range gopherRules {
+		if keep, reason := h.evaluate(d, params); !keep { return false, fmt.Sprintf("rule %s failed: %s", h.name, reason) }
+	}
+	return true, "ok"
+}
@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"graunt/pkg/langid"
+	"graunt/pkg/nlp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LangIDFilter 识别文档语种，丢弃不在 langid_allowed 中或置信度低于 langid_min_confidence 的文档，
+// 并以 lang / lang_confidence 元数据传给后续阶段。langid_corpus 为 Dir 下存放 <lang>.txt 语料的子目录名，用其训练的模型代替内置模型
+type LangIDFilter struct {
+	Dir     string
+	mu      sync.Mutex
+	seen    map[string]int
+	dropped map[string]int
+}
+
+type LangIDStats struct {
+	Detected map[string]int `json:"detected"`
+	Dropped  map[string]int `json:"dropped"`
+}
+
+func NewLangIDFilter(dir string) *LangIDFilter {
+	return &LangIDFilter{Dir: dir, seen: make(map[string]int), dropped: make(map[string]int)}
+}
+func (f *LangIDFilter) Name() string { return "langid" }
+
+// stringSet 接受逗号分隔的字符串或字符串数组
+func stringSet(v interface{}) map[string]bool {
+	set := make(map[string]bool)
+	switch x := v.(type) {
+	case string:
+		for _, s := range strings.Split(x, ",") { if s = strings.TrimSpace(s); s != "" { set[s] = true } }
+	case []interface{}:
+		for _, s := range x { if str, ok := s.(string); ok { set[str] = true } }
+	}
+	return set
+}
+
+// documentLang 优先使用上游 langid 阶段附加的 lang，否则就地识别并缓存在共享特征上
+func documentLang(feats *nlp.Features, params map[string]interface{}) string {
+	if lang, ok := params["lang"].(string); ok && lang != "" { return lang }
+	return feats.Memo("langid", func() interface{} { return langid.Detect(feats.Text).Lang }).(string)
+}
+
+func (f *LangIDFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _ := f.EvaluateWithMetadata(text, params)
+	return keep, reason
+}
+
+// model 返回内置模型，或用 langid_corpus 指定的语料训练的模型
+func (f *LangIDFilter) model(params map[string]interface{}) (*langid.Model, error) {
+	name, _ := params["langid_corpus"].(string)
+	if name == "" { return langid.Default(), nil }
+	if !validModelName(name) { return nil, fmt.Errorf("invalid langid corpus name '%s'", name) }
+	if f.Dir == "" { return nil, errors.New("langid corpus dir is not configured") }
+	m, err := langid.LoadCorpusDir(filepath.Join(f.Dir, name))
+	if err != nil { return nil, fmt.Errorf("failed to load langid corpus: %v", err) }
+	return m, nil
+}
+
+// Validate 在批处理开始前加载 langid_corpus
+func (f *LangIDFilter) Validate(params map[string]interface{}) error {
+	_, err := f.model(params)
+	return err
+}
+
+func (f *LangIDFilter) EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{}) {
+	keep, reason, meta, err := f.EvaluateChecked(text, params)
+	if err != nil { return false, err.Error(), nil }
+	return keep, reason, meta
+}
+
+func (f *LangIDFilter) EvaluateChecked(text string, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	model, err := f.model(params)
+	if err != nil { return false, "", nil, err }
+	minConf := 0.0
+	if v, ok := params["langid_min_confidence"].(float64); ok { minConf = v }
+	allowed := stringSet(params["langid_allowed"])
+
+	res := model.Detect(text)
+	meta := map[string]interface{}{"lang": res.Lang, "lang_confidence": res.Confidence}
+	keep, reason := true, fmt.Sprintf("lang=%s confidence=%f", res.Lang, res.Confidence)
+	switch {
+	case len(allowed) > 0 && !allowed[res.Lang]:
+		keep, reason = false, fmt.Sprintf("language '%s' not allowed (confidence %f)", res.Lang, res.Confidence)
+	case res.Confidence < minConf:
+		keep, reason = false, fmt.Sprintf("language '%s' confidence %f < %f", res.Lang, res.Confidence, minConf)
+	}
+
+	f.mu.Lock()
+	f.seen[res.Lang]++
+	if !keep { f.dropped[res.Lang]++ }
+	f.mu.Unlock()
+	return keep, reason, meta, nil
+}
+
+func (f *LangIDFilter) Stats() interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := LangIDStats{Detected: make(map[string]int, len(f.seen)), Dropped: make(map[string]int, len(f.dropped))}
+	for k, v := range f.seen { st.Detected[k] = v }
+	for k, v := range f.dropped { st.Dropped[k] = v }
+	return st
+}
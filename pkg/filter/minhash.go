@@ -1,60 +1,287 @@
 package filter
 
 import (
+	"graunt/pkg/minhash"
+	"graunt/pkg/nlp"
+	"encoding/json"
 	"fmt"
-	"hash/fnv"
-	"math"
-	"strings"
+	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
-const numHashFunctions = 100
+// maxNumPerm 限制签名长度，每个签名与每次比较的开销都与之成正比
+const maxNumPerm = 1024
+
+type lshConfig struct{ bands, rows int }
+
+// 每个签名存储最多保留的 LSH 索引数 (不同阈值/bands/rows 各需一个)，超出时淘汰最久未用的；
+// 参数与哈希器缓存超过上限时整体清空重建
+const (
+	maxLSHIndexes   = 4
+	maxCachedParams = 256
+	maxHashers      = 64
+)
+
+type lshEntry struct {
+	idx  *minhash.LSHIndex
+	used atomic.Int64
+}
+
+// signatureStore 保存同一哈希配置下的签名；不同 shingle/种子/排列数的签名之间不可比较。
+// 签名与索引由 MinHashFilter.mu 保护：查询持读锁，插入与建索引持写锁；计数器为原子变量
+type signatureStore struct {
+	Signatures [][]uint64
+	indexes    map[lshConfig]*lshEntry
+	clock      atomic.Int64
+	queries    atomic.Int64
+	compared   atomic.Int64
+	dupes      atomic.Int64
+	saved      int
+}
+
+// storeKey 区分命名空间 (不同数据集各自去重) 与签名配置
+type storeKey struct {
+	Namespace string
+	Options   minhash.Options
+}
 
 type MinHashFilter struct {
-	mu        sync.RWMutex
-	SignStore [][]uint32
+	mu       sync.RWMutex
+	hashers  map[minhash.Options]*minhash.Hasher
+	stores   map[storeKey]*signatureStore
+	paramsMu sync.Mutex
+	params   map[[2]float64]lshConfig
 }
 
-func NewMinHashFilter() *MinHashFilter { return &MinHashFilter{SignStore: make([][]uint32, 0)} }
+type MinHashStoreStats struct {
+	Namespace     string             `json:"namespace"`
+	Options       minhash.Options    `json:"options"`
+	Signatures    int                `json:"signatures"`
+	Queries       int                `json:"queries"`
+	Duplicates    int                `json:"duplicates"`
+	Comparisons   int                `json:"comparisons"`
+	AvgCandidates float64            `json:"avg_candidates"`
+	Indexes       []minhash.LSHStats `json:"indexes"`
+}
+
+func NewMinHashFilter() *MinHashFilter {
+	return &MinHashFilter{
+		hashers: make(map[minhash.Options]*minhash.Hasher),
+		stores:  make(map[storeKey]*signatureStore),
+		params:  make(map[[2]float64]lshConfig),
+	}
+}
 func (f *MinHashFilter) Name() string { return "minhash" }
 
-func getSignature(text string) []uint32 {
-	words := strings.Fields(strings.ToLower(text))
-	sig := make([]uint32, numHashFunctions)
-	for i := range sig { sig[i] = math.MaxUint32 }
+// seedParam 读取 64 位种子：JSON 数字解码为 float64 时超过 2^53 会丢失精度，大种子须以字符串或 json.Number 给出
+func seedParam(v interface{}) (uint64, error) {
+	switch s := v.(type) {
+	case float64:
+		if s < 0 || s > 1<<53 || s != float64(uint64(s)) { return 0, fmt.Errorf("minhash_seed %v must be an integer in [0, 2^53]; pass larger seeds as a string", s) }
+		return uint64(s), nil
+	case string:
+		return strconv.ParseUint(s, 10, 64)
+	case json.Number:
+		return strconv.ParseUint(s.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("invalid minhash_seed %v", v)
+}
+
+// hashOptions 从 minhash_shingle/minhash_ngram/minhash_seed/minhash_num_perm 读取签名配置
+func hashOptions(params map[string]interface{}) (minhash.Options, error) {
+	opts := minhash.DefaultOptions()
+	if v, ok := params["minhash_shingle"].(string); ok {
+		if !nlp.ValidShingle(v) { return opts, fmt.Errorf("unknown minhash_shingle '%s' (word, char, cjk)", v) }
+		opts.Shingle = v
+	}
+	if v, ok := params["minhash_ngram"].(float64); ok && v >= 1 { opts.NGram = int(v) }
+	if v, ok := params["minhash_seed"]; ok {
+		seed, err := seedParam(v)
+		if err != nil { return opts, err }
+		opts.Seed = seed
+	}
+	if v, ok := params["minhash_num_perm"].(float64); ok && v >= 1 {
+		if v > maxNumPerm { return opts, fmt.Errorf("minhash_num_perm %v exceeds %d", v, maxNumPerm) }
+		opts.NumPerm = int(v)
+	}
+	return opts, nil
+}
+
+func (f *MinHashFilter) Validate(params map[string]interface{}) error {
+	_, err := hashOptions(params)
+	return err
+}
 
-	for _, word := range words {
-		for i := 0; i < numHashFunctions; i++ {
-			h := fnv.New32a(); h.Write([]byte(word)); hashVal := h.Sum32() ^ uint32(i)
-			if hashVal < sig[i] { sig[i] = hashVal }
+func (f *MinHashFilter) hasher(opts minhash.Options) *minhash.Hasher {
+	f.mu.RLock()
+	h, ok := f.hashers[opts]
+	f.mu.RUnlock()
+	if ok { return h }
+	h = minhash.NewHasher(opts)
+	f.mu.Lock()
+	if len(f.hashers) >= maxHashers { f.hashers = make(map[minhash.Options]*minhash.Hasher) }
+	f.hashers[opts] = h
+	f.mu.Unlock()
+	return h
+}
+
+// lshParams 解析 minhash_bands/minhash_rows，未指定时按阈值推导最优配置
+func (f *MinHashFilter) lshParams(threshold float64, numPerm int, params map[string]interface{}) lshConfig {
+	b, okB := params["minhash_bands"].(float64)
+	r, okR := params["minhash_rows"].(float64)
+	if okB && okR && b >= 1 && r >= 1 && int(b)*int(r) <= numPerm { return lshConfig{int(b), int(r)} }
+	key := [2]float64{threshold, float64(numPerm)}
+	f.paramsMu.Lock()
+	defer f.paramsMu.Unlock()
+	cfg, ok := f.params[key]
+	if !ok {
+		cfg.bands, cfg.rows = minhash.OptimalParams(threshold, numPerm)
+		if len(f.params) >= maxCachedParams { f.params = make(map[[2]float64]lshConfig) }
+		f.params[key] = cfg
+	}
+	return cfg
+}
+
+func namespace(params map[string]interface{}) string {
+	if ns, ok := params["minhash_namespace"].(string); ok && ns != "" { return ns }
+	return "default"
+}
+
+func newSignatureStore(sigs [][]uint64) *signatureStore {
+	return &signatureStore{Signatures: sigs, indexes: make(map[lshConfig]*lshEntry)}
+}
+
+// store 返回 key 对应的签名存储 (需持有写锁)
+func (f *MinHashFilter) store(key storeKey) *signatureStore {
+	s, ok := f.stores[key]
+	if !ok {
+		s = newSignatureStore(nil)
+		f.stores[key] = s
+	}
+	return s
+}
+
+func (f *MinHashFilter) sharedStore(key storeKey) *signatureStore {
+	f.mu.RLock()
+	s, ok := f.stores[key]
+	f.mu.RUnlock()
+	if ok { return s }
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.store(key)
+}
+
+// addIndex 登记新建的索引，超出 maxLSHIndexes 时淘汰最久未用的 (需持有写锁)
+func (s *signatureStore) addIndex(cfg lshConfig, idx *minhash.LSHIndex) *minhash.LSHIndex {
+	if len(s.indexes) >= maxLSHIndexes {
+		var oldest lshConfig
+		t := int64(-1)
+		for c, e := range s.indexes {
+			if u := e.used.Load(); t < 0 || u < t { oldest, t = c, u }
 		}
+		delete(s.indexes, oldest)
 	}
-	return sig
+	e := &lshEntry{idx: idx}
+	e.used.Store(s.clock.Add(1))
+	s.indexes[cfg] = e
+	return idx
 }
 
-func jaccardSimilarity(sig1, sig2 []uint32) float64 {
-	matches := 0
-	for i := 0; i < numHashFunctions; i++ { if sig1[i] == sig2[i] { matches++ } }
-	return float64(matches) / float64(numHashFunctions)
+// index 返回指定 bands/rows 的 LSH 索引，首次使用时用已有签名回填 (需持有写锁)
+func (s *signatureStore) index(cfg lshConfig) *minhash.LSHIndex {
+	if e, ok := s.indexes[cfg]; ok { return e.idx }
+	idx := minhash.NewLSHIndex(cfg.bands, cfg.rows)
+	for id, sig := range s.Signatures { idx.Insert(id, sig) }
+	return s.addIndex(cfg, idx)
+}
+
+// buildIndex 在锁外用已有签名回填新索引，只在登记时短暂持写锁并补上期间新增的签名
+func (f *MinHashFilter) buildIndex(s *signatureStore, cfg lshConfig) {
+	f.mu.RLock()
+	_, ok := s.indexes[cfg]
+	sigs := s.Signatures[:len(s.Signatures):len(s.Signatures)]
+	f.mu.RUnlock()
+	if ok { return }
+	idx := minhash.NewLSHIndex(cfg.bands, cfg.rows)
+	for id, sig := range sigs { idx.Insert(id, sig) }
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := s.indexes[cfg]; ok { return }
+	for id := len(sigs); id < len(s.Signatures); id++ { idx.Insert(id, s.Signatures[id]) }
+	s.addIndex(cfg, idx)
 }
 
 func (f *MinHashFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
 	threshold := 0.8
 	if t, ok := params["minhash_threshold"].(float64); ok { threshold = t }
 
-	sig := getSignature(text)
-	f.mu.RLock()
-	for _, storedSig := range f.SignStore {
-		sim := jaccardSimilarity(sig, storedSig)
-		if sim >= threshold {
-			f.mu.RUnlock()
-			return false, fmt.Sprintf("duplicate found, similarity: %f", sim)
+	opts, err := hashOptions(params)
+	if err != nil { return false, err.Error() }
+	h := f.hasher(opts)
+	sig := h.Signature(text)
+	s := f.sharedStore(storeKey{namespace(params), h.Options})
+	cfg := f.lshParams(threshold, h.NumPerm, params)
+	s.queries.Add(1)
+
+	// 查询只持读锁；索引不存在 (或刚被淘汰) 时在锁外建好再查
+	var sim float64
+	var dup bool
+	var seen int
+	for {
+		f.mu.RLock()
+		e, ok := s.indexes[cfg]
+		if ok {
+			e.used.Store(s.clock.Add(1))
+			sim, dup = s.findDuplicate(e.idx, sig, threshold)
+			seen = len(s.Signatures)
 		}
+		f.mu.RUnlock()
+		if ok { break }
+		f.buildIndex(s, cfg)
+	}
+	if !dup {
+		// 释放读锁后可能有并发插入的近重复，插入前在写锁下与这部分新签名逐一比较
+		f.mu.Lock()
+		for id := seen; id < len(s.Signatures) && !dup; id++ {
+			s.compared.Add(1)
+			if j := minhash.JaccardSimilarity(sig, s.Signatures[id]); j >= threshold { sim, dup = j, true }
+		}
+		if !dup { s.insert(sig) }
+		f.mu.Unlock()
+	}
+	if dup {
+		s.dupes.Add(1)
+		return false, fmt.Sprintf("duplicate found, similarity: %f", sim)
 	}
-	f.mu.RUnlock()
-
-	f.mu.Lock()
-	f.SignStore = append(f.SignStore, sig)
-	f.mu.Unlock()
 	return true, "unique"
-}
\ No newline at end of file
+}
+
+// findDuplicate 在 idx 的候选中查找相似度不低于 threshold 的签名 (需持有读锁)
+func (s *signatureStore) findDuplicate(idx *minhash.LSHIndex, sig []uint64, threshold float64) (float64, bool) {
+	for _, id := range idx.Query(sig) {
+		s.compared.Add(1)
+		if sim := minhash.JaccardSimilarity(sig, s.Signatures[id]); sim >= threshold { return sim, true }
+	}
+	return 0, false
+}
+
+// insert 追加签名并写入所有索引 (需持有写锁)
+func (s *signatureStore) insert(sig []uint64) {
+	id := len(s.Signatures)
+	s.Signatures = append(s.Signatures, sig)
+	for _, e := range s.indexes { e.idx.Insert(id, sig) }
+}
+
+func (f *MinHashFilter) Stats() interface{} {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]MinHashStoreStats, 0, len(f.stores))
+	for key, s := range f.stores {
+		st := MinHashStoreStats{Namespace: key.Namespace, Options: key.Options, Signatures: len(s.Signatures), Queries: int(s.queries.Load()), Duplicates: int(s.dupes.Load()), Comparisons: int(s.compared.Load()), Indexes: make([]minhash.LSHStats, 0, len(s.indexes))}
+		if st.Queries > 0 { st.AvgCandidates = float64(st.Comparisons) / float64(st.Queries) }
+		for _, e := range s.indexes { st.Indexes = append(st.Indexes, e.idx.Stats()) }
+		out = append(out, st)
+	}
+	return out
+}
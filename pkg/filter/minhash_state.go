@@ -0,0 +1,178 @@
+package filter
+
+import (
+	"graunt/pkg/minhash"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 快照格式: zstd 压缩的 gob 流，先是 snapshotHeader，随后是若干批签名。
+// LSH 索引只记录 bands/rows，加载时由签名重建，因此不同机器的快照可以直接合并。
+const (
+	snapshotVersion = 1
+	snapshotChunk   = 10000
+	snapshotExt     = ".mhs"
+)
+
+type snapshotHeader struct {
+	Version   int
+	Namespace string
+	Options   minhash.Options
+	LSH       [][2]int
+	Count     int
+}
+
+type MinHashMergeStats struct {
+	Namespace  string `json:"namespace"`
+	Incoming   int    `json:"incoming"`
+	Added      int    `json:"added"`
+	Duplicates int    `json:"duplicates"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func snapshotFile(key storeKey) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%+v", key.Namespace, key.Options)
+	return fmt.Sprintf("%s-%08x%s", unsafeFileChars.ReplaceAllString(key.Namespace, "_"), h.Sum32(), snapshotExt)
+}
+
+func writeSnapshot(w io.Writer, hdr snapshotHeader, sigs [][]uint64) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil { return err }
+	enc := gob.NewEncoder(zw)
+	if err := enc.Encode(hdr); err != nil { zw.Close(); return err }
+	for start := 0; start < len(sigs); start += snapshotChunk {
+		end := start + snapshotChunk
+		if end > len(sigs) { end = len(sigs) }
+		if err := enc.Encode(sigs[start:end]); err != nil { zw.Close(); return err }
+	}
+	return zw.Close()
+}
+
+// validate 在分配内存、建立索引之前检查快照头，损坏或伪造的文件不能让合并接口或启动加载崩溃
+func (hdr snapshotHeader) validate() error {
+	if hdr.Count < 0 { return fmt.Errorf("invalid minhash snapshot: negative count %d", hdr.Count) }
+	if n := hdr.Options.NumPerm; n <= 0 || n > maxNumPerm { return fmt.Errorf("invalid minhash snapshot: num_perm %d out of range (1-%d)", n, maxNumPerm) }
+	if len(hdr.LSH) > maxLSHIndexes { return fmt.Errorf("invalid minhash snapshot: %d lsh indexes exceeds %d", len(hdr.LSH), maxLSHIndexes) }
+	for _, cfg := range hdr.LSH {
+		bands, rows := cfg[0], cfg[1]
+		if bands < 1 || rows < 1 || bands > hdr.Options.NumPerm || rows > hdr.Options.NumPerm || bands*rows > hdr.Options.NumPerm {
+			return fmt.Errorf("invalid minhash snapshot: lsh bands=%d rows=%d does not fit num_perm %d", bands, rows, hdr.Options.NumPerm)
+		}
+	}
+	return nil
+}
+
+func readSnapshot(r io.Reader) (snapshotHeader, [][]uint64, error) {
+	var hdr snapshotHeader
+	zr, err := zstd.NewReader(r)
+	if err != nil { return hdr, nil, err }
+	defer zr.Close()
+	dec := gob.NewDecoder(zr)
+	if err := dec.Decode(&hdr); err != nil { return hdr, nil, fmt.Errorf("invalid minhash snapshot: %v", err) }
+	if hdr.Version != snapshotVersion { return hdr, nil, fmt.Errorf("unsupported minhash snapshot version %d", hdr.Version) }
+	if err := hdr.validate(); err != nil { return hdr, nil, err }
+	// Count 来自文件，预分配不超过一批，其余按实际读到的签名增长
+	sigs := make([][]uint64, 0, min(hdr.Count, snapshotChunk))
+	for len(sigs) < hdr.Count {
+		var chunk [][]uint64
+		if err := dec.Decode(&chunk); err != nil { return hdr, nil, fmt.Errorf("truncated minhash snapshot: %v", err) }
+		sigs = append(sigs, chunk...)
+	}
+	for _, sig := range sigs {
+		if len(sig) != hdr.Options.NumPerm { return hdr, nil, errors.New("minhash snapshot signature length mismatch") }
+	}
+	return hdr, sigs, nil
+}
+
+func readSnapshotFile(path string) (snapshotHeader, [][]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil { return snapshotHeader{}, nil, err }
+	defer f.Close()
+	return readSnapshot(f)
+}
+
+// SaveState 将每个 (命名空间, 签名配置) 的存储写成独立快照文件，未变化的存储跳过
+func (f *MinHashFilter) SaveState(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil { return err }
+	type job struct {
+		key  storeKey
+		s    *signatureStore
+		hdr  snapshotHeader
+		sigs [][]uint64
+	}
+	var jobs []job
+	f.mu.RLock()
+	for key, s := range f.stores {
+		if s.saved == len(s.Signatures) { continue }
+		hdr := snapshotHeader{Version: snapshotVersion, Namespace: key.Namespace, Options: key.Options, Count: len(s.Signatures)}
+		for cfg := range s.indexes { hdr.LSH = append(hdr.LSH, [2]int{cfg.bands, cfg.rows}) }
+		jobs = append(jobs, job{key, s, hdr, s.Signatures[:len(s.Signatures):len(s.Signatures)]})
+	}
+	f.mu.RUnlock()
+
+	for _, j := range jobs {
+		path := filepath.Join(dir, snapshotFile(j.key))
+		tmp, err := os.CreateTemp(dir, ".minhash-*")
+		if err != nil { return err }
+		if err := writeSnapshot(tmp, j.hdr, j.sigs); err != nil { tmp.Close(); os.Remove(tmp.Name()); return err }
+		if err := tmp.Close(); err != nil { os.Remove(tmp.Name()); return err }
+		if err := os.Rename(tmp.Name(), path); err != nil { return err }
+		f.mu.Lock()
+		if j.s.saved < j.hdr.Count { j.s.saved = j.hdr.Count }
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// LoadState 从 dir 恢复全部快照，替换同名空间同配置的内存状态
+func (f *MinHashFilter) LoadState(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+snapshotExt))
+	if err != nil { return err }
+	for _, path := range files {
+		hdr, sigs, err := readSnapshotFile(path)
+		if err != nil { return fmt.Errorf("%s: %v", path, err) }
+		s := newSignatureStore(sigs)
+		s.saved = len(sigs)
+		for _, cfg := range hdr.LSH { s.index(lshConfig{cfg[0], cfg[1]}) }
+		f.mu.Lock()
+		f.stores[storeKey{hdr.Namespace, hdr.Options}] = s
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// MergeState 把其他节点生成的快照并入本地。给定 minhash_threshold 时逐条去重，
+// 只加入与现有签名不重复的部分；否则直接求并集。minhash_namespace 可改写目标命名空间。
+func (f *MinHashFilter) MergeState(path string, params map[string]interface{}) (interface{}, error) {
+	hdr, sigs, err := readSnapshotFile(path)
+	if err != nil { return nil, err }
+	key := storeKey{hdr.Namespace, hdr.Options}
+	if ns, ok := params["minhash_namespace"].(string); ok && ns != "" { key.Namespace = ns }
+	threshold, dedup := params["minhash_threshold"].(float64)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.store(key)
+	for _, cfg := range hdr.LSH { s.index(lshConfig{cfg[0], cfg[1]}) }
+	stats := MinHashMergeStats{Namespace: key.Namespace, Incoming: len(sigs)}
+	var cfg lshConfig
+	if dedup { cfg = f.lshParams(threshold, key.Options.NumPerm, params) }
+	for _, sig := range sigs {
+		if dedup {
+			if _, dup := s.findDuplicate(s.index(cfg), sig, threshold); dup { stats.Duplicates++; continue }
+		}
+		s.insert(sig)
+		stats.Added++
+	}
+	return stats, nil
+}
@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"graunt/pkg/minhash"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadSnapshotRejectsCorruptHeader(t *testing.T) {
+	opts := minhash.DefaultOptions()
+	for name, hdr := range map[string]snapshotHeader{
+		"negative count": {Count: -1, Options: opts},
+		"huge count":     {Count: 1 << 40, Options: opts},
+		"zero num_perm":  {Options: minhash.Options{NumPerm: 0}},
+		"big num_perm":   {Options: minhash.Options{NumPerm: maxNumPerm + 1}},
+		"zero bands":     {Options: opts, LSH: [][2]int{{0, 8}}},
+		"negative rows":  {Options: opts, LSH: [][2]int{{8, -1}}},
+		"bands*rows":     {Options: opts, LSH: [][2]int{{opts.NumPerm, 2}}},
+		"overflow":       {Options: opts, LSH: [][2]int{{1 << 32, 1 << 32}}},
+	} {
+		hdr.Version = snapshotVersion
+		var buf bytes.Buffer
+		if err := writeSnapshot(&buf, hdr, nil); err != nil { t.Fatal(err) }
+		_, _, err := readSnapshot(&buf)
+		if err == nil || !strings.Contains(err.Error(), "minhash snapshot") { t.Errorf("%s: err = %v, want invalid snapshot", name, err) }
+	}
+}
+
+func TestMergeStateRejectsCorruptFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSnapshot(&buf, snapshotHeader{Version: snapshotVersion, Options: minhash.DefaultOptions(), LSH: [][2]int{{0, 0}}}, nil); err != nil { t.Fatal(err) }
+	path := t.TempDir() + "/bad" + snapshotExt
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { t.Fatal(err) }
+	if _, err := NewMinHashFilter().MergeState(path, nil); err == nil { t.Fatal("MergeState accepted a corrupt snapshot") }
+}
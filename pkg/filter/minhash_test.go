@@ -0,0 +1,11 @@
+package filter
+
+import "testing"
+
+func TestMinHashValidateShingle(t *testing.T) {
+	f := NewMinHashFilter()
+	for _, mode := range []string{"word", "char", "cjk"} {
+		if err := f.Validate(map[string]interface{}{"minhash_shingle": mode}); err != nil { t.Errorf("%s: %v", mode, err) }
+	}
+	if err := f.Validate(map[string]interface{}{"minhash_shingle": "wrod"}); err == nil { t.Fatal("unknown minhash_shingle accepted") }
+}
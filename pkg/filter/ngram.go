@@ -1,8 +1,8 @@
 package filter
 
 import (
+	"graunt/pkg/nlp"
 	"fmt"
-	"strings"
 )
 
 type NGramFilter struct{}
@@ -13,17 +13,7 @@ func (f *NGramFilter) Evaluate(text string, params map[string]interface{}) (bool
 	if nv, ok := params["ngram_n"].(float64); ok { n = int(nv) }
 	if tv, ok := params["ngram_threshold"].(float64); ok { threshold = tv }
 
-	words := strings.Fields(text)
-	if len(words) < n { return true, "ok" }
-
-	ngrams := make(map[string]int)
-	total := len(words) - n + 1
-	for i := 0; i <= len(words)-n; i++ {
-		gram := strings.Join(words[i:i+n], " ")
-		ngrams[gram]++
-	}
-
-	ratio := 1.0 - (float64(len(ngrams)) / float64(total))
+	ratio := nlp.Analyze(text).NGramRepetition(n)
 	if ratio > threshold { return false, fmt.Sprintf("ngram rep ratio %f > %f", ratio, threshold) }
 	return true, "ok"
-}
\ No newline at end of file
+}
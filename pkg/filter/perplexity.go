@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"graunt/pkg/lm"
+	"graunt/pkg/nlp"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+var errNoModel = errors.New("no language model")
+
+// PerplexityFilter 按 CCNet 的做法用本地 Kneser-Ney 模型计算困惑度并分桶。
+// perplexity_model 为 Dir 下的模型名 (不含目录)：目录内按语种存放 <lang>.lm (缺省回退 default.lm)，单个文件则用于所有语种。
+// perplexity_min / perplexity_max / perplexity_buckets 可以是单个值，也可以是按语种给出的对象。
+type PerplexityFilter struct {
+	Dir string
+}
+
+func NewPerplexityFilter(dir string) *PerplexityFilter { return &PerplexityFilter{Dir: dir} }
+func (f *PerplexityFilter) Name() string { return "perplexity" }
+
+// modelPath 在 Dir 下解析模型。lang 可能来自上游输入的元数据，不是合法文件名时只找 default.lm
+func (f *PerplexityFilter) modelPath(name, lang string) (string, error) {
+	if !validModelName(name) { return "", fmt.Errorf("invalid language model name '%s'", name) }
+	if f.Dir == "" { return "", errors.New("language model dir is not configured") }
+	base := filepath.Join(f.Dir, name)
+	st, err := os.Stat(base)
+	if err != nil { return "", err }
+	if !st.IsDir() { return base, nil }
+	cands := []string{"default" + lm.Ext}
+	if validModelName(lang) { cands = append([]string{lang + lm.Ext}, cands...) }
+	for _, cand := range cands {
+		if p := filepath.Join(base, cand); fileExists(p) { return p, nil }
+	}
+	return "", errNoModel
+}
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
+
+// langValue 取按语种配置的参数：对象按 lang 取值，其他类型原样返回
+func langValue(v interface{}, lang string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if x, ok := m[lang]; ok { return x }
+		return m["default"]
+	}
+	return v
+}
+
+func bucketOf(ppl float64, thresholds interface{}) string {
+	bounds, ok := thresholds.([]interface{})
+	if !ok || len(bounds) == 0 { return "" }
+	names := []string{"head", "middle", "tail"}
+	for i, b := range bounds {
+		if v, ok := b.(float64); ok && ppl <= v && i < len(names)-1 { return names[i] }
+	}
+	return names[min(len(bounds), len(names)-1)]
+}
+
+// Validate 检查 perplexity_model 可以解析：单个文件时加载模型，目录时只检查存在，按语种的模型在用到时加载
+func (f *PerplexityFilter) Validate(params map[string]interface{}) error {
+	name, _ := params["perplexity_model"].(string)
+	if name == "" { return errors.New("perplexity_model param is required") }
+	path, err := f.modelPath(name, "")
+	if err == errNoModel { return nil }
+	if err != nil { return fmt.Errorf("failed to resolve language model: %v", err) }
+	if _, err := lm.Cached(path); err != nil { return fmt.Errorf("failed to load language model: %v", err) }
+	return nil
+}
+
+func (f *PerplexityFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _ := f.EvaluateWithMetadata(text, params)
+	return keep, reason
+}
+
+func (f *PerplexityFilter) EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{}) {
+	keep, reason, meta, err := f.EvaluateChecked(text, params)
+	if err != nil { return false, err.Error(), nil }
+	return keep, reason, meta
+}
+
+func (f *PerplexityFilter) EvaluateChecked(text string, params map[string]interface{}) (bool, string, map[string]interface{}, error) {
+	name, _ := params["perplexity_model"].(string)
+	if name == "" { return false, "", nil, errors.New("perplexity_model param is required") }
+	feats := nlp.Analyze(text)
+	lang := documentLang(feats, params)
+
+	path, err := f.modelPath(name, lang)
+	if err == errNoModel {
+		if missing, _ := params["perplexity_missing"].(string); missing == "drop" { return false, fmt.Sprintf("no language model for lang=%s", lang), nil, nil }
+		return true, fmt.Sprintf("skipped: no language model for lang=%s", lang), nil, nil
+	}
+	if err != nil { return false, "", nil, fmt.Errorf("failed to resolve language model: %v", err) }
+	model, err := lm.Cached(path)
+	if err != nil { return false, "", nil, fmt.Errorf("failed to load language model: %v", err) }
+
+	ppl := feats.Memo("perplexity:"+path, func() interface{} { return model.Perplexity(text) }).(float64)
+	// 空文本或只有标点时没有可打分的 token，困惑度为 +Inf，不能写入元数据 (JSON 无法编码)
+	if math.IsInf(ppl, 0) || math.IsNaN(ppl) { return false, fmt.Sprintf("perplexity undefined: no scorable tokens (lang=%s)", lang), nil, nil }
+	meta := map[string]interface{}{"perplexity": ppl}
+	bucket := bucketOf(ppl, langValue(params["perplexity_buckets"], lang))
+	if bucket != "" { meta["perplexity_bucket"] = bucket }
+
+	if v, ok := langValue(params["perplexity_max"], lang).(float64); ok && ppl > v { return false, fmt.Sprintf("perplexity %f > %f (lang=%s)", ppl, v, lang), meta, nil }
+	if v, ok := langValue(params["perplexity_min"], lang).(float64); ok && ppl < v { return false, fmt.Sprintf("perplexity %f < %f (lang=%s)", ppl, v, lang), meta, nil }
+	if keepBuckets := stringSet(params["perplexity_keep"]); bucket != "" && len(keepBuckets) > 0 && !keepBuckets[bucket] {
+		return false, fmt.Sprintf("perplexity %f in bucket %s (lang=%s)", ppl, bucket, lang), meta, nil
+	}
+	return true, fmt.Sprintf("perplexity=%f bucket=%s lang=%s", ppl, bucket, lang), meta, nil
+}
@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"graunt/pkg/lm"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPerplexityUnscorableDocument(t *testing.T) {
+	tr := lm.NewTrainer(3)
+	tr.Add("The cat sat on the mat. The dog sat on the rug. A cat and a dog.")
+	dir := t.TempDir()
+	if err := tr.Build().Save(filepath.Join(dir, "en"+lm.Ext)); err != nil { t.Fatal(err) }
+
+	f := NewPerplexityFilter(dir)
+	params := map[string]interface{}{"perplexity_model": "en" + lm.Ext}
+	for _, text := range []string{"...!!! ???", ""} {
+		keep, reason, meta := f.EvaluateWithMetadata(text, params)
+		if keep { t.Errorf("%q: kept, want dropped", text) }
+		if !strings.Contains(reason, "no scorable tokens") { t.Errorf("%q: reason = %q", text, reason) }
+		if _, err := json.Marshal(meta); err != nil { t.Errorf("%q: metadata not encodable: %v", text, err) }
+	}
+
+	keep, _, meta := f.EvaluateWithMetadata("The cat sat on the rug.", params)
+	if !keep { t.Error("scorable document dropped") }
+	if _, err := json.Marshal(meta); err != nil { t.Errorf("metadata not encodable: %v", err) }
+}
+
+func TestPerplexityModelStaysInDir(t *testing.T) {
+	dir := t.TempDir()
+	tr := lm.NewTrainer(3)
+	tr.Add("The cat sat on the mat.")
+	if err := tr.Build().Save(filepath.Join(dir, "web", "default"+lm.Ext)); err != nil { t.Fatal(err) }
+	outside := filepath.Join(t.TempDir(), "x"+lm.Ext)
+	if err := tr.Build().Save(outside); err != nil { t.Fatal(err) }
+
+	f := NewPerplexityFilter(dir)
+	for _, name := range []string{outside, "../x" + lm.Ext, "web/default" + lm.Ext, ".."} {
+		if err := f.Validate(map[string]interface{}{"perplexity_model": name}); err == nil { t.Errorf("perplexity_model %q accepted", name) }
+	}
+	// 上游元数据里的 lang 不能把路径带出模型目录
+	for _, lang := range []string{"../../x", "/etc/passwd", "en/../../x"} {
+		path, err := f.modelPath("web", lang)
+		if err != nil || path != filepath.Join(dir, "web", "default"+lm.Ext) { t.Errorf("lang %q: path = %q, %v", lang, path, err) }
+	}
+}
@@ -1,38 +1,26 @@
 package filter
 
 import (
+	"graunt/pkg/nlp"
 	"fmt"
-	"strings"
 )
 
 type ReadabilityFilter struct{}
 func (f *ReadabilityFilter) Name() string { return "readability_fog" }
 
-func countSyllables(word string) int {
-	word = strings.ToLower(word)
-	vowels := "aeiouy"
-	count := 0
-	prevVowel := false
-	for _, char := range word {
-		isVowel := strings.ContainsRune(vowels, char)
-		if isVowel && !prevVowel { count++ }
-		prevVowel = isVowel
-	}
-	if strings.HasSuffix(word, "e") { count-- }
-	if count <= 0 { count = 1 }
-	return count
-}
-
 func (f *ReadabilityFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
 	minFog := 6.0
 	if val, ok := params["min_fog_index"].(float64); ok { minFog = val }
+	// 迷雾指数基于英文音节，langid 判定为其他语种时不作判断
+	if lang, ok := params["lang"].(string); ok && lang != "en" { return true, fmt.Sprintf("skipped: fog index only applies to English (lang=%s)", lang) }
 
-	words := strings.Fields(text)
-	sentences := strings.Split(text, ".")
+	feats := nlp.Analyze(text)
+	words := feats.Words()
+	sentences := feats.Sentences()
 	if len(words) == 0 || len(sentences) == 0 { return false, "empty text" }
 
 	complexWords := 0
-	for _, word := range words { if countSyllables(word) >= 3 { complexWords++ } }
+	for _, word := range words { if nlp.CountSyllables(word) >= 3 { complexWords++ } }
 
 	wordsPerSentence := float64(len(words)) / float64(len(sentences))
 	percentageComplex := (float64(complexWords) / float64(len(words))) * 100
@@ -40,4 +28,4 @@ func (f *ReadabilityFilter) Evaluate(text string, params map[string]interface{})
 
 	if fogIndex < minFog { return false, fmt.Sprintf("fog index %f < threshold %f", fogIndex, minFog) }
 	return true, "ok"
-}
\ No newline at end of file
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"graunt/pkg/nlp"
+	"graunt/pkg/secrets"
+	"fmt"
+	"sync"
+)
+
+// SecretsFilter 丢弃泄漏密钥、令牌、私钥、带密码数据库地址等凭据的文档 (规则见 secrets.Catalog)。
+// secrets_rules / secrets_exclude_rules 选择规则，命中数超过 secrets_max (默认 0) 时丢弃；
+// 每处命中的规则 ID 与区间以 secrets 元数据返回。需要保留文档时改用 secrets_redact 改写。
+type SecretsFilter struct {
+	mu      sync.Mutex
+	checked int
+	dropped int
+	hits    map[string]int
+}
+
+type SecretsStats struct {
+	Checked int            `json:"checked"`
+	Dropped int            `json:"dropped"`
+	Hits    map[string]int `json:"hits"`
+}
+
+func NewSecretsFilter() *SecretsFilter { return &SecretsFilter{hits: make(map[string]int)} }
+func (f *SecretsFilter) Name() string  { return "secrets" }
+
+// secretRules 读取 secrets_rules / secrets_exclude_rules，未知规则 ID 报错
+func secretRules(params map[string]interface{}) (include, exclude map[string]bool, err error) {
+	include, exclude = stringSet(params["secrets_rules"]), stringSet(params["secrets_exclude_rules"])
+	for _, set := range []map[string]bool{include, exclude} {
+		for id := range set {
+			if !secrets.KnownRule(id) { return nil, nil, fmt.Errorf("unknown secret rule '%s'", id) }
+		}
+	}
+	return include, exclude, nil
+}
+
+// scanSecrets 在共享特征上缓存扫描结果，同一文档的过滤与改写只扫描一次
+func scanSecrets(text string, params map[string]interface{}) ([]secrets.Finding, error) {
+	include, exclude, err := secretRules(params)
+	if err != nil { return nil, err }
+	all := nlp.Analyze(text).Memo("secrets", func() interface{} { return secrets.Scan(text) }).([]secrets.Finding)
+	return secrets.Select(all, include, exclude), nil
+}
+
+func (f *SecretsFilter) Validate(params map[string]interface{}) error {
+	_, _, err := secretRules(params)
+	return err
+}
+
+func (f *SecretsFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	keep, reason, _ := f.EvaluateWithMetadata(text, params)
+	return keep, reason
+}
+
+func (f *SecretsFilter) EvaluateWithMetadata(text string, params map[string]interface{}) (bool, string, map[string]interface{}) {
+	findings, err := scanSecrets(text, params)
+	if err != nil { return false, err.Error(), nil }
+	maxHits := 0
+	if v, ok := params["secrets_max"].(float64); ok { maxHits = int(v) }
+	keep := len(findings) <= maxHits
+
+	f.mu.Lock()
+	f.checked++
+	if !keep { f.dropped++ }
+	for _, h := range findings { f.hits[h.RuleID]++ }
+	f.mu.Unlock()
+
+	if len(findings) == 0 { return true, "no secrets found", nil }
+	meta := map[string]interface{}{"secrets": findings, "secret_count": len(findings)}
+	reason := fmt.Sprintf("%d secrets found, first: %s at line %d", len(findings), findings[0].RuleID, findings[0].Line)
+	if keep { return true, reason, meta }
+	return false, reason, meta
+}
+
+func (f *SecretsFilter) Stats() interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := SecretsStats{Checked: f.checked, Dropped: f.dropped, Hits: make(map[string]int, len(f.hits))}
+	for k, v := range f.hits { st.Hits[k] = v }
+	return st
+}
@@ -0,0 +1,11 @@
+package filter
+
+import "testing"
+
+func TestSecretsValidateRules(t *testing.T) {
+	f := NewSecretsFilter()
+	if err := f.Validate(map[string]interface{}{"secrets_rules": []interface{}{"jwt", "github-token"}}); err != nil { t.Fatal(err) }
+	for _, key := range []string{"secrets_rules", "secrets_exclude_rules"} {
+		if err := f.Validate(map[string]interface{}{key: []interface{}{"jwt", "gihtub-token"}}); err == nil { t.Errorf("%s: unknown rule accepted", key) }
+	}
+}
@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"graunt/pkg/nlp"
+	"graunt/pkg/simhash"
+	"fmt"
+	"math"
+	"sync"
+)
+
+type simhashConfig struct {
+	Namespace string `json:"namespace"`
+	K         int    `json:"k"`
+	Blocks    int    `json:"blocks"`
+	Shingle   string `json:"shingle"`
+	NGram     int    `json:"ngram"`
+	Weight    string `json:"weight"`
+}
+
+type simhashStore struct {
+	index      *simhash.Index
+	df         map[string]int
+	docs       int
+	queries    int
+	candidates int
+	dupes      int
+}
+
+// SimHashFilter 用 64 位 SimHash 指纹做近重复过滤，与 MinHashFilter 共用 shingle 切分，便于在同一语料上对比
+type SimHashFilter struct {
+	mu     sync.Mutex
+	stores map[simhashConfig]*simhashStore
+}
+
+type SimHashStoreStats struct {
+	Config        simhashConfig `json:"config"`
+	Queries       int           `json:"queries"`
+	Duplicates    int           `json:"duplicates"`
+	AvgCandidates float64       `json:"avg_candidates"`
+	Index         simhash.Stats `json:"index"`
+}
+
+func NewSimHashFilter() *SimHashFilter { return &SimHashFilter{stores: make(map[simhashConfig]*simhashStore)} }
+func (f *SimHashFilter) Name() string { return "simhash" }
+
+// 索引为 C(blocks, blocks-k) 张置换表，每篇文档在每张表里各存一份：k 与 blocks 的上限防止一个请求建出巨大的索引
+const (
+	maxSimhashK      = 16
+	maxSimhashBlocks = 64
+	maxSimhashTables = 1000
+)
+
+// binomial 返回 C(n, r)，超过 limit 时返回 limit+1
+func binomial(n, r, limit int) int {
+	r = min(r, n-r)
+	c := 1
+	for i := 1; i <= r; i++ {
+		c = c * (n - r + i) / i
+		if c > limit { return limit + 1 }
+	}
+	return c
+}
+
+func simhashParams(params map[string]interface{}) (simhashConfig, error) {
+	cfg := simhashConfig{Namespace: "default", K: 3, Shingle: nlp.ShingleCJK, NGram: 1, Weight: "tf"}
+	if v, ok := params["simhash_namespace"].(string); ok && v != "" { cfg.Namespace = v }
+	if v, ok := params["simhash_k"].(float64); ok && v >= 0 {
+		if v > maxSimhashK { return cfg, fmt.Errorf("simhash_k %v exceeds %d", v, maxSimhashK) }
+		cfg.K = int(v)
+	}
+	if v, ok := params["simhash_shingle"].(string); ok { cfg.Shingle = v }
+	if v, ok := params["simhash_ngram"].(float64); ok && v >= 1 { cfg.NGram = int(v) }
+	if v, ok := params["simhash_weight"].(string); ok { cfg.Weight = v }
+	cfg.Blocks = cfg.K + 2
+	if v, ok := params["simhash_blocks"].(float64); ok && int(v) > cfg.K { cfg.Blocks = min(int(v), maxSimhashBlocks) }
+	if cfg.Weight != "tf" && cfg.Weight != "tfidf" { return cfg, fmt.Errorf("unknown simhash_weight '%s'", cfg.Weight) }
+	if n := binomial(cfg.Blocks, cfg.Blocks-cfg.K, maxSimhashTables); n > maxSimhashTables {
+		return cfg, fmt.Errorf("simhash_k=%d with simhash_blocks=%d needs more than %d tables", cfg.K, cfg.Blocks, maxSimhashTables)
+	}
+	return cfg, nil
+}
+
+func (f *SimHashFilter) Validate(params map[string]interface{}) error {
+	_, err := simhashParams(params)
+	return err
+}
+
+func (f *SimHashFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
+	cfg, err := simhashParams(params)
+	if err != nil { return false, err.Error() }
+
+	tf := make(map[string]float64)
+	for _, sh := range nlp.Analyze(text).Shingles(cfg.Shingle, cfg.NGram) { tf[sh]++ }
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.stores[cfg]
+	if !ok {
+		s = &simhashStore{index: simhash.NewIndex(cfg.K, cfg.Blocks), df: make(map[string]int)}
+		f.stores[cfg] = s
+	}
+
+	// tfidf 的文档频率随语料在线累积
+	weights := tf
+	if cfg.Weight == "tfidf" {
+		weights = make(map[string]float64, len(tf))
+		for feat, c := range tf { weights[feat] = c * (math.Log(float64(s.docs+1)/float64(s.df[feat]+1)) + 1) }
+		for feat := range tf { s.df[feat]++ }
+		s.docs++
+	}
+
+	fp := simhash.Fingerprint(weights)
+	id, dist, candidates := s.index.Nearest(fp)
+	s.queries++
+	s.candidates += candidates
+	if id >= 0 {
+		s.dupes++
+		return false, fmt.Sprintf("near duplicate found, hamming distance: %d", dist)
+	}
+	s.index.Add(fp)
+	return true, "unique"
+}
+
+func (f *SimHashFilter) Stats() interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]SimHashStoreStats, 0, len(f.stores))
+	for cfg, s := range f.stores {
+		st := SimHashStoreStats{Config: cfg, Queries: s.queries, Duplicates: s.dupes, Index: s.index.Stats()}
+		if s.queries > 0 { st.AvgCandidates = float64(s.candidates) / float64(s.queries) }
+		out = append(out, st)
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+package filter
+
+import "testing"
+
+func TestSimHashValidateK(t *testing.T) {
+	f := NewSimHashFilter()
+	if err := f.Validate(map[string]interface{}{"simhash_k": float64(maxSimhashK)}); err != nil { t.Fatal(err) }
+	if err := f.Validate(map[string]interface{}{"simhash_k": float64(maxSimhashK + 1)}); err == nil { t.Fatal("simhash_k above the limit accepted") }
+}
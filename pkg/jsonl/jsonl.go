@@ -0,0 +1,70 @@
+package jsonl
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const MaxLineBytes = 64 << 20
+
+type zstdReadCloser struct{ *zstd.Decoder }
+
+func (z zstdReadCloser) Close() error { z.Decoder.Close(); return nil }
+
+// NewReader 按编码 (""/identity、gzip、zstd) 包装输入流
+func NewReader(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case "gzip", "gz":
+		return gzip.NewReader(r)
+	case "zstd", "zst":
+		dec, err := zstd.NewReader(r)
+		if err != nil { return nil, err }
+		return zstdReadCloser{dec}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding '%s'", encoding)
+}
+
+// EncodingFromPath 根据文件扩展名推断压缩格式
+func EncodingFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return "gzip"
+	case strings.HasSuffix(path, ".zst"), strings.HasSuffix(path, ".zstd"):
+		return "zstd"
+	}
+	return ""
+}
+
+type fileReadCloser struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (r fileReadCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.f.Close()
+}
+
+// Open 打开 JSONL 分片文件，自动处理 .gz/.zst 压缩
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, err }
+	rc, err := NewReader(f, EncodingFromPath(path))
+	if err != nil { f.Close(); return nil, err }
+	return fileReadCloser{rc, f}, nil
+}
+
+// NewScanner 返回按行切分的 Scanner，单行上限 MaxLineBytes
+func NewScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), MaxLineBytes)
+	return sc
+}
@@ -0,0 +1,235 @@
+package langid
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 内置语料: corpus/<lang>.txt，每种语言若干段自然文本，code 为多种编程语言的代码片段
+//
+//go:embed corpus/*.txt
+var corpusFS embed.FS
+
+const (
+	Undetermined = "und"
+	order        = 3    // 字符 n-gram 的最大阶数
+	alpha        = 0.5  // 加性平滑
+	maxRunes     = 2048 // 长文档只看开头，足以判断语种
+	// evidence 为计算置信度时等效的特征个数上限：朴素贝叶斯在长文本上的后验会饱和到 1，
+	// 按特征数缩放对数似然后，置信度反映的是各语言之间的平均差距
+	evidence = 20
+)
+
+var names = map[string]string{
+	"en": "English", "zh": "Chinese", "ja": "Japanese", "ko": "Korean", "fr": "French",
+	"de": "German", "es": "Spanish", "ru": "Russian", "code": "source code",
+}
+
+// Name 返回语言代码对应的英文名称，未知代码原样返回
+func Name(lang string) string {
+	if n, ok := names[lang]; ok { return n }
+	return lang
+}
+
+type Result struct {
+	Lang       string             `json:"lang"`
+	Confidence float64            `json:"confidence"`
+	Scores     map[string]float64 `json:"scores,omitempty"` // 前三名候选的概率
+}
+
+// Model 是字符 1~3-gram 与书写系统特征上的多项式朴素贝叶斯模型。
+// 检测时先按文本的主书写系统筛出候选语言，再在候选之间比较 n-gram 似然，
+// 这样夹杂大量英文术语的中文技术文本不会被英文 n-gram 压过。
+type Model struct {
+	Langs   []string
+	Scripts []string // 各语言训练语料的主书写系统
+	logProb map[string][]float64
+}
+
+// script 把字符归入书写系统，作为额外特征：小语料下汉字本身多为未登录字符，但汉字/假名/谚文的比例足以区分中日韩
+func script(r rune) string {
+	switch {
+	case unicode.Is(unicode.Han, r): return "\x00han"
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r): return "\x00kana"
+	case unicode.Is(unicode.Hangul, r): return "\x00hangul"
+	case unicode.Is(unicode.Cyrillic, r): return "\x00cyrillic"
+	case unicode.Is(unicode.Latin, r): return "\x00latin"
+	case unicode.IsDigit(r): return "\x00digit"
+	case unicode.IsPunct(r), unicode.IsSymbol(r): return "\x00punct"
+	}
+	return ""
+}
+
+// normalize 转小写、数字归一为 0、空白压缩 (含换行的空白保留为换行，代码的行结构是重要特征)
+func normalize(text string) []rune {
+	out := make([]rune, 0, 64)
+	space := rune(0)
+	n := 0
+	for _, r := range text {
+		if n++; n > maxRunes { break }
+		if unicode.IsSpace(r) {
+			if r == '\n' || space == 0 { space = r }
+			continue
+		}
+		if space != 0 {
+			if space != '\n' { space = ' ' }
+			out = append(out, space)
+			space = 0
+		}
+		if unicode.IsDigit(r) { r = '0' }
+		out = append(out, unicode.ToLower(r))
+	}
+	return out
+}
+
+func isAlphabet(s string) bool { return s == "\x00latin" || s == "\x00cyrillic" }
+
+// dominantScript 以"字"为单位统计书写系统：汉字/假名/谚文每字计 1 并归为同一组 (由 n-gram 区分中日韩)，
+// 拼音文字每个词计 1。没有任何文字时返回空串。
+func dominantScript(text string) string {
+	units := make(map[string]int)
+	prev := ""
+	for _, r := range normalize(text) {
+		s := script(r)
+		if s == "" || s == "\x00digit" || s == "\x00punct" { prev = ""; continue }
+		if isAlphabet(s) {
+			if s != prev { units[s]++ }
+		} else {
+			units["\x00cjk"]++
+		}
+		prev = s
+	}
+	best := ""
+	for s, n := range units {
+		if best == "" || n > units[best] || (n == units[best] && s < best) { best = s }
+	}
+	return best
+}
+
+func features(text string, emit func(string)) {
+	runes := normalize(text)
+	for _, r := range runes {
+		if s := script(r); s != "" { emit(s) }
+	}
+	padded := append(append([]rune{' '}, runes...), ' ')
+	for n := 1; n <= order; n++ {
+		for i := 0; i+n <= len(padded); i++ {
+			if n == 1 && padded[i] == ' ' { continue }
+			emit(string(padded[i : i+n]))
+		}
+	}
+}
+
+// Train 由每种语言的样本文本训练模型
+func Train(samples map[string]string) *Model {
+	m := &Model{logProb: make(map[string][]float64)}
+	for lang := range samples { m.Langs = append(m.Langs, lang) }
+	sort.Strings(m.Langs)
+	for _, lang := range m.Langs { m.Scripts = append(m.Scripts, dominantScript(samples[lang])) }
+
+	counts := make(map[string][]float64)
+	totals := make([]float64, len(m.Langs))
+	for i, lang := range m.Langs {
+		features(samples[lang], func(f string) {
+			c, ok := counts[f]
+			if !ok { c = make([]float64, len(m.Langs)); counts[f] = c }
+			c[i]++
+			totals[i]++
+		})
+	}
+	v := float64(len(counts))
+	for f, c := range counts {
+		lp := make([]float64, len(m.Langs))
+		for i := range lp { lp[i] = math.Log((c[i] + alpha) / (totals[i] + alpha*v)) }
+		m.logProb[f] = lp
+	}
+	return m
+}
+
+// Detect 返回最可能的语言及置信度；文本不含文字或没有任何已知特征时返回 und
+func (m *Model) Detect(text string) Result {
+	main := dominantScript(text)
+	if main == "" { return Result{Lang: Undetermined} }
+	var cands []int
+	for i, s := range m.Scripts { if s == main { cands = append(cands, i) } }
+	if len(cands) == 0 { for i := range m.Langs { cands = append(cands, i) } }
+
+	scores := make([]float64, len(cands))
+	n := 0
+	features(text, func(f string) {
+		lp, ok := m.logProb[f]
+		if !ok { return }
+		n++
+		for j, i := range cands { scores[j] += lp[i] }
+	})
+	if n == 0 { return Result{Lang: Undetermined} }
+
+	scale := 1.0
+	if n > evidence { scale = evidence / float64(n) }
+	best := 0
+	for j := range scores {
+		scores[j] *= scale
+		if scores[j] > scores[best] { best = j }
+	}
+	sum := 0.0
+	probs := make([]float64, len(scores))
+	for j := range scores { probs[j] = math.Exp(scores[j] - scores[best]); sum += probs[j] }
+
+	ranked := make([]int, len(probs))
+	for j := range ranked { ranked[j] = j }
+	sort.Slice(ranked, func(a, b int) bool { return probs[ranked[a]] > probs[ranked[b]] })
+	res := Result{Lang: m.Langs[cands[best]], Confidence: probs[best] / sum, Scores: make(map[string]float64)}
+	for _, j := range ranked[:min(3, len(ranked))] { res.Scores[m.Langs[cands[j]]] = probs[j] / sum }
+	return res
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultModel *Model
+
+	modelsMu sync.Mutex
+	models   = make(map[string]*Model)
+)
+
+// Default 返回由内置语料训练的模型
+func Default() *Model {
+	defaultOnce.Do(func() {
+		samples := make(map[string]string)
+		files, _ := corpusFS.ReadDir("corpus")
+		for _, f := range files {
+			data, err := corpusFS.ReadFile("corpus/" + f.Name())
+			if err != nil { panic(err) }
+			samples[strings.TrimSuffix(f.Name(), ".txt")] = string(data)
+		}
+		defaultModel = Train(samples)
+	})
+	return defaultModel
+}
+
+// LoadCorpusDir 用目录下的 <lang>.txt 训练模型，按路径缓存
+func LoadCorpusDir(dir string) (*Model, error) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	if m, ok := models[dir]; ok { return m, nil }
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil { return nil, err }
+	if len(files) == 0 { return nil, fmt.Errorf("no <lang>.txt files in %s", dir) }
+	samples := make(map[string]string)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil { return nil, err }
+		samples[strings.TrimSuffix(filepath.Base(path), ".txt")] = string(data)
+	}
+	m := Train(samples)
+	models[dir] = m
+	return m, nil
+}
+
+func Detect(text string) Result { return Default().Detect(text) }
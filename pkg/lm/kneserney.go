@@ -0,0 +1,147 @@
+package lm
+
+import (
+	"graunt/pkg/nlp"
+	"math"
+	"strings"
+)
+
+const (
+	BOS = "<s>"
+	EOS = "</s>"
+)
+
+// Model 是插值 Kneser-Ney n-gram 语言模型。每阶保存折扣后的 n-gram 概率项
+// max(c-D,0)/c(h) 与上下文的回退权重 D*N1+(h·)/c(h)，查询时逐阶插值到均匀分布。
+type Model struct {
+	Order     int
+	Vocab     int                  // 训练语料的词表大小 (不含未登录词)
+	Discounts []float64            // 各阶折扣 D
+	Probs     []map[string]float32 // Probs[n-1]: n-gram -> 折扣后的概率项
+	Backoffs  []map[string]float32 // Backoffs[n-1]: (n-1) 阶上下文 -> 回退权重；Backoffs[0][""] 为一元模型回退到均匀分布的权重
+	Tokens    int                  // 训练 token 数
+}
+
+// Trainer 累积 n-gram 计数，Build 时计算 Kneser-Ney 的续接计数与折扣
+type Trainer struct {
+	Order  int
+	counts []map[string]int
+	tokens int
+}
+
+func NewTrainer(order int) *Trainer {
+	if order < 1 { order = 1 }
+	t := &Trainer{Order: order, counts: make([]map[string]int, order)}
+	for i := range t.counts { t.counts[i] = make(map[string]int) }
+	return t
+}
+
+// Tokenize 按句切分后分词并加上句首/句尾标记，训练与打分共用
+func Tokenize(text string) [][]string {
+	var out [][]string
+	for _, sent := range nlp.SplitSentences(text) {
+		toks := nlp.Tokens(sent)
+		if len(toks) == 0 { continue }
+		out = append(out, append(append([]string{BOS}, toks...), EOS))
+	}
+	return out
+}
+
+func (t *Trainer) Add(text string) {
+	for _, sent := range Tokenize(text) {
+		t.tokens += len(sent) - 1
+		for i := 1; i < len(sent); i++ {
+			for n := 1; n <= t.Order && i-n+1 >= 0; n++ { t.counts[n-1][strings.Join(sent[i-n+1:i+1], " ")]++ }
+		}
+	}
+}
+
+func (t *Trainer) Tokens() int { return t.tokens }
+
+func splitLast(gram string) (ctx, word string) {
+	if i := strings.LastIndexByte(gram, ' '); i >= 0 { return gram[:i], gram[i+1:] }
+	return "", gram
+}
+
+func dropFirst(gram string) string {
+	if i := strings.IndexByte(gram, ' '); i >= 0 { return gram[i+1:] }
+	return ""
+}
+
+func (t *Trainer) Build() *Model {
+	m := &Model{Order: t.Order, Discounts: make([]float64, t.Order), Probs: make([]map[string]float32, t.Order), Backoffs: make([]map[string]float32, t.Order), Tokens: t.tokens}
+
+	// 低阶使用续接计数 N1+(·g)；以 <s> 开头的 n-gram 没有左侧扩展，沿用原始计数
+	adjusted := make([]map[string]int, t.Order)
+	adjusted[t.Order-1] = t.counts[t.Order-1]
+	for n := t.Order - 1; n >= 1; n-- {
+		adj := make(map[string]int)
+		for gram := range t.counts[n] { adj[dropFirst(gram)]++ }
+		for gram, c := range t.counts[n-1] { if strings.HasPrefix(gram, BOS+" ") || gram == BOS { adj[gram] = c } }
+		adjusted[n-1] = adj
+	}
+
+	for n := 1; n <= t.Order; n++ {
+		counts := adjusted[n-1]
+		n1, n2 := 0, 0
+		for _, c := range counts {
+			if c == 1 { n1++ } else if c == 2 { n2++ }
+		}
+		d := 0.5
+		if n1 > 0 && n1+2*n2 > 0 { d = float64(n1) / float64(n1+2*n2) }
+		m.Discounts[n-1] = d
+
+		ctxSum := make(map[string]int)
+		ctxTypes := make(map[string]int)
+		for gram, c := range counts {
+			ctx, _ := splitLast(gram)
+			ctxSum[ctx] += c
+			ctxTypes[ctx]++
+		}
+		probs := make(map[string]float32, len(counts))
+		for gram, c := range counts {
+			ctx, _ := splitLast(gram)
+			probs[gram] = float32(math.Max(float64(c)-d, 0) / float64(ctxSum[ctx]))
+		}
+		backoffs := make(map[string]float32, len(ctxSum))
+		for ctx, sum := range ctxSum { backoffs[ctx] = float32(d * float64(ctxTypes[ctx]) / float64(sum)) }
+		m.Probs[n-1], m.Backoffs[n-1] = probs, backoffs
+		if n == 1 { m.Vocab = len(counts) }
+	}
+	return m
+}
+
+// Prob 返回 P(word | history)，history 取最后 Order-1 个词
+func (m *Model) Prob(history []string, word string) float64 {
+	p := 1.0 / float64(m.Vocab+1)
+	if bo, ok := m.Backoffs[0][""]; ok { p *= float64(bo) }
+	p += float64(m.Probs[0][word])
+	for n := 2; n <= m.Order && n-1 <= len(history); n++ {
+		ctx := strings.Join(history[len(history)-n+1:], " ")
+		bo, ok := m.Backoffs[n-1][ctx]
+		if !ok { break }
+		p = float64(m.Probs[n-1][ctx+" "+word]) + float64(bo)*p
+	}
+	return p
+}
+
+// LogProb 返回文本的自然对数概率与被打分的 token 数 (含句尾标记)
+func (m *Model) LogProb(text string) (float64, int) {
+	total, n := 0.0, 0
+	for _, sent := range Tokenize(text) {
+		for i := 1; i < len(sent); i++ {
+			start := i - m.Order + 1
+			if start < 0 { start = 0 }
+			total += math.Log(m.Prob(sent[start:i], sent[i]))
+			n++
+		}
+	}
+	return total, n
+}
+
+// Perplexity 返回 exp(-平均对数概率)，空文本返回 +Inf
+func (m *Model) Perplexity(text string) float64 {
+	lp, n := m.LogProb(text)
+	if n == 0 { return math.Inf(1) }
+	return math.Exp(-lp / float64(n))
+}
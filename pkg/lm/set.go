@@ -0,0 +1,80 @@
+package lm
+
+import (
+	"graunt/pkg/jsonl"
+	"graunt/pkg/langid"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+type ModelInfo struct {
+	Lang      string `json:"lang"`
+	Path      string `json:"path"`
+	Order     int    `json:"order"`
+	Documents int    `json:"documents"`
+	Tokens    int    `json:"tokens"`
+	Vocab     int    `json:"vocab"`
+}
+
+// SetTrainer 按语种分别训练模型：Lang 非空时全部文档归入该语种，否则逐篇用 langid 判定
+type SetTrainer struct {
+	Order    int
+	Lang     string
+	trainers map[string]*Trainer
+	docs     map[string]int
+}
+
+func NewSetTrainer(order int, lang string) *SetTrainer {
+	return &SetTrainer{Order: order, Lang: lang, trainers: make(map[string]*Trainer), docs: make(map[string]int)}
+}
+
+func (s *SetTrainer) Add(text string) {
+	lang := s.Lang
+	if lang == "" { lang = langid.Detect(text).Lang }
+	if lang == langid.Undetermined { return }
+	t, ok := s.trainers[lang]
+	if !ok { t = NewTrainer(s.Order); s.trainers[lang] = t }
+	t.Add(text)
+	s.docs[lang]++
+}
+
+// AddJSONL 读取 {"text"} 每行一条的 JSONL (可 .gz/.zst 压缩)
+func (s *SetTrainer) AddJSONL(path string) (int, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return 0, err }
+	defer in.Close()
+	sc := jsonl.NewScanner(in)
+	n, line := 0, 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 { continue }
+		var doc struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil { return n, fmt.Errorf("%s:%d: %v", path, line, err) }
+		s.Add(doc.Text)
+		n++
+	}
+	return n, sc.Err()
+}
+
+// Save 把 token 数不少于 minTokens 的语种模型写成 dir/<lang>.lm
+func (s *SetTrainer) Save(dir string, minTokens int) ([]ModelInfo, error) {
+	langs := make([]string, 0, len(s.trainers))
+	for lang := range s.trainers { langs = append(langs, lang) }
+	sort.Strings(langs)
+	var out []ModelInfo
+	for _, lang := range langs {
+		t := s.trainers[lang]
+		if t.Tokens() < minTokens || t.Tokens() == 0 { continue }
+		m := t.Build()
+		path := filepath.Join(dir, lang+Ext)
+		if err := m.Save(path); err != nil { return out, err }
+		out = append(out, ModelInfo{Lang: lang, Path: path, Order: m.Order, Documents: s.docs[lang], Tokens: m.Tokens, Vocab: m.Vocab})
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package minhash
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// OptimalParams 给定相似度阈值与签名长度，选取使假阳性与假阴性概率 (各占一半权重) 之和最小的 bands/rows
+func OptimalParams(threshold float64, numPerm int) (bands, rows int) {
+	minErr := math.MaxFloat64
+	for b := 1; b <= numPerm; b++ {
+		for r := 1; b*r <= numPerm; r++ {
+			fp := integrate(func(s float64) float64 { return 1 - math.Pow(1-math.Pow(s, float64(r)), float64(b)) }, 0, threshold)
+			fn := integrate(func(s float64) float64 { return math.Pow(1-math.Pow(s, float64(r)), float64(b)) }, threshold, 1)
+			if e := 0.5*fp + 0.5*fn; e < minErr { minErr, bands, rows = e, b, r }
+		}
+	}
+	return bands, rows
+}
+
+func integrate(f func(float64) float64, a, b float64) float64 {
+	const steps = 100
+	width := (b - a) / steps
+	area := 0.0
+	for i := 0; i < steps; i++ { area += f(a+(float64(i)+0.5)*width) * width }
+	return area
+}
+
+// LSHIndex 本身不加锁：Query 之间可以并发，Insert 须与其他调用互斥 (调用方持读写锁，查询用读锁、插入用写锁)
+type LSHIndex struct {
+	Bands   int
+	Rows    int
+	buckets []map[uint64][]int
+	size    int
+}
+
+type LSHStats struct {
+	Bands      int     `json:"bands"`
+	Rows       int     `json:"rows"`
+	Threshold  float64 `json:"approx_threshold"`
+	Entries    int     `json:"entries"`
+	Buckets    int     `json:"buckets"`
+	MaxBucket  int     `json:"max_bucket"`
+	MeanBucket float64 `json:"mean_bucket"`
+}
+
+func NewLSHIndex(bands, rows int) *LSHIndex {
+	idx := &LSHIndex{Bands: bands, Rows: rows, buckets: make([]map[uint64][]int, bands)}
+	for i := range idx.buckets { idx.buckets[i] = make(map[uint64][]int) }
+	return idx
+}
+
+func (idx *LSHIndex) bandKey(sig []uint64, band int) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, v := range sig[band*idx.Rows : (band+1)*idx.Rows] {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func (idx *LSHIndex) Insert(id int, sig []uint64) {
+	for b := range idx.buckets {
+		key := idx.bandKey(sig, b)
+		idx.buckets[b][key] = append(idx.buckets[b][key], id)
+	}
+	idx.size++
+}
+
+// Query 返回至少在一个 band 上完全相同的候选 id，调用方需再用完整签名校验相似度
+func (idx *LSHIndex) Query(sig []uint64) []int {
+	seen := make(map[int]struct{})
+	var out []int
+	for b := range idx.buckets {
+		for _, id := range idx.buckets[b][idx.bandKey(sig, b)] {
+			if _, ok := seen[id]; ok { continue }
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (idx *LSHIndex) Stats() LSHStats {
+	st := LSHStats{Bands: idx.Bands, Rows: idx.Rows, Entries: idx.size}
+	st.Threshold = math.Pow(1/float64(idx.Bands), 1/float64(idx.Rows))
+	total := 0
+	for _, band := range idx.buckets {
+		for _, ids := range band {
+			st.Buckets++
+			total += len(ids)
+			if len(ids) > st.MaxBucket { st.MaxBucket = len(ids) }
+		}
+	}
+	if st.Buckets > 0 { st.MeanBucket = float64(total) / float64(st.Buckets) }
+	return st
+}
@@ -1,43 +1,103 @@
 package minhash
 
 import (
+	"graunt/pkg/nlp"
 	"hash/fnv"
 	"math"
-	"strings"
+	"math/bits"
 )
 
 const NumHashFunctions = 100
 
-func GetSignature(text string) []uint32 {
-	words := strings.Fields(strings.ToLower(text))
-	sig := make([]uint32, NumHashFunctions)
-	for i := range sig {
-		sig[i] = math.MaxUint32
+// mersennePrime = 2^61 - 1，通用哈希 (a*x + b) mod p 的模数
+const mersennePrime = (1 << 61) - 1
+
+type Options struct {
+	NumPerm int    `json:"num_perm"`
+	Shingle string `json:"shingle"`
+	NGram   int    `json:"ngram"`
+	Seed    uint64 `json:"seed"`
+}
+
+func DefaultOptions() Options {
+	return Options{NumPerm: NumHashFunctions, Shingle: nlp.ShingleCJK, NGram: 3, Seed: 1}
+}
+
+// Hasher 持有由种子确定的 NumPerm 组 (a, b) 系数，相同 Options 在任意进程中得到相同签名
+type Hasher struct {
+	Options
+	a, b []uint64
+}
+
+func NewHasher(opts Options) *Hasher {
+	def := DefaultOptions()
+	if opts.NumPerm <= 0 { opts.NumPerm = def.NumPerm }
+	if opts.Shingle == "" { opts.Shingle = def.Shingle }
+	if opts.NGram <= 0 { opts.NGram = def.NGram }
+
+	h := &Hasher{Options: opts, a: make([]uint64, opts.NumPerm), b: make([]uint64, opts.NumPerm)}
+	state := opts.Seed
+	for i := 0; i < opts.NumPerm; i++ {
+		h.a[i] = splitmix64(&state)%(mersennePrime-1) + 1
+		h.b[i] = splitmix64(&state) % mersennePrime
 	}
+	return h
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func mod61(x uint64) uint64 {
+	x = (x & mersennePrime) + (x >> 61)
+	if x >= mersennePrime { x -= mersennePrime }
+	return x
+}
+
+// permute 计算 (a*x + b) mod 2^61-1，a、x、b 均小于模数
+func permute(a, x, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, x)
+	r := mod61((lo & mersennePrime) + (lo >> 61) + (hi << 3))
+	return mod61(r + b)
+}
+
+func hashShingle(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mod61(h.Sum64())
+}
 
-	for _, word := range words {
-		for i := 0; i < NumHashFunctions; i++ {
-			h := hashString(word, uint32(i))
-			if h < sig[i] {
-				sig[i] = h
-			}
+func (h *Hasher) Signature(text string) []uint64 {
+	sig := make([]uint64, h.NumPerm)
+	for i := range sig { sig[i] = math.MaxUint64 }
+
+	seen := make(map[string]struct{})
+	for _, sh := range nlp.Analyze(text).Shingles(h.Shingle, h.NGram) {
+		if _, ok := seen[sh]; ok { continue }
+		seen[sh] = struct{}{}
+		x := hashShingle(sh)
+		for i := range sig {
+			if v := permute(h.a[i], x, h.b[i]); v < sig[i] { sig[i] = v }
 		}
 	}
 	return sig
 }
 
-func JaccardSimilarity(sig1, sig2 []uint32) float64 {
+var defaultHasher = NewHasher(DefaultOptions())
+
+func GetSignature(text string) []uint64 { return defaultHasher.Signature(text) }
+
+func JaccardSimilarity(sig1, sig2 []uint64) float64 {
+	n := len(sig1)
+	if len(sig2) < n { n = len(sig2) }
+	if n == 0 { return 0 }
 	matches := 0
-	for i := 0; i < NumHashFunctions; i++ {
-		if sig1[i] == sig2[i] {
-			matches++
-		}
+	for i := 0; i < n; i++ {
+		if sig1[i] == sig2[i] { matches++ }
 	}
-	return float64(matches) / float64(NumHashFunctions)
+	return float64(matches) / float64(n)
 }
-
-func hashString(s string, seed uint32) uint32 {
-	h := fnv.New32a()
-	h.Write([]byte(s))
-	return h.Sum32() ^ seed
-}
\ No newline at end of file
@@ -1,33 +1,19 @@
 package nlp
 
-import (
-	"fmt"
-	"math"
-)
-
-type EntropyFilter struct{}
-
-func (f *EntropyFilter) Name() string { return "entropy" }
-
-func (f *EntropyFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
-	threshold := 2.0
-	if t, ok := params["entropy_threshold"].(float64); ok { threshold = t }
+import "math"
 
+// ShannonEntropy 计算字符分布的香农熵 (bits)
+func ShannonEntropy(text string) float64 {
 	freq := make(map[rune]float64)
 	total := 0
-	for _, char := range text {
-		freq[char]++
-		total++
-	}
+	for _, char := range text { freq[char]++; total++ }
 
 	entropy := 0.0
 	for _, count := range freq {
 		prob := count / float64(total)
 		entropy -= prob * math.Log2(prob)
 	}
+	return entropy
+}
 
-	if entropy < threshold {
-		return false, fmt.Sprintf("entropy %f < %f", entropy, threshold)
-	}
-	return true, "ok"
-}
\ No newline at end of file
+func CalculateShannonEntropy(text string) float64 { return Analyze(text).Entropy() }
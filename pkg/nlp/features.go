@@ -0,0 +1,145 @@
+package nlp
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// Features 惰性计算并缓存一段文本的特征。流水线中各过滤器通过 Analyze 拿到同一实例，
+// 分词、熵、n-gram 统计等只计算一次。返回的切片为共享数据，调用方不得修改。
+type Features struct {
+	Text string
+
+	wordsOnce, tokensOnce, sentencesOnce, entropyOnce, linesOnce, paragraphsOnce sync.Once
+	words, tokens, sentences, lines, paragraphs                                []string
+	entropy                                                                    float64
+
+	mu       sync.Mutex
+	ngrams   map[int]float64
+	shingles map[shingleKey][]string
+	memo     map[string]interface{}
+}
+
+type shingleKey struct {
+	mode string
+	k    int
+}
+
+func NewFeatures(text string) *Features { return &Features{Text: text} }
+
+func (f *Features) Words() []string {
+	f.wordsOnce.Do(func() { f.words = Words(f.Text) })
+	return f.words
+}
+
+func (f *Features) Tokens() []string {
+	f.tokensOnce.Do(func() { f.tokens = Tokens(f.Text) })
+	return f.tokens
+}
+
+func (f *Features) Sentences() []string {
+	f.sentencesOnce.Do(func() { f.sentences = SplitSentences(f.Text) })
+	return f.sentences
+}
+
+func (f *Features) Lines() []string {
+	f.linesOnce.Do(func() { f.lines = Lines(f.Text) })
+	return f.lines
+}
+
+func (f *Features) Paragraphs() []string {
+	f.paragraphsOnce.Do(func() { f.paragraphs = Paragraphs(f.Text) })
+	return f.paragraphs
+}
+
+func (f *Features) Entropy() float64 {
+	f.entropyOnce.Do(func() { f.entropy = ShannonEntropy(f.Text) })
+	return f.entropy
+}
+
+// NGramRepetition 返回词级 n-gram 重复率
+func (f *Features) NGramRepetition(n int) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v, ok := f.ngrams[n]; ok { return v }
+	if f.ngrams == nil { f.ngrams = make(map[int]float64) }
+	v := RepetitionRatio(f.Words(), n)
+	f.ngrams[n] = v
+	return v
+}
+
+func (f *Features) Shingles(mode string, k int) []string {
+	key := shingleKey{mode, k}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sh, ok := f.shingles[key]; ok { return sh }
+	if f.shingles == nil { f.shingles = make(map[shingleKey][]string) }
+	sh := Shingles(f.Text, mode, k)
+	f.shingles[key] = sh
+	return sh
+}
+
+// Memo 缓存以 key 标识的派生特征，供各过滤器共享不在本包内定义的指标。
+// compute 在锁外执行，并发时可能重复计算，但结果相同。
+func (f *Features) Memo(key string, compute func() interface{}) interface{} {
+	f.mu.Lock()
+	v, ok := f.memo[key]
+	f.mu.Unlock()
+	if ok { return v }
+	v = compute()
+	f.mu.Lock()
+	if f.memo == nil { f.memo = make(map[string]interface{}) }
+	f.memo[key] = v
+	f.mu.Unlock()
+	return v
+}
+
+// FeatureCacheSize 需大于同时在处理的文档数 (批处理 worker 数)，否则同一文档在各阶段间会被淘汰。
+// 缓存同时受文本总字节数 FeatureCacheBytes 限制，超过 FeatureCacheMaxText 的文本不进缓存 (各阶段各自计算)
+const (
+	FeatureCacheSize    = 1024
+	FeatureCacheBytes   = 64 << 20
+	FeatureCacheMaxText = 1 << 20
+)
+
+type cacheKey [sha256.Size]byte
+
+type featureCache struct {
+	mu       sync.Mutex
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+	size     int
+	bytes    int
+	maxBytes int
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	features *Features
+}
+
+var cache = &featureCache{entries: make(map[cacheKey]*list.Element), order: list.New(), size: FeatureCacheSize, maxBytes: FeatureCacheBytes}
+
+// Analyze 返回 text 的特征，最近使用过的文本命中 LRU 缓存 (按文本的 SHA-256 索引)
+func Analyze(text string) *Features {
+	if len(text) > FeatureCacheMaxText { return NewFeatures(text) }
+	key := cacheKey(sha256.Sum256([]byte(text)))
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if el, ok := cache.entries[key]; ok {
+		cache.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).features
+	}
+	f := NewFeatures(text)
+	cache.entries[key] = cache.order.PushFront(&cacheEntry{key, f})
+	cache.bytes += len(text)
+	for cache.order.Len() > cache.size || cache.bytes > cache.maxBytes {
+		last := cache.order.Back()
+		e := last.Value.(*cacheEntry)
+		cache.order.Remove(last)
+		delete(cache.entries, e.key)
+		cache.bytes -= len(e.features.Text)
+	}
+	return f
+}
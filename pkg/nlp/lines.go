@@ -0,0 +1,43 @@
+package nlp
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// Lines 返回去除首尾空白后的非空行
+func Lines(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" { out = append(out, line) }
+	}
+	return out
+}
+
+// Paragraphs 按空行切分段落
+func Paragraphs(text string) []string {
+	var out []string
+	for _, p := range paragraphBreak.Split(text, -1) {
+		if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+	}
+	return out
+}
+
+// DuplicateFraction 返回与前文重复的单元 (行/段落) 所占的个数比例与字符比例
+func DuplicateFraction(units []string) (count, chars float64) {
+	if len(units) == 0 { return 0, 0 }
+	seen := make(map[string]bool, len(units))
+	dup, dupChars, total := 0, 0, 0
+	for _, u := range units {
+		n := utf8.RuneCountInString(u)
+		total += n
+		if seen[u] { dup++; dupChars += n; continue }
+		seen[u] = true
+	}
+	count = float64(dup) / float64(len(units))
+	if total > 0 { chars = float64(dupChars) / float64(total) }
+	return count, chars
+}
@@ -1,33 +1,59 @@
 package nlp
 
 import (
-	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
-type NGramFilter struct{}
+// NGramCounts 统计 units 中连续 n 元组的出现次数
+func NGramCounts(units []string, n int) map[string]int {
+	counts := make(map[string]int)
+	if n <= 0 { return counts }
+	for i := 0; i+n <= len(units); i++ { counts[strings.Join(units[i:i+n], " ")]++ }
+	return counts
+}
 
-func (f *NGramFilter) Name() string { return "ngram" }
+// RepetitionRatio 返回重复 n 元组占全部 n 元组的比例，units 不足 n 个时为 0
+func RepetitionRatio(units []string, n int) float64 {
+	if n <= 0 || len(units) < n { return 0 }
+	total := len(units) - n + 1
+	return 1.0 - float64(len(NGramCounts(units, n)))/float64(total)
+}
 
-func (f *NGramFilter) Evaluate(text string, params map[string]interface{}) (bool, string) {
-	n := 3
-	threshold := 0.5
-	if nv, ok := params["ngram_n"].(float64); ok { n = int(nv) }
-	if tv, ok := params["ngram_threshold"].(float64); ok { threshold = tv }
+// CalculateNGramRepetitionRatio 以空白切分的词计算 n-gram 重复率
+func CalculateNGramRepetitionRatio(text string, n int) float64 { return Analyze(text).NGramRepetition(n) }
 
-	words := strings.Fields(text)
-	if len(words) < n { return true, "ok" }
+func runeLen(units []string) int {
+	n := 0
+	for _, u := range units { n += utf8.RuneCountInString(u) }
+	return n
+}
 
-	ngrams := make(map[string]int)
-	total := len(words) - n + 1
-	for i := 0; i <= len(words)-n; i++ {
-		gram := strings.Join(words[i:i+n], " ")
-		ngrams[gram]++
+// TopNGramCharFraction 返回出现次数最多的 n-gram 覆盖的字符占全部字符的比例 (Gopher 重复度规则)
+func TopNGramCharFraction(units []string, n int) float64 {
+	total := runeLen(units)
+	if n <= 0 || len(units) < n || total == 0 { return 0 }
+	top, topCount := "", 0
+	for gram, c := range NGramCounts(units, n) {
+		if c > topCount || (c == topCount && gram < top) { top, topCount = gram, c }
 	}
+	if topCount < 2 { return 0 }
+	return float64(runeLen(strings.Split(top, " "))*topCount) / float64(total)
+}
 
-	ratio := 1.0 - (float64(len(ngrams)) / float64(total))
-	if ratio > threshold {
-		return false, fmt.Sprintf("ngram rep ratio %f > %f", ratio, threshold)
+// DuplicateNGramCharFraction 返回被重复出现的 n-gram 覆盖的字符占全部字符的比例，重叠部分只计一次
+func DuplicateNGramCharFraction(units []string, n int) float64 {
+	total := runeLen(units)
+	if n <= 0 || len(units) < n || total == 0 { return 0 }
+	first := make(map[string]int)
+	covered := make([]bool, len(units))
+	for i := 0; i+n <= len(units); i++ {
+		gram := strings.Join(units[i:i+n], " ")
+		j, ok := first[gram]
+		if !ok { first[gram] = i; continue }
+		for k := 0; k < n; k++ { covered[i+k] = true; covered[j+k] = true }
 	}
-	return true, "ok"
-}
\ No newline at end of file
+	dup := 0
+	for i, c := range covered { if c { dup += utf8.RuneCountInString(units[i]) } }
+	return float64(dup) / float64(total)
+}
@@ -0,0 +1,43 @@
+package nlp
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func isTerminator(r rune) bool { return strings.ContainsRune("。！？!?；…", r) }
+func isCloser(r rune) bool     { return strings.ContainsRune("\"'”’)）」』】》", r) }
+
+// SplitSentences 按中英文句末标点切分句子。句号仅在其后为空白或文本结尾时断句，
+// 以免拆开小数与缩写；空行也视为句子边界。结尾的引号、括号归入前一句。
+func SplitSentences(text string) []string {
+	runes := []rune(text)
+	var out []string
+	start := 0
+	emit := func(end int) {
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" { out = append(out, s) }
+		start = end
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n' && i+1 < len(runes) && runes[i+1] == '\n':
+			emit(i)
+		case isTerminator(r) || (r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || isCloser(runes[i+1]))):
+			j := i + 1
+			for j < len(runes) && (isTerminator(runes[j]) || runes[j] == '.' || isCloser(runes[j])) { j++ }
+			emit(j)
+			i = j - 1
+		}
+	}
+	emit(len(runes))
+	return out
+}
+
+// EndsWithTerminalPunct 判断文本是否以句末标点 (可带收尾引号、括号) 结尾
+func EndsWithTerminalPunct(text string) bool {
+	text = strings.TrimRightFunc(text, func(r rune) bool { return unicode.IsSpace(r) || isCloser(r) })
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return isTerminator(r) || r == '.'
+}
@@ -0,0 +1,19 @@
+package nlp
+
+import "strings"
+
+// CountSyllables 按元音组估算英文单词的音节数，至少为 1
+func CountSyllables(word string) int {
+	word = strings.ToLower(word)
+	vowels := "aeiouy"
+	count := 0
+	prevVowel := false
+	for _, char := range word {
+		isVowel := strings.ContainsRune(vowels, char)
+		if isVowel && !prevVowel { count++ }
+		prevVowel = isVowel
+	}
+	if strings.HasSuffix(word, "e") { count-- }
+	if count <= 0 { count = 1 }
+	return count
+}
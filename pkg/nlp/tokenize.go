@@ -0,0 +1,65 @@
+package nlp
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	ShingleWord = "word" // 空白切分的词 k-gram
+	ShingleChar = "char" // 字符 k-gram
+	ShingleCJK  = "cjk"  // 中日韩文字逐字切分、其余按词切分后的 k-gram
+)
+
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Words 按空白切分，保留原始大小写与标点
+func Words(text string) []string { return strings.Fields(text) }
+
+// Tokens 转小写后切分：中日韩文字每字一个 token，字母数字串作为一个 token，其余字符作为分隔符
+func Tokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 { tokens = append(tokens, word.String()); word.Reset() }
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case IsCJK(r): flush(); tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r): word.WriteRune(r)
+		default: flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+func kgrams(units []string, k int, sep string) []string {
+	if len(units) == 0 { return nil }
+	if len(units) <= k { return []string{strings.Join(units, sep)} }
+	out := make([]string, 0, len(units)-k+1)
+	for i := 0; i+k <= len(units); i++ { out = append(out, strings.Join(units[i:i+k], sep)) }
+	return out
+}
+
+// ValidShingle 判断 mode 是否为已知的切分方式
+func ValidShingle(mode string) bool {
+	return mode == ShingleWord || mode == ShingleChar || mode == ShingleCJK
+}
+
+// Shingles 按 mode 切分后取 k-gram，供 MinHash/SimHash 等去重算法使用
+func Shingles(text, mode string, k int) []string {
+	if k <= 0 { k = 1 }
+	switch mode {
+	case ShingleWord:
+		return kgrams(strings.Fields(strings.ToLower(text)), k, " ")
+	case ShingleChar:
+		runes := []rune(strings.Join(strings.Fields(strings.ToLower(text)), " "))
+		units := make([]string, len(runes))
+		for i, r := range runes { units[i] = string(r) }
+		return kgrams(units, k, "")
+	}
+	return kgrams(Tokens(text), k, " ")
+}
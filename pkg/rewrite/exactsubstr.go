@@ -0,0 +1,38 @@
+package rewrite
+
+import (
+	"graunt/internal/external"
+	"graunt/pkg/exactsubstr"
+	"errors"
+)
+
+// ExactSubstrRewrite 依据 cmd/exactsubstr 离线生成的索引，删除文档中的重复子串。
+// exact_substr_index 为 Dir 下的索引文件名
+type ExactSubstrRewrite struct {
+	Dir string
+}
+
+func NewExactSubstrRewrite(dir string) *ExactSubstrRewrite { return &ExactSubstrRewrite{Dir: dir} }
+func (r *ExactSubstrRewrite) Name() string                 { return "exact_substr" }
+
+func (r *ExactSubstrRewrite) index(params map[string]interface{}) (*exactsubstr.Index, error) {
+	name, _ := params["exact_substr_index"].(string)
+	if name == "" { return nil, errors.New("exact_substr_index param is required") }
+	path, err := exactsubstr.IndexPath(r.Dir, name)
+	if err != nil { return nil, err }
+	return exactsubstr.CachedIndex(path)
+}
+
+// Validate 在批处理开始前加载索引，缺少参数或索引无法读取时整批报错
+func (r *ExactSubstrRewrite) Validate(params map[string]interface{}) error {
+	_, err := r.index(params)
+	return err
+}
+
+func (r *ExactSubstrRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	idx, err := r.index(params)
+	if err != nil { return "", err }
+	doc, ok := idx.Lookup(text)
+	if !ok { return text, nil }
+	return exactsubstr.Cut(text, doc.Spans), nil
+}
@@ -0,0 +1,162 @@
+package rewrite
+
+import (
+	"graunt/internal/external"
+	"graunt/pkg/extract"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	htmlDocument  = regexp.MustCompile(`(?i)^\s*(?:<!doctype html|<html|<head|<body)`)
+	htmlTag       = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9]*\b[^<>]*>`)
+	htmlBlock     = regexp.MustCompile(`(?i)<(?:div|p|article|section|table|ul|ol|h[1-6])\b[^>]*>`)
+	markdownBlock = regexp.MustCompile("(?m)^(?:#{1,6}\\s|```|~~~|\\s*[-*+]\\s|\\s*\\d+[.)]\\s|>\\s)")
+)
+
+// htmlDensity 是按 HTML 处理时标签至少占去的非空白字节比例
+const htmlDensity = 0.2
+
+// ExtractRewrite 把爬虫抓回的原始页面转成干净文本。extract_input 指定输入类型 (默认 auto 自动识别)：
+// html 做 Readability 式正文提取 (extract_full=true 时转换整页)，标题、列表、表格、代码块保留为 Markdown；
+// markdown 做格式规范化；reddit / zhihu 解析爬虫保存的 JSON (知乎也接受 "URL:/Title:" 文本格式)，
+// 按 extract_format 输出为 document (标题+正文+按分数排列的回复) 或 conversation (沿最高分回复链的多轮对话 JSON)。
+// 回复受 extract_max_replies (默认 50) 与 extract_min_score (默认 0) 限制。
+type ExtractRewrite struct {
+	mu    sync.Mutex
+	stats ExtractStats
+}
+
+type ExtractStats struct {
+	Documents int            `json:"documents"`
+	Empty     int            `json:"empty"`
+	ByInput   map[string]int `json:"by_input"`
+}
+
+func NewExtractRewrite() *ExtractRewrite { return &ExtractRewrite{stats: ExtractStats{ByInput: make(map[string]int)}} }
+func (r *ExtractRewrite) Name() string   { return "extract" }
+
+func (r *ExtractRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	out, _, err := r.RewriteWithMetadata(text, params, llm)
+	return out, err
+}
+
+// zhihuShaped 判断 JSON 是否为知乎接口的数据：条目 (顶层数组、data 数组或对象本身) 带 target 或 type 为知乎内容类型
+func zhihuShaped(t string) bool {
+	var v interface{}
+	if err := json.Unmarshal([]byte(t), &v); err != nil { return false }
+	var items []interface{}
+	switch x := v.(type) {
+	case []interface{}:
+		items = x
+	case map[string]interface{}:
+		if data, ok := x["data"].([]interface{}); ok { items = data } else { items = []interface{}{x} }
+	}
+	for _, it := range items {
+		m, _ := it.(map[string]interface{})
+		if m == nil { continue }
+		if _, ok := m["target"].(map[string]interface{}); ok { return true }
+		switch m["type"] {
+		case "answer", "article", "question", "comment": return true
+		}
+	}
+	return false
+}
+
+// sniffInput 根据内容猜测输入类型，不认识的 JSON 按普通文本处理
+func sniffInput(text string) string {
+	t := strings.TrimSpace(text)
+	if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		if json.Valid([]byte(t)) {
+			if strings.Contains(t, `"kind"`) && strings.Contains(t, `"Listing"`) { return "reddit" }
+			if zhihuShaped(t) { return "zhihu" }
+		}
+	}
+	if _, _, ok := extract.SpiderText(text); ok { return "zhihu" }
+	if looksHTML(t) { return "html" }
+	return "markdown"
+}
+
+// looksHTML 判断文本是否为 HTML 页面或片段：以 doctype/html/head/body 开头，或没有 Markdown 的标题、代码块、列表、引用
+// 而含块级标签或标签密度高。夹带 HTML 块的 Markdown (如 GitHub README 开头的 <div align="center">) 仍按 Markdown 处理
+func looksHTML(t string) bool {
+	if htmlDocument.MatchString(t) { return true }
+	if markdownBlock.MatchString(t) { return false }
+	if htmlBlock.MatchString(t) { return true }
+	tags := 0
+	for _, m := range htmlTag.FindAllStringIndex(t, -1) { tags += m[1] - m[0] }
+	return tags > 0 && float64(tags) >= htmlDensity*float64(len(strings.Join(strings.Fields(t), "")))
+}
+
+func (r *ExtractRewrite) RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	input, _ := params["extract_input"].(string)
+	if input == "" || input == "auto" { input = sniffInput(text) }
+	format, _ := params["extract_format"].(string)
+	if format == "" { format = "document" }
+	if format != "document" && format != "conversation" { return text, nil, fmt.Errorf("unknown extract_format '%s'", format) }
+	lim := extract.Limits{MaxReplies: int(floatParam(params, "extract_max_replies", 50)), MinScore: int(floatParam(params, "extract_min_score", 0))}
+	meta := map[string]interface{}{"extract_input": input}
+
+	var posts []*extract.Post
+	var err error
+	switch input {
+	case "html":
+		full, _ := params["extract_full"].(bool)
+		page := extract.HTML(text, full)
+		posts = []*extract.Post{{Title: page.Title, Body: page.Markdown}}
+		if page.Title != "" { meta["extract_title"] = page.Title }
+		// 正文已以一级标题开头，或 extract_title=false 时不再补页面标题
+		if keep, ok := params["extract_title"].(bool); (ok && !keep) || strings.HasPrefix(page.Markdown, "# ") { posts[0].Title = "" }
+	case "markdown":
+		posts = []*extract.Post{{Body: extract.Markdown(text)}}
+	case "reddit":
+		posts, err = extract.Reddit([]byte(text))
+	case "zhihu":
+		if url, p, ok := extract.SpiderText(text); ok {
+			posts = []*extract.Post{p}
+			meta["extract_url"] = url
+		} else {
+			posts, err = extract.Zhihu([]byte(text))
+		}
+	default:
+		return text, nil, fmt.Errorf("unknown extract_input '%s'", input)
+	}
+	if err != nil { return text, nil, fmt.Errorf("extract %s: %v", input, err) }
+	if t := posts[0].Title; t != "" { meta["extract_title"] = t }
+	if len(posts) > 1 { meta["extract_posts"] = len(posts) }
+
+	var out string
+	if format == "conversation" {
+		turns := extract.Conversation(posts[0], lim)
+		meta["extract_turns"] = len(turns)
+		if len(turns) > 0 {
+			b, _ := json.Marshal(turns)
+			out = string(b)
+		}
+	} else {
+		docs := make([]string, 0, len(posts))
+		for _, p := range posts {
+			if d := extract.Document(p, lim); d != "" { docs = append(docs, d) }
+		}
+		out = strings.Join(docs, "\n\n---\n\n")
+	}
+
+	r.mu.Lock()
+	r.stats.Documents++
+	r.stats.ByInput[input]++
+	if out == "" { r.stats.Empty++ }
+	r.mu.Unlock()
+	return out, meta, nil
+}
+
+func (r *ExtractRewrite) Stats() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.ByInput = make(map[string]int, len(r.stats.ByInput))
+	for k, v := range r.stats.ByInput { st.ByInput[k] = v }
+	return st
+}
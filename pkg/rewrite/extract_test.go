@@ -0,0 +1,43 @@
+package rewrite
+
+import (
+	"strings"
+	"testing"
+)
+
+const readme = `<div align="center">
+  <img src="logo.png" width="120">
+  <h1>Tool</h1>
+</div>
+
+# Tool
+
+## Install
+
+` + "```bash\nnpm i tool\nnpm run build\n```" + `
+
+- one
+- two
+`
+
+func TestSniffInput(t *testing.T) {
+	for _, c := range []struct{ text, want string }{
+		{readme, "markdown"},
+		{"<!DOCTYPE html><html><body><p>hello</p></body></html>", "html"},
+		{"<div class=\"post\"><p>First paragraph.</p><p>Second paragraph.</p></div>", "html"},
+		{"Plain text with a <b>bold</b> word and nothing else going on here at all.", "markdown"},
+		{"<span>a</span><a href=\"/x\">b</a><span>c</span>", "html"},
+		{`{"type":"answer","content":"<p>x</p>"}`, "zhihu"},
+		{`{"text":"hello"}`, "markdown"},
+	} {
+		if got := sniffInput(c.text); got != c.want { t.Errorf("%.40q: sniffed %s, want %s", c.text, got, c.want) }
+	}
+}
+
+func TestExtractKeepsReadmeStructure(t *testing.T) {
+	out, _, err := NewExtractRewrite().RewriteWithMetadata(readme, nil, nil)
+	if err != nil { t.Fatal(err) }
+	for _, want := range []string{"\n## Install\n", "```bash\nnpm i tool\nnpm run build\n```", "- one\n- two"} {
+		if !strings.Contains(out, want) { t.Errorf("output lost %q:\n%s", want, out) }
+	}
+}
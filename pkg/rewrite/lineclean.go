@@ -0,0 +1,226 @@
+package rewrite
+
+import (
+	"graunt/internal/external"
+	"graunt/pkg/nlp"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 常见的网页模板行：cookie 提示、分享按钮、版权页脚、导航菜单等
+var defaultBoilerplate = []string{
+	"cookie", "accept all", "privacy policy", "terms of use", "terms of service", "all rights reserved", "copyright ©",
+	"share this", "share on", "follow us", "subscribe", "sign up", "sign in", "log in", "click here", "read more",
+	"skip to content", "back to top", "enable javascript", "powered by", "advertisement",
+	"版权所有", "转载请注明", "免责声明", "点击查看", "点击进入", "扫码关注", "关注公众号", "分享到", "上一篇", "下一篇",
+	"返回顶部", "隐私政策", "用户协议", "登录", "注册", "icp备", "网安备", "广告", "阅读原文", "责任编辑",
+}
+
+var (
+	urlPattern    = regexp.MustCompile(`https?://\S+|www\.\S+`)
+	menuSeparator = regexp.MustCompile(`\s*[|｜·»›>]\s*`)
+)
+
+// LineCleanRewrite 逐行打分并删除模板行，保留正文。打分规则：
+// 不长于 line_clean_blocklist_max_chars 的行命中屏蔽短语 (英文按词边界匹配，不把 "subscriber" 当作 "subscribe")
+// 计 line_clean_blocklist_weight，再加上 line_clean_blocklist_coverage_weight × 短语占行内字符的比例；过短 (line_clean_short_weight)、无句末标点 (line_clean_punct_weight)、
+// 符号/链接占比过高 (line_clean_symbol_weight)、形如 "首页 | 新闻 | 联系我们" 的导航菜单 (line_clean_menu_weight) 累加，总分不低于 line_clean_threshold 时删除。
+type LineCleanRewrite struct {
+	mu    sync.Mutex
+	stats LineCleanStats
+}
+
+type LineCleanStats struct {
+	Documents    int            `json:"documents"`
+	LinesRemoved int            `json:"lines_removed"`
+	CharsRemoved int            `json:"chars_removed"`
+	CharsTotal   int            `json:"chars_total"`
+	ByRule       map[string]int `json:"by_rule"`
+}
+
+type lineCleanConfig struct {
+	threshold      float64
+	minWords       int
+	minCJKChars    int
+	maxSymbolRatio float64
+	shortWeight    float64
+	punctWeight    float64
+	symbolWeight   float64
+	menuWeight     float64
+	blocklist      []string
+	blockMaxChars  int
+	blockWeight    float64
+	coverWeight    float64
+}
+
+func NewLineCleanRewrite() *LineCleanRewrite {
+	return &LineCleanRewrite{stats: LineCleanStats{ByRule: make(map[string]int)}}
+}
+func (r *LineCleanRewrite) Name() string { return "line_clean" }
+
+func floatParam(params map[string]interface{}, key string, def float64) float64 {
+	if v, ok := params[key].(float64); ok { return v }
+	return def
+}
+
+func lineCleanParams(params map[string]interface{}) lineCleanConfig {
+	cfg := lineCleanConfig{
+		threshold:      floatParam(params, "line_clean_threshold", 0.6),
+		minWords:       int(floatParam(params, "line_clean_min_words", 3)),
+		minCJKChars:    int(floatParam(params, "line_clean_min_cjk_chars", 8)),
+		maxSymbolRatio: floatParam(params, "line_clean_max_symbol_ratio", 0.3),
+		shortWeight:    floatParam(params, "line_clean_short_weight", 0.4),
+		punctWeight:    floatParam(params, "line_clean_punct_weight", 0.3),
+		symbolWeight:   floatParam(params, "line_clean_symbol_weight", 0.6),
+		menuWeight:     floatParam(params, "line_clean_menu_weight", 0.6),
+		blocklist:      defaultBoilerplate,
+		blockMaxChars:  int(floatParam(params, "line_clean_blocklist_max_chars", 120)),
+		blockWeight:    floatParam(params, "line_clean_blocklist_weight", 0.5),
+		coverWeight:    floatParam(params, "line_clean_blocklist_coverage_weight", 1),
+	}
+	switch v := params["line_clean_blocklist"].(type) {
+	case string:
+		cfg.blocklist = append(append([]string(nil), cfg.blocklist...), strings.Split(v, ",")...)
+	case []interface{}:
+		cfg.blocklist = append([]string(nil), cfg.blocklist...)
+		for _, s := range v { if str, ok := s.(string); ok { cfg.blocklist = append(cfg.blocklist, str) } }
+	}
+	return cfg
+}
+
+// symbolRatio 返回链接与非字母数字符号占行内字符的比例
+func symbolRatio(line string) float64 {
+	total := utf8.RuneCountInString(line)
+	if total == 0 { return 0 }
+	symbols := 0
+	for _, url := range urlPattern.FindAllString(line, -1) { symbols += utf8.RuneCountInString(url) }
+	for _, r := range urlPattern.ReplaceAllString(line, "") {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) { symbols++ }
+	}
+	return float64(symbols) / float64(total)
+}
+
+// isMenu 判断一行是否由分隔符隔开的三个以上短项组成
+func isMenu(line string) bool {
+	items := menuSeparator.Split(urlPattern.ReplaceAllString(line, ""), -1)
+	if len(items) < 3 { return false }
+	for _, item := range items {
+		if len(strings.Fields(item)) > 3 || utf8.RuneCountInString(item) > 12 { return false }
+	}
+	return true
+}
+
+// isWordRune 判断字母数字 (不含中日韩字符，中文没有词边界)
+func isWordRune(r rune) bool { return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !nlp.IsCJK(r) }
+
+// joined 判断 a、b 两个相邻字符是否连成一个词
+func joined(a, b rune) bool { return isWordRune(a) && isWordRune(b) }
+
+// phraseRunes 返回 phrase 在行内按词边界出现的总字符数，英文短语允许带复数 s ("cookies")
+func phraseRunes(line, phrase string) int {
+	first, _ := utf8.DecodeRuneInString(phrase)
+	last, _ := utf8.DecodeLastRuneInString(phrase)
+	total := 0
+	for i := 0; i < len(line); {
+		j := strings.Index(line[i:], phrase)
+		if j < 0 { break }
+		start, end := i+j, i+j+len(phrase)
+		if end < len(line) && line[end] == 's' && isWordRune(last) { end++ }
+		before, _ := utf8.DecodeLastRuneInString(line[:start])
+		after, _ := utf8.DecodeRuneInString(line[end:])
+		if (start == 0 || !joined(before, first)) && (end == len(line) || !joined(last, after)) {
+			total += utf8.RuneCountInString(line[start:end])
+			i = end
+		} else {
+			_, size := utf8.DecodeRuneInString(line[start:])
+			i = start + size
+		}
+	}
+	return total
+}
+
+// scoreLine 返回删除该行的原因，空串表示保留
+func (cfg lineCleanConfig) scoreLine(line string) string {
+	// 原因取权重最大的一项
+	score, reason, top := 0.0, "", 0.0
+	add := func(w float64, name string) {
+		score += w
+		if w > top { reason, top = name, w }
+	}
+	if n := utf8.RuneCountInString(line); n <= cfg.blockMaxChars {
+		lower, covered := strings.ToLower(line), 0
+		for _, phrase := range cfg.blocklist {
+			if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" { covered += phraseRunes(lower, phrase) }
+		}
+		if covered > 0 { add(cfg.blockWeight+cfg.coverWeight*min(1, float64(covered)/float64(n)), "blocklist") }
+	}
+	cjk := 0
+	for _, r := range line { if nlp.IsCJK(r) { cjk++ } }
+	if cjk*2 >= utf8.RuneCountInString(line) {
+		if cjk < cfg.minCJKChars { add(cfg.shortWeight, "short") }
+	} else if len(strings.Fields(line)) < cfg.minWords {
+		add(cfg.shortWeight, "short")
+	}
+	if !nlp.EndsWithTerminalPunct(line) { add(cfg.punctWeight, "no_punct") }
+	if symbolRatio(line) > cfg.maxSymbolRatio { add(cfg.symbolWeight, "symbols") }
+	if isMenu(line) { add(cfg.menuWeight, "menu") }
+	if reason != "" && score >= cfg.threshold { return reason }
+	return ""
+}
+
+func (r *LineCleanRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	out, _, err := r.RewriteWithMetadata(text, params, llm)
+	return out, err
+}
+
+// RewriteWithMetadata 返回清洗后的文本，元数据记录删除的行数与字符数。删除后留下的连续空行合并为一个。
+func (r *LineCleanRewrite) RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	cfg := lineCleanParams(params)
+	var kept []string
+	removedLines := 0
+	byRule := make(map[string]int)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(kept) > 0 && kept[len(kept)-1] != "" { kept = append(kept, "") }
+			continue
+		}
+		if rule := cfg.scoreLine(strings.TrimSpace(line)); rule != "" {
+			removedLines++
+			byRule[rule]++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	out := text
+	if removedLines > 0 {
+		for len(kept) > 0 && kept[len(kept)-1] == "" { kept = kept[:len(kept)-1] }
+		out = strings.Join(kept, "\n")
+	}
+	removedChars := utf8.RuneCountInString(text) - utf8.RuneCountInString(out)
+
+	r.mu.Lock()
+	r.stats.Documents++
+	r.stats.LinesRemoved += removedLines
+	r.stats.CharsRemoved += removedChars
+	r.stats.CharsTotal += utf8.RuneCountInString(text)
+	for k, v := range byRule { r.stats.ByRule[k] += v }
+	r.mu.Unlock()
+
+	meta := map[string]interface{}{
+		"line_clean_removed_lines": removedLines,
+		"line_clean_removed_chars": removedChars,
+	}
+	return out, meta, nil
+}
+
+func (r *LineCleanRewrite) Stats() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.ByRule = make(map[string]int, len(r.stats.ByRule))
+	for k, v := range r.stats.ByRule { st.ByRule[k] = v }
+	return st
+}
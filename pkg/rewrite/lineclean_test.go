@@ -0,0 +1,33 @@
+package rewrite
+
+import "testing"
+
+func TestLineCleanRemovesBoilerplate(t *testing.T) {
+	cfg := lineCleanParams(nil)
+	for _, line := range []string{
+		"We use cookies to improve your experience on our website.",
+		"Copyright © 2024 Example Inc. All rights reserved.",
+		"Subscribe to our newsletter for weekly updates.",
+		"Share this article on Twitter",
+		"版权所有 © 2023 某某科技有限公司",
+		"关注公众号获取更多资讯",
+		"转载请注明出处。",
+		"上一篇：如何学习 Go 语言",
+		"首页 | 新闻 | 产品 | 联系我们",
+	} {
+		if cfg.scoreLine(line) == "" { t.Errorf("%q: kept, want removed", line) }
+	}
+}
+
+func TestLineCleanKeepsProse(t *testing.T) {
+	cfg := lineCleanParams(nil)
+	for _, line := range []string{
+		"The magazine lost a third of its subscribers after the price increase last spring.",
+		"Each signature is verified against the public key before the block is accepted.",
+		"注册会计师考试每年举行一次，报名人数近年来持续增长。",
+		"他在文章中回顾了这家公司二十年来的发展历程，以及创始人早年的创业经历。",
+		"Publishers argue that the new privacy policy rules, which take effect next year, will raise compliance costs for small websites considerably.",
+	} {
+		if rule := cfg.scoreLine(line); rule != "" { t.Errorf("%q: removed as %s, want kept", line, rule) }
+	}
+}
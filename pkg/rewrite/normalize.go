@@ -0,0 +1,57 @@
+package rewrite
+
+import (
+	"graunt/internal/external"
+	"graunt/pkg/textnorm"
+	"sync"
+)
+
+// NormalizeRewrite 规范化 Unicode 与中文文本。normalize_steps 选择步骤 (默认 mojibake,control,nfkc,fullwidth,whitespace，
+// 可用 all 或显式加入 t2s 做繁→简转换)，normalize_t2s_dict 可叠加 textnorm.DictDir 下的 OpenCC 格式词典 (文件名，给出时自动开启 t2s)。
+// 步骤固定按 mojibake → control → nfkc → fullwidth → t2s → whitespace 的顺序执行；元数据 normalized 记录各步骤的改动量。
+// 只想让过滤器看到规范化文本而不改写原文时，在过滤参数里给 normalize (见 service.evaluateFilter)。
+type NormalizeRewrite struct {
+	mu    sync.Mutex
+	stats NormalizeStats
+}
+
+type NormalizeStats struct {
+	Documents int            `json:"documents"`
+	Changed   int            `json:"changed"`
+	ByStep    map[string]int `json:"by_step"`
+}
+
+func NewNormalizeRewrite() *NormalizeRewrite {
+	return &NormalizeRewrite{stats: NormalizeStats{ByStep: make(map[string]int)}}
+}
+func (r *NormalizeRewrite) Name() string { return "normalize" }
+
+func (r *NormalizeRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	out, _, err := r.RewriteWithMetadata(text, params, llm)
+	return out, err
+}
+
+func (r *NormalizeRewrite) RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	dict, _ := params["normalize_t2s_dict"].(string)
+	opts, err := textnorm.ParseOptions(params["normalize_steps"], dict)
+	if err != nil { return text, nil, err }
+	out, st := textnorm.Normalize(text, opts)
+
+	r.mu.Lock()
+	r.stats.Documents++
+	if out != text { r.stats.Changed++ }
+	for k, v := range st { r.stats.ByStep[k] += v }
+	r.mu.Unlock()
+
+	if len(st) == 0 { return out, nil, nil }
+	return out, map[string]interface{}{"normalized": map[string]int(st)}, nil
+}
+
+func (r *NormalizeRewrite) Stats() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.ByStep = make(map[string]int, len(r.stats.ByStep))
+	for k, v := range r.stats.ByStep { st.ByStep[k] = v }
+	return st
+}
@@ -2,19 +2,224 @@ package rewrite
 
 import (
 	"graunt/internal/external"
+	"fmt"
+	"net"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
-type PIIMaskRewrite struct{}
+// piiCategory 描述一类个人信息：正则含捕获组时只替换第一个命中的组 (如 "QQ：12345" 只替换号码)，
+// valid 对候选做校验和等二次确认，near 结合上下文确认 (start/end 为候选在 text 中的位置)
+type piiCategory struct {
+	name        string
+	placeholder string
+	re          *regexp.Regexp
+	valid       func(string) bool
+	near        func(text string, start, end int) bool
+}
 
+// piiCategories 按优先级排列，重叠时排在前面的类别优先 (如 18 位身份证号不再当作银行卡号)
+var piiCategories = []piiCategory{
+	{"url_token", "[TOKEN]", regexp.MustCompile(`(?i)https?://\S*?[?&#](?:access_token|token|api_?key|key|secret|sig|signature|auth|password|passwd|pwd|session(?:id)?|sid|ticket)=([^&#\s"'<>）】」]+)`), nil, nil},
+	{"email", "[EMAIL]", regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`), nil, nil},
+	{"id_card", "[ID_CARD]", regexp.MustCompile(`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`), validIDCard, nil},
+	{"bank_card", "[BANK_CARD]", regexp.MustCompile(`\b[1-9]\d{3}(?:[ -]?\d{4}){2}[ -]?\d{4,7}\b`), validBankCard, bankCardContext},
+	{"mobile", "[PHONE]", regexp.MustCompile(`(?:\+86[ -]?|\b86[ -]?|\b)1[3-9]\d[ -]?\d{4}[ -]?\d{4}\b`), nil, nil},
+	{"phone", "[PHONE]", regexp.MustCompile(`\b\d{3}[-.]?\d{3}[-.]?\d{4}\b`), nil, nil},
+	{"ipv6", "[IP]", regexp.MustCompile(`(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}`), validIPv6, nil},
+	{"ipv4", "[IP]", regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`), nil, nil},
+	{"passport", "[PASSPORT]", regexp.MustCompile(`\b(?:[EG]\d{8}|E[A-HJ-NP-Z]\d{7}|(?:DE|SE|PE)\d{7}|[HM]\d{8,10})\b`), nil, nil},
+	{"license_plate", "[PLATE]", regexp.MustCompile(`[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼][A-HJ-NP-Z][·•]?(?:[DF][A-HJ-NP-Z0-9]\d{4}|\d{5}[DF]|[A-HJ-NP-Z0-9]{4}[A-HJ-NP-Z0-9挂学警港澳])`), nil, nil},
+	{"qq", "[QQ]", regexp.MustCompile(`(?i)(?:qq|扣扣)\s*(?:号码?)?\s*[:：]?\s*([1-9]\d{4,10})\b`), nil, nil},
+	{"wechat", "[WECHAT]", regexp.MustCompile(`(?i)(?:微信|wechat|weixin|vx|v信)\s*(?:号|id\b)?\s*[:：]?\s*([a-zA-Z][-_a-zA-Z0-9]{5,19})\b|\b(wxid_[a-z0-9]{6,})\b`), nil, wechatContext},
+}
+
+var idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// validIDCard 校验 GB 11643 身份证号的第 18 位校验码
+func validIDCard(s string) bool {
+	sum := 0
+	for i, w := range idCardWeights { sum += int(s[i]-'0') * w }
+	return "10X98765432"[sum%11] == strings.ToUpper(s[17:])[0]
+}
+
+// validBankCard 以 Luhn 校验 16–19 位卡号
+func validBankCard(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 16 || len(digits) > 19 { return false }
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 { d *= 2; if d > 9 { d -= 9 } }
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// bankCardIIN 是常见卡组织的发卡行号前缀：银联 62、Visa 4、Mastercard 5x、American Express 34/37
+var bankCardIIN = regexp.MustCompile(`^(?:62|4|5\d|3[47])`)
+
+// bankCardKeyword 出现在候选之前不远处时，不认识前缀的号码也按卡号处理
+var bankCardKeyword = regexp.MustCompile(`(?i)卡号|银行卡|信用卡|借记卡|card`)
+
+// bankCardKeywordWindow 是向前查找关键词的字节数
+const bankCardKeywordWindow = 48
+
+// bankCardContext 要求通过 Luhn 的号码带有已知发卡行前缀或前面有卡号关键词，避免把订单号等长数字当作卡号
+func bankCardContext(text string, start, end int) bool {
+	if bankCardIIN.MatchString(text[start:end]) { return true }
+	from := max(0, start-bankCardKeywordWindow)
+	for from > 0 && !utf8.RuneStart(text[from]) { from-- }
+	return bankCardKeyword.MatchString(text[from:start])
+}
+
+// wechatContext 要求关键词与候选之间有 号/ID/冒号，或候选本身含数字、下划线，
+// 避免把 "WeChat because ..." 这类英文叙述中关键词后的普通单词当作微信号
+func wechatContext(text string, start, end int) bool {
+	if strings.ContainsAny(text[start:end], "0123456789_") { return true }
+	prefix := strings.ToLower(strings.TrimRight(text[:start], " \t\n\f\r"))
+	for _, sep := range []string{"号", ":", "：", "id"} {
+		if strings.HasSuffix(prefix, sep) { return true }
+	}
+	return false
+}
+
+func validIPv6(s string) bool {
+	return strings.Count(s, ":") >= 2 && len(strings.ReplaceAll(s, ":", "")) >= 3 && net.ParseIP(s) != nil
+}
+
+func knownPIICategory(name string) bool {
+	for _, c := range piiCategories {
+		if c.name == name { return true }
+	}
+	return false
+}
+
+type piiSpan struct {
+	start, end int
+	category   *piiCategory
+}
+
+// findPII 返回互不重叠的个人信息片段，按位置排序
+func findPII(text string, enabled map[string]bool) []piiSpan {
+	var spans []piiSpan
+	taken := func(s, e int) bool {
+		for _, sp := range spans {
+			if s < sp.end && e > sp.start { return true }
+		}
+		return false
+	}
+	for i := range piiCategories {
+		cat := &piiCategories[i]
+		if len(enabled) > 0 && !enabled[cat.name] { continue }
+		for _, m := range cat.re.FindAllStringSubmatchIndex(text, -1) {
+			s, e := m[0], m[1]
+			for g := 2; g+1 < len(m); g += 2 {
+				if m[g] >= 0 { s, e = m[g], m[g+1]; break }
+			}
+			if cat.valid != nil && !cat.valid(text[s:e]) { continue }
+			if cat.near != nil && !cat.near(text, s, e) { continue }
+			if !taken(s, e) { spans = append(spans, piiSpan{s, e, cat}) }
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// PIIMaskRewrite 把个人信息替换为类别占位符。pii_categories 限定启用的类别 (默认全部)：
+// url_token / email / id_card / bank_card / mobile / phone / ipv6 / ipv4 / passport / license_plate / qq / wechat；
+// pii_placeholders 可按类别覆盖占位符。各类别的替换数以 pii_counts 元数据返回。
+// pii_mode=pseudonymize 时改用格式有效的替身值，映射加密存放在 VaultDir 下的保险库中，见 newPseudonymizer。
+type PIIMaskRewrite struct {
+	VaultDir string
+	mu       sync.Mutex
+	docs     int
+	counts   map[string]int
+}
+
+type PIIMaskStats struct {
+	Documents int            `json:"documents"`
+	Masked    map[string]int `json:"masked"`
+}
+
+func NewPIIMaskRewrite(vaultDir string) *PIIMaskRewrite {
+	return &PIIMaskRewrite{VaultDir: vaultDir, counts: make(map[string]int)}
+}
 func (r *PIIMaskRewrite) Name() string { return "pii_mask" }
 
-func (r *PIIMaskRewrite) Rewrite(text string, params map[string]interface{}, vllm *external.VLLMClient) (string, error) {
-	emailRegex := regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
-	phoneRegex := regexp.MustCompile(`\b\d{3}[-.]?\d{3}[-.]?\d{4}\b`)
+func (r *PIIMaskRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	out, _, err := r.RewriteWithMetadata(text, params, llm)
+	return out, err
+}
+
+func (r *PIIMaskRewrite) RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	enabled := stringList(params["pii_categories"])
+	set := make(map[string]bool, len(enabled))
+	for _, c := range enabled {
+		if !knownPIICategory(c) { return text, nil, fmt.Errorf("unknown pii category '%s'", c) }
+		set[c] = true
+	}
+	placeholders, _ := params["pii_placeholders"].(map[string]interface{})
+	meta := make(map[string]interface{})
+	var pseudo *pseudonymizer
+	switch mode, _ := params["pii_mode"].(string); mode {
+	case "", "mask":
+	case "pseudonymize":
+		p, name, err := newPseudonymizer(r.VaultDir, text, params)
+		if err != nil { return text, nil, err }
+		pseudo = p
+		meta["pii_vault"] = name
+	default:
+		return text, nil, fmt.Errorf("unknown pii_mode '%s'", mode)
+	}
+
+	counts := make(map[string]int)
+	var b strings.Builder
+	last := 0
+	for _, sp := range findPII(text, set) {
+		ph := sp.category.placeholder
+		if v, ok := placeholders[sp.category.name].(string); ok { ph = v }
+		if pseudo != nil {
+			s, err := pseudo.replace(sp.category.name, text[sp.start:sp.end])
+			if err != nil { return text, nil, err }
+			ph = s
+		}
+		b.WriteString(text[last:sp.start])
+		b.WriteString(ph)
+		last = sp.end
+		counts[sp.category.name]++
+	}
+	b.WriteString(text[last:])
+
+	total := 0
+	r.mu.Lock()
+	if r.counts == nil { r.counts = make(map[string]int) }
+	r.docs++
+	for k, v := range counts { r.counts[k] += v; total += v }
+	r.mu.Unlock()
+	meta["pii_counts"], meta["pii_masked"] = counts, total
+	return b.String(), meta, nil
+}
 
-	masked := emailRegex.ReplaceAllString(text, "[EMAIL]")
-	masked = phoneRegex.ReplaceAllString(masked, "[PHONE]")
+func (r *PIIMaskRewrite) Stats() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := PIIMaskStats{Documents: r.docs, Masked: make(map[string]int, len(r.counts))}
+	for k, v := range r.counts { st.Masked[k] = v }
+	return st
+}
 
-	return masked, nil
-}
\ No newline at end of file
+// stringList 接受逗号分隔的字符串或字符串数组
+func stringList(v interface{}) []string {
+	var out []string
+	switch x := v.(type) {
+	case string:
+		for _, s := range strings.Split(x, ",") { if s = strings.TrimSpace(s); s != "" { out = append(out, s) } }
+	case []interface{}:
+		for _, s := range x { if str, ok := s.(string); ok { out = append(out, str) } }
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package rewrite
+
+import "testing"
+
+func TestWeChatNeedsSeparatorOrDigit(t *testing.T) {
+	enabled := map[string]bool{"wechat": true}
+	for _, text := range []string{
+		"Download WeChat because everyone uses it",
+		"Most people in China use wechat instead of email",
+		"Open the WeChat Windows client",
+		"微信Windows版已更新",
+	} {
+		if spans := findPII(text, enabled); len(spans) > 0 {
+			t.Errorf("%q: masked %q", text, text[spans[0].start:spans[0].end])
+		}
+	}
+	for text, want := range map[string]string{
+		"微信号 zhangsan":           "zhangsan",
+		"WeChat: alicewong":      "alicewong",
+		"WeChat ID alicewong":    "alicewong",
+		"加微信：lilei_88":           "lilei_88",
+		"vx zhang2024":           "zhang2024",
+		"my id is wxid_abc12345": "wxid_abc12345",
+	} {
+		spans := findPII(text, enabled)
+		if len(spans) != 1 || text[spans[0].start:spans[0].end] != want {
+			t.Errorf("%q: spans %v, want %q", text, spans, want)
+		}
+	}
+}
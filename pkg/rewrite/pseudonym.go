@@ -0,0 +1,184 @@
+package rewrite
+
+import (
+	"graunt/pkg/vault"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// 保险库口令只从环境变量或密钥文件读取，不接受请求参数：任务参数会落盘并通过任务接口返回
+const (
+	PassphraseEnv     = "GRAUNT_PII_VAULT_PASSPHRASE"
+	PassphraseFileEnv = "GRAUNT_PII_VAULT_KEY_FILE"
+	PassphraseParam   = "pii_vault_passphrase" // 已废弃，出现时报错
+)
+
+// vaultPassphrase 依次读取 GRAUNT_PII_VAULT_PASSPHRASE 与 GRAUNT_PII_VAULT_KEY_FILE 指向的文件
+func vaultPassphrase() (string, error) {
+	if pass := os.Getenv(PassphraseEnv); pass != "" { return pass, nil }
+	path := os.Getenv(PassphraseFileEnv)
+	if path == "" { return "", fmt.Errorf("vault passphrase is not configured: set %s or %s", PassphraseEnv, PassphraseFileEnv) }
+	b, err := os.ReadFile(path)
+	if err != nil { return "", fmt.Errorf("read vault key file: %v", err) }
+	return strings.TrimSpace(string(b)), nil
+}
+
+var (
+	surnames     = []string{"wang", "li", "zhang", "liu", "chen", "yang", "zhao", "huang", "zhou", "wu", "xu", "sun", "hu", "zhu", "gao", "lin", "he", "guo", "ma", "luo"}
+	givenNames   = []string{"wei", "fang", "na", "min", "jing", "lei", "jun", "yang", "yong", "yan", "jie", "tao", "ming", "chao", "xiu", "hua", "ping", "gang", "hui", "qiang"}
+	regionCodes  = []string{"110101", "110105", "310104", "310115", "440103", "440305", "330106", "320102", "510107", "420102", "610113", "120101"}
+	plateRegions = []rune("京津沪渝冀豫云辽黑湘皖鲁苏浙赣鄂桂甘晋陕吉闽贵粤川琼")
+	plateLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	alnum        = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// surrogateRand 由保险库密钥、范围、类别、原文与重试序号确定性地派生随机源，使同一原文总得到同一替身
+func surrogateRand(secret []byte, scope, category, original string, attempt int) *rand.Rand {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\x00%s\x00%s\x00%d", scope, category, original, attempt)
+	return rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(mac.Sum(nil)))))
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' { b.WriteRune(c) }
+	}
+	return b.String()
+}
+
+func randDigits(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b { b[i] = byte('0' + rng.Intn(10)) }
+	return string(b)
+}
+
+func randFrom(rng *rand.Rand, chars string, n int) string {
+	b := make([]byte, n)
+	for i := range b { b[i] = chars[rng.Intn(len(chars))] }
+	return string(b)
+}
+
+// reshape 按原文的排版 (空格、连字符、+86 等) 依次填入新数字
+func reshape(original, digits string) string {
+	var b strings.Builder
+	i := 0
+	for _, c := range original {
+		if c >= '0' && c <= '9' && i < len(digits) { b.WriteByte(digits[i]); i++ } else { b.WriteRune(c) }
+	}
+	return b.String()
+}
+
+func luhnDigit(payload string) byte {
+	sum := 0
+	for i := 0; i < len(payload); i++ {
+		d := int(payload[len(payload)-1-i] - '0')
+		if i%2 == 0 { d *= 2; if d > 9 { d -= 9 } }
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func idCardChecksum(first17 string) byte {
+	sum := 0
+	for i, w := range idCardWeights { sum += int(first17[i]-'0') * w }
+	return "10X98765432"[sum%11]
+}
+
+// surrogateFor 生成与原文同类、格式有效的替身：身份证号与银行卡号带正确校验位，号码保留原排版。
+// 替身在整个保险库内唯一，各类别的取值空间都在百万以上，邮箱与微信号带 6 位数字后缀
+func surrogateFor(category, original string, rng *rand.Rand) string {
+	switch category {
+	case "email":
+		return fmt.Sprintf("%s.%s%06d@example.com", givenNames[rng.Intn(len(givenNames))], surnames[rng.Intn(len(surnames))], rng.Intn(1000000))
+	case "mobile":
+		d := "1" + string(rune('3'+rng.Intn(7))) + randDigits(rng, 9)
+		if len(digitsOf(original)) == 13 { d = "86" + d }
+		return reshape(original, d)
+	case "phone":
+		return reshape(original, fmt.Sprintf("%d%02d555%04d", 2+rng.Intn(8), rng.Intn(100), rng.Intn(10000)))
+	case "id_card":
+		year := 1950 + rng.Intn(56)
+		d := fmt.Sprintf("%s%04d%02d%02d%s", regionCodes[rng.Intn(len(regionCodes))], year, 1+rng.Intn(12), 1+rng.Intn(28), randDigits(rng, 3))
+		return d + string(idCardChecksum(d))
+	case "bank_card":
+		n := len(digitsOf(original))
+		payload := "62" + randDigits(rng, n-3)
+		return reshape(original, payload+string(luhnDigit(payload)))
+	case "ipv4":
+		return fmt.Sprintf("10.%d.%d.%d", rng.Intn(256), rng.Intn(256), 1+rng.Intn(254))
+	case "ipv6":
+		return fmt.Sprintf("2001:db8:%x:%x::%x", rng.Intn(0x10000), rng.Intn(0x10000), 1+rng.Intn(0xffff))
+	case "passport":
+		return "E" + randDigits(rng, 8)
+	case "license_plate":
+		p := string(plateRegions[rng.Intn(len(plateRegions))]) + string(plateLetters[rng.Intn(len(plateLetters))])
+		if utf8.RuneCountInString(original) >= 8 { return p + "D" + randDigits(rng, 5) }
+		return p + randFrom(rng, plateLetters[:8], 1) + randDigits(rng, 4)
+	case "qq":
+		return string(rune('1'+rng.Intn(9))) + randDigits(rng, max(len(original), 9)-1)
+	case "wechat":
+		if strings.HasPrefix(original, "wxid_") { return "wxid_" + randFrom(rng, alnum, 14) }
+		return fmt.Sprintf("%s_%s%06d", givenNames[rng.Intn(len(givenNames))], surnames[rng.Intn(len(surnames))], rng.Intn(1000000))
+	}
+	return randFrom(rng, alnum, max(len(original), 8))
+}
+
+// pseudonymizer 把同一范围内的相同原文映射为同一替身，并把映射写入加密保险库
+type pseudonymizer struct {
+	vault *vault.Vault
+	scope string
+}
+
+// newPseudonymizer 读取 pii_vault / pii_scope：
+// pii_scope=document (默认) 时替身只在单篇文档内一致，dataset 时在 pii_dataset (默认为保险库名) 内一致
+func newPseudonymizer(dir, text string, params map[string]interface{}) (*pseudonymizer, string, error) {
+	name, _ := params["pii_vault"].(string)
+	if name == "" { return nil, "", fmt.Errorf("pii_vault param is required for pii_mode=pseudonymize") }
+	path, err := vault.Path(dir, name)
+	if err != nil { return nil, "", err }
+	if _, ok := params[PassphraseParam]; ok { return nil, "", fmt.Errorf("%s is not accepted in params; set %s or %s on the server", PassphraseParam, PassphraseEnv, PassphraseFileEnv) }
+	pass, err := vaultPassphrase()
+	if err != nil { return nil, "", err }
+	v, err := vault.Cached(path, pass)
+	if err != nil { return nil, "", err }
+
+	scope, _ := params["pii_scope"].(string)
+	switch scope {
+	case "", "document":
+		sum := sha256.Sum256([]byte(text))
+		scope = "doc:" + hex.EncodeToString(sum[:8])
+	case "dataset":
+		dataset, _ := params["pii_dataset"].(string)
+		if dataset == "" { dataset = name }
+		scope = "dataset:" + dataset
+	default:
+		return nil, "", fmt.Errorf("unknown pii_scope '%s'", scope)
+	}
+	return &pseudonymizer{vault: v, scope: scope}, name, nil
+}
+
+func (p *pseudonymizer) replace(category, original string) (string, error) {
+	return p.vault.Surrogate(p.scope, category, original, func(attempt int) string {
+		return surrogateFor(category, original, surrogateRand(p.vault.Secret(), p.scope, category, original, attempt))
+	})
+}
+
+// Reidentify 用保险库把替身还原为原文，供授权审计使用
+func Reidentify(dir, name, passphrase string, texts []string) ([]string, error) {
+	path, err := vault.Path(dir, name)
+	if err != nil { return nil, err }
+	if _, err := os.Stat(path); err != nil { return nil, fmt.Errorf("vault '%s' not found", name) }
+	v, err := vault.Cached(path, passphrase)
+	if err != nil { return nil, err }
+	out := make([]string, len(texts))
+	for i, t := range texts { out[i] = v.Reidentify(t) }
+	return out, nil
+}
@@ -0,0 +1,21 @@
+package rewrite
+
+import (
+	"graunt/pkg/vault"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// 整个保险库共用一个替身空间，数据集范围内的大量不同原文都要能分到替身
+func TestSurrogateSpaceIsLarge(t *testing.T) {
+	v, err := vault.Open(filepath.Join(t.TempDir(), "s"+vault.Ext), "pw")
+	if err != nil { t.Fatal(err) }
+	defer v.Close()
+	p := &pseudonymizer{vault: v, scope: "dataset:t"}
+	for i := 0; i < 50000; i++ {
+		for _, c := range [][2]string{{"email", fmt.Sprintf("user%d@corp.cn", i)}, {"wechat", fmt.Sprintf("wx_user%d", i)}, {"phone", fmt.Sprintf("415-%03d-%04d", i/10000, i%10000)}} {
+			if _, err := p.replace(c[0], c[1]); err != nil { t.Fatalf("%s #%d: %v", c[0], i, err) }
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rewrite
+
+import (
+	"graunt/internal/external"
+	"graunt/pkg/nlp"
+	"graunt/pkg/secrets"
+	"fmt"
+	"sync"
+)
+
+// SecretsRedactRewrite 把命中 secrets.Catalog 的凭据替换为 secrets_placeholder (默认 "[REDACTED:{rule}]")。
+// 规则选择参数同 secrets 过滤器；元数据 secrets 记录每处命中在原文中的规则 ID 与区间。
+type SecretsRedactRewrite struct {
+	mu       sync.Mutex
+	docs     int
+	redacted map[string]int
+}
+
+type SecretsRedactStats struct {
+	Documents int            `json:"documents"`
+	Redacted  map[string]int `json:"redacted"`
+}
+
+func NewSecretsRedactRewrite() *SecretsRedactRewrite {
+	return &SecretsRedactRewrite{redacted: make(map[string]int)}
+}
+func (r *SecretsRedactRewrite) Name() string { return "secrets_redact" }
+
+func (r *SecretsRedactRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	out, _, err := r.RewriteWithMetadata(text, params, llm)
+	return out, err
+}
+
+func ruleSet(v interface{}) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, id := range stringList(v) {
+		if !secrets.KnownRule(id) { return nil, fmt.Errorf("unknown secret rule '%s'", id) }
+		set[id] = true
+	}
+	return set, nil
+}
+
+func (r *SecretsRedactRewrite) Validate(params map[string]interface{}) error {
+	if _, err := ruleSet(params["secrets_rules"]); err != nil { return err }
+	_, err := ruleSet(params["secrets_exclude_rules"])
+	return err
+}
+
+func (r *SecretsRedactRewrite) RewriteWithMetadata(text string, params map[string]interface{}, llm external.LLM) (string, map[string]interface{}, error) {
+	include, err := ruleSet(params["secrets_rules"])
+	if err != nil { return text, nil, err }
+	exclude, err := ruleSet(params["secrets_exclude_rules"])
+	if err != nil { return text, nil, err }
+	placeholder := "[REDACTED:{rule}]"
+	if v, ok := params["secrets_placeholder"].(string); ok { placeholder = v }
+
+	all := nlp.Analyze(text).Memo("secrets", func() interface{} { return secrets.Scan(text) }).([]secrets.Finding)
+	findings := secrets.Select(all, include, exclude)
+
+	r.mu.Lock()
+	r.docs++
+	for k, v := range secrets.CountByRule(findings) { r.redacted[k] += v }
+	r.mu.Unlock()
+
+	if len(findings) == 0 { return text, map[string]interface{}{"secret_count": 0}, nil }
+	return secrets.Redact(text, findings, placeholder), map[string]interface{}{"secrets": findings, "secret_count": len(findings)}, nil
+}
+
+func (r *SecretsRedactRewrite) Stats() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := SecretsRedactStats{Documents: r.docs, Redacted: make(map[string]int, len(r.redacted))}
+	for k, v := range r.redacted { st.Redacted[k] = v }
+	return st
+}
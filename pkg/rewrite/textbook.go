@@ -3,18 +3,25 @@ package rewrite
 import (
 	"graunt/internal/external"
 	"graunt/internal/model"
+	"graunt/pkg/langid"
 	"fmt"
 )
 
 type TextbookRewrite struct{}
 func (r *TextbookRewrite) Name() string { return "textbook" }
-func (r *TextbookRewrite) Rewrite(text string, params map[string]interface{}, vllm *external.VLLMClient) (string, error) {
+func (r *TextbookRewrite) Rewrite(text string, params map[string]interface{}, llm external.LLM) (string, error) {
+	system := "Rewrite into a textbook-level explanation."
+	// 上游 langid 给出语种时要求模型保持原文语言
+	if lang, ok := params["lang"].(string); ok && lang != langid.Undetermined && lang != "code" {
+		system += " Write the explanation in " + langid.Name(lang) + "."
+	}
 	req := model.VLLMRequest{
 		Model:       params["model"].(string),
-		Messages:    []model.Message{{Role: "system", Content: "Rewrite into a textbook-level explanation."}, {Role: "user", Content: text}},
+		Seed:        external.SeedParam(params),
+		Messages:    []model.Message{{Role: "system", Content: system}, {Role: "user", Content: text}},
 		MaxTokens:   2048, Temperature: 0.3,
 	}
-	resp, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), req)
-	if err != nil || len(resp.Choices) == 0 { return "", fmt.Errorf("failed to rewrite") }
+	resp, err := llm.CallChatCompletion(params["vllm_base_url"].(string), req)
+	if err != nil { return "", fmt.Errorf("failed to rewrite: %w", err) }
 	return resp.Choices[0].Message.Content, nil
 }
\ No newline at end of file
@@ -0,0 +1,114 @@
+package secrets
+
+import "regexp"
+
+// Rule 参照 gitleaks 的规则格式：Keywords 为小写关键词预筛 (任一出现才跑正则)，
+// SecretGroup 指出正则中密钥所在的捕获组 (0 为整个匹配)，MinEntropy 过滤低熵的占位值
+type Rule struct {
+	ID          string
+	Description string
+	Regex       *regexp.Regexp
+	SecretGroup int
+	MinEntropy  float64
+	Keywords    []string
+	Allowlist   []*regexp.Regexp // 密钥匹配其中任一时不算命中 (变量引用、常量名等)
+}
+
+// secretName 为形如密钥的变量名/配置键
+const secretName = `[A-Z0-9_.-]*(?:api_?key|secret|token|passw(?:or)?d|pwd|access_?key|private_?key|auth_?key|credential)[A-Z0-9_.-]*`
+
+// Catalog 按优先级排列，同一片段被多条规则命中时只记录排在前面的规则
+var Catalog = []Rule{
+	{
+		ID: "private-key", Description: "PEM private key block",
+		Regex:    regexp.MustCompile(`-----BEGIN[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----[\s\S]*?(?:-----END[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----|\z)`),
+		Keywords: []string{"private key"},
+	},
+	{
+		ID: "aws-access-key-id", Description: "AWS access key ID",
+		Regex:       regexp.MustCompile(`\b((?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16})\b`),
+		SecretGroup: 1, Keywords: []string{"akia", "asia", "abia", "acca", "a3t"},
+	},
+	{
+		ID: "aws-secret-access-key", Description: "AWS secret access key",
+		Regex:       regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?['"=:\s]+([A-Za-z0-9/+=]{40})\b`),
+		SecretGroup: 1, MinEntropy: 4, Keywords: []string{"aws"},
+	},
+	{
+		ID: "aliyun-access-key-id", Description: "Alibaba Cloud AccessKey ID",
+		Regex:       regexp.MustCompile(`\b(LTAI[A-Za-z0-9]{12,20})\b`),
+		SecretGroup: 1, Keywords: []string{"ltai"},
+	},
+	{
+		ID: "aliyun-access-key-secret", Description: "Alibaba Cloud AccessKey secret",
+		Regex:       regexp.MustCompile(`(?i)(?:aliyun|alibaba|access_?key_?secret).{0,20}?['"=:\s]+([A-Za-z0-9]{30})\b`),
+		SecretGroup: 1, MinEntropy: 3.5, Keywords: []string{"aliyun", "alibaba", "secret"},
+	},
+	{
+		ID: "github-token", Description: "GitHub personal access / OAuth / app token",
+		Regex:       regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+		SecretGroup: 1, Keywords: []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"},
+	},
+	{
+		ID: "gitlab-token", Description: "GitLab personal access token",
+		Regex:       regexp.MustCompile(`\b(glpat-[A-Za-z0-9_-]{20})\b`),
+		SecretGroup: 1, Keywords: []string{"glpat-"},
+	},
+	{
+		ID: "slack-token", Description: "Slack token",
+		Regex:       regexp.MustCompile(`\b(xox[baprs]-[0-9A-Za-z-]{10,72})\b`),
+		SecretGroup: 1, Keywords: []string{"xox"},
+	},
+	{
+		ID: "openai-api-key", Description: "OpenAI API key",
+		Regex:       regexp.MustCompile(`\b(sk-(?:proj-|svcacct-)?[A-Za-z0-9_-]{20,}T3BlbkFJ[A-Za-z0-9_-]{20,}|sk-[A-Za-z0-9]{48})\b`),
+		SecretGroup: 1, Keywords: []string{"sk-"},
+	},
+	{
+		ID: "anthropic-api-key", Description: "Anthropic API key",
+		Regex:       regexp.MustCompile(`\b(sk-ant-[a-z0-9]+-[A-Za-z0-9_-]{80,})`),
+		SecretGroup: 1, Keywords: []string{"sk-ant-"},
+	},
+	{
+		ID: "google-api-key", Description: "Google API key",
+		Regex:       regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`),
+		SecretGroup: 1, Keywords: []string{"aiza"},
+	},
+	{
+		ID: "stripe-key", Description: "Stripe secret / restricted key",
+		Regex:       regexp.MustCompile(`\b((?:sk|rk)_(?:live|test)_[0-9A-Za-z]{24,})\b`),
+		SecretGroup: 1, Keywords: []string{"sk_live", "sk_test", "rk_live", "rk_test"},
+	},
+	{
+		ID: "jwt", Description: "JSON Web Token",
+		Regex:       regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+		SecretGroup: 1, Keywords: []string{"eyj"},
+	},
+	{
+		ID: "database-url", Description: "database connection URL with password",
+		Regex:       regexp.MustCompile(`(?i)\b(?:postgres(?:ql)?|mysql|mariadb|mongodb(?:\+srv)?|rediss?|amqps?|mssql|sqlserver|clickhouse)://[^\s:/@]*:([^\s@/]+)@[^\s/]+`),
+		SecretGroup: 1, Keywords: []string{"://"},
+	},
+	{
+		ID: "url-credentials", Description: "password embedded in URL",
+		Regex:       regexp.MustCompile(`(?i)\b(?:https?|ftp|ssh)://[^\s:/@]+:([^\s@/]+)@[^\s/]+`),
+		SecretGroup: 1, Keywords: []string{"://"},
+	},
+	{
+		// 只认两种形式：赋值为引号内的字面量，或 .env 风格的整行 KEY=value；代码中的表达式赋值不算
+		ID: "generic-secret", Description: "secret-looking assignment (quoted literal or .env line)",
+		Regex: regexp.MustCompile(`(?im)(?:^[ \t]*(?:export[ \t]+)?` + secretName + `=['"]?|\b` + secretName + `['"]?[ \t]*(?::=|=>|[:=])[ \t]*['"])` +
+			`([^\s'"` + "`" + `,;()\[\]]{8,})(?:['"]|[ \t]*$)`),
+		SecretGroup: 1, MinEntropy: 3.5, Keywords: []string{"key", "secret", "token", "passw", "pwd", "credential"},
+		Allowlist: []*regexp.Regexp{
+			regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(?:\.[A-Za-z_$][A-Za-z0-9_$]*)+$`), // settings.SECRET_KEY 之类的属性链
+			regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`),                                         // 全大写常量名
+		},
+	},
+}
+
+// placeholders 为示例代码与文档中常见的占位值片段，命中时不视为泄漏
+var placeholders = []string{"example", "xxxx", "****", "your_", "your-", "yourpass", "placeholder", "changeme", "dummy", "redacted", "<", "${", "{{", "%s", "process.env", "os.environ", "getenv"}
+
+// placeholderValues 为整段作为占位的常见取值
+var placeholderValues = map[string]bool{"password": true, "passwd": true, "pass": true, "pwd": true, "secret": true, "root": true, "admin": true, "test": true}
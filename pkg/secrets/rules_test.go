@@ -0,0 +1,34 @@
+package secrets
+
+import "testing"
+
+func genericHits(text string) []Finding {
+	return Select(Scan(text), map[string]bool{"generic-secret": true}, nil)
+}
+
+func TestGenericSecretMatches(t *testing.T) {
+	for _, text := range []string{
+		`api_key = "q8Fz3LmN0pXr7TbV2wYe"`,
+		`const dbPassword = 'Zx9!kQ2@vLp#7Rt'`,
+		`{"client_secret": "a8F3kL0qZ7mN2xR5tW9y"}`,
+		"DB_PASSWORD=Zx9kQ2vLp7RtWm4",
+		"export GITHUB_TOKEN=q8Fz3LmN0pXr7TbV2wYe",
+	} {
+		if hits := genericHits(text); len(hits) != 1 { t.Errorf("%s: %d hits, want 1", text, len(hits)) }
+	}
+}
+
+func TestGenericSecretIgnoresCode(t *testing.T) {
+	for _, text := range []string{
+		"tokenizer = AutoTokenizer.from_pretrained(model_name)",
+		"secret_key = settings.SECRET_KEY",
+		"api_key = config.get_api_key()",
+		"    SECRET_KEY=settings.SECRET_KEY",
+		"password = DEFAULT_ADMIN_PASSWORD",
+		`token = headers["Authorization"]`,
+		"auth_token: q8Fz3LmN0pXr7TbV2wYe in yaml prose",
+		"access_key = os.environ.get('ACCESS_KEY')",
+	} {
+		if hits := genericHits(text); len(hits) != 0 { t.Errorf("%s: flagged as %q", text, text[hits[0].Start:hits[0].End]) }
+	}
+}
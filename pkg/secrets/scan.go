@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"graunt/pkg/nlp"
+	"sort"
+	"strings"
+)
+
+// Finding 记录一处命中：Start/End 为密钥在原文中的字节区间，Line 从 1 开始；不包含密钥本身
+type Finding struct {
+	RuleID string `json:"rule_id"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Line   int    `json:"line"`
+}
+
+func isPlaceholder(secret string) bool {
+	lower := strings.ToLower(secret)
+	if placeholderValues[lower] { return true }
+	for _, p := range placeholders {
+		if strings.Contains(lower, p) { return true }
+	}
+	return false
+}
+
+func allowed(rule *Rule, secret string) bool {
+	for _, re := range rule.Allowlist {
+		if re.MatchString(secret) { return true }
+	}
+	return false
+}
+
+// Scan 按 Catalog 扫描文本，返回互不重叠、按位置排序的命中
+func Scan(text string) []Finding {
+	lower := strings.ToLower(text)
+	var out []Finding
+	overlaps := func(s, e int) bool {
+		for _, f := range out {
+			if s < f.End && e > f.Start { return true }
+		}
+		return false
+	}
+	for i := range Catalog {
+		rule := &Catalog[i]
+		if len(rule.Keywords) > 0 {
+			hit := false
+			for _, k := range rule.Keywords {
+				if strings.Contains(lower, k) { hit = true; break }
+			}
+			if !hit { continue }
+		}
+		for _, m := range rule.Regex.FindAllStringSubmatchIndex(text, -1) {
+			s, e := m[2*rule.SecretGroup], m[2*rule.SecretGroup+1]
+			if s < 0 { continue }
+			secret := text[s:e]
+			if isPlaceholder(secret) || allowed(rule, secret) { continue }
+			if rule.MinEntropy > 0 && nlp.ShannonEntropy(secret) < rule.MinEntropy { continue }
+			if overlaps(s, e) { continue }
+			out = append(out, Finding{RuleID: rule.ID, Start: s, End: e, Line: 1 + strings.Count(text[:s], "\n")})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	return out
+}
+
+// Select 按规则白名单/黑名单筛选命中，名单为空时不限制
+func Select(findings []Finding, include, exclude map[string]bool) []Finding {
+	if len(include) == 0 && len(exclude) == 0 { return findings }
+	var out []Finding
+	for _, f := range findings {
+		if (len(include) == 0 || include[f.RuleID]) && !exclude[f.RuleID] { out = append(out, f) }
+	}
+	return out
+}
+
+// Redact 把命中的片段替换为 placeholder，其中的 {rule} 替换为规则 ID
+func Redact(text string, findings []Finding, placeholder string) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(text[last:f.Start])
+		b.WriteString(strings.ReplaceAll(placeholder, "{rule}", f.RuleID))
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// CountByRule 按规则统计命中数
+func CountByRule(findings []Finding) map[string]int {
+	out := make(map[string]int)
+	for _, f := range findings { out[f.RuleID]++ }
+	return out
+}
+
+// KnownRule 报告规则 ID 是否在 Catalog 中
+func KnownRule(id string) bool {
+	for _, r := range Catalog {
+		if r.ID == id { return true }
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+// Fingerprint 计算 64 位 SimHash：每个特征的哈希在各位上按权重投票，正票位置 1
+func Fingerprint(features map[string]float64) uint64 {
+	var v [64]float64
+	for feat, w := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feat))
+		x := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if x&(1<<uint(i)) != 0 { v[i] += w } else { v[i] -= w }
+		}
+	}
+	var fp uint64
+	for i := 0; i < 64; i++ { if v[i] > 0 { fp |= 1 << uint(i) } }
+	return fp
+}
+
+func Distance(a, b uint64) int { return bits.OnesCount64(a ^ b) }
+
+// Index 是 Manku 等人的置换表索引：指纹切成 Blocks 块，汉明距离不超过 K 的两个指纹
+// 至少有 Blocks-K 块完全相同，因此为每种 (Blocks-K) 块组合建一张以这些块为键的表。
+type Index struct {
+	K      int
+	Blocks int
+	masks  []uint64
+	tables []map[uint64][]int
+	fps    []uint64
+}
+
+type Stats struct {
+	K       int `json:"k"`
+	Blocks  int `json:"blocks"`
+	Tables  int `json:"tables"`
+	Entries int `json:"entries"`
+	Buckets int `json:"buckets"`
+	Max     int `json:"max_bucket"`
+}
+
+func NewIndex(k, blocks int) *Index {
+	if k < 0 { k = 0 }
+	if blocks <= k { blocks = k + 1 }
+	if blocks > 64 { blocks = 64 }
+	idx := &Index{K: k, Blocks: blocks}
+
+	blockMasks := make([]uint64, blocks)
+	for b := 0; b < blocks; b++ {
+		lo, hi := b*64/blocks, (b+1)*64/blocks
+		for i := lo; i < hi; i++ { blockMasks[b] |= 1 << uint(i) }
+	}
+	var combine func(start, left int, mask uint64)
+	combine = func(start, left int, mask uint64) {
+		if left == 0 { idx.masks = append(idx.masks, mask); return }
+		for b := start; b <= blocks-left; b++ { combine(b+1, left-1, mask|blockMasks[b]) }
+	}
+	combine(0, blocks-k, 0)
+	idx.tables = make([]map[uint64][]int, len(idx.masks))
+	for i := range idx.tables { idx.tables[i] = make(map[uint64][]int) }
+	return idx
+}
+
+func (idx *Index) Add(fp uint64) int {
+	id := len(idx.fps)
+	idx.fps = append(idx.fps, fp)
+	for t, mask := range idx.masks { idx.tables[t][fp&mask] = append(idx.tables[t][fp&mask], id) }
+	return id
+}
+
+// Nearest 返回距离不超过 K 的最近指纹 id 与距离，以及核验过的候选数
+func (idx *Index) Nearest(fp uint64) (id, dist, candidates int) {
+	id, dist = -1, 65
+	seen := make(map[int]struct{})
+	for t, mask := range idx.masks {
+		for _, c := range idx.tables[t][fp&mask] {
+			if _, ok := seen[c]; ok { continue }
+			seen[c] = struct{}{}
+			if d := Distance(fp, idx.fps[c]); d <= idx.K && d < dist { id, dist = c, d }
+		}
+	}
+	return id, dist, len(seen)
+}
+
+func (idx *Index) Len() int { return len(idx.fps) }
+
+func (idx *Index) Stats() Stats {
+	st := Stats{K: idx.K, Blocks: idx.Blocks, Tables: len(idx.tables), Entries: len(idx.fps)}
+	for _, table := range idx.tables {
+		st.Buckets += len(table)
+		for _, ids := range table { if len(ids) > st.Max { st.Max = len(ids) } }
+	}
+	return st
+}
@@ -3,6 +3,7 @@ package synthetic
 import (
 	"graunt/internal/external"
 	"graunt/internal/model"
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -10,7 +11,7 @@ import (
 type DPOConstruct struct{}
 func (d *DPOConstruct) Name() string { return "dpo_pairs" }
 
-func (d *DPOConstruct) Synthesize(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error) {
+func (d *DPOConstruct) Synthesize(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error) {
 	var wg sync.WaitGroup
 	var chosen, rejected string
 	var err1, err2 error
@@ -18,8 +19,8 @@ func (d *DPOConstruct) Synthesize(prompt string, params map[string]interface{},
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		r, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), model.VLLMRequest{
-			Model: params["model"].(string), Messages: []model.Message{{Role: "system", Content: "Give a perfect answer."}, {Role: "user", Content: prompt}}, MaxTokens: 1024, Temperature: 0.2,
+		r, err := llm.CallChatCompletion(params["vllm_base_url"].(string), model.VLLMRequest{
+			Model: params["model"].(string), Messages: []model.Message{{Role: "system", Content: "Give a perfect answer."}, {Role: "user", Content: prompt}}, MaxTokens: 1024, Temperature: 0.2, Seed: external.SeedParam(params),
 		})
 		if err == nil && len(r.Choices) > 0 { chosen = r.Choices[0].Message.Content } else { err1 = err }
 	}()
@@ -27,14 +28,14 @@ func (d *DPOConstruct) Synthesize(prompt string, params map[string]interface{},
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		r, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), model.VLLMRequest{
-			Model: params["model"].(string), Messages: []model.Message{{Role: "system", Content: "Give a terrible answer."}, {Role: "user", Content: prompt}}, MaxTokens: 1024, Temperature: 1.2,
+		r, err := llm.CallChatCompletion(params["vllm_base_url"].(string), model.VLLMRequest{
+			Model: params["model"].(string), Messages: []model.Message{{Role: "system", Content: "Give a terrible answer."}, {Role: "user", Content: prompt}}, MaxTokens: 1024, Temperature: 1.2, Seed: external.SeedParam(params),
 		})
 		if err == nil && len(r.Choices) > 0 { rejected = r.Choices[0].Message.Content } else { err2 = err }
 	}()
 
 	wg.Wait()
-	if err1 != nil || err2 != nil { return nil, fmt.Errorf("failed generating pairs") }
+	if err := errors.Join(err1, err2); err != nil { return nil, fmt.Errorf("failed generating pairs: %w", err) }
 
 	return model.DPOPair{Prompt: prompt, Chosen: chosen, Rejected: rejected}, nil
 }
\ No newline at end of file
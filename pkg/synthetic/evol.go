@@ -9,7 +9,7 @@ import (
 type EvolInstruct struct{}
 func (e *EvolInstruct) Name() string { return "evol_instruct" }
 
-func (e *EvolInstruct) Synthesize(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error) {
+func (e *EvolInstruct) Synthesize(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error) {
 	evolType := "in-depth"
 	if t, ok := params["evol_type"].(string); ok { evolType = t }
 
@@ -18,11 +18,12 @@ func (e *EvolInstruct) Synthesize(prompt string, params map[string]interface{},
 
 	req := model.VLLMRequest{
 		Model:       params["model"].(string),
+		Seed:        external.SeedParam(params),
 		Messages:    []model.Message{{Role: "system", Content: sys}, {Role: "user", Content: prompt}},
 		MaxTokens:   1024, Temperature: 0.7,
 	}
 
-	resp, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), req)
-	if err != nil || len(resp.Choices) == 0 { return nil, fmt.Errorf("evol failed") }
+	resp, err := llm.CallChatCompletion(params["vllm_base_url"].(string), req)
+	if err != nil { return nil, fmt.Errorf("evol failed: %w", err) }
 	return resp.Choices[0].Message.Content, nil
 }
\ No newline at end of file
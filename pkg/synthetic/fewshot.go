@@ -10,7 +10,7 @@ import (
 type FewshotSynthetic struct{}
 func (s *FewshotSynthetic) Name() string { return "fewshot" }
 
-func (s *FewshotSynthetic) Synthesize(prompt string, params map[string]interface{}, vllm *external.VLLMClient) (interface{}, error) {
+func (s *FewshotSynthetic) Synthesize(prompt string, params map[string]interface{}, llm external.LLM) (interface{}, error) {
 	domain := "general"
 	if d, ok := params["domain"].(string); ok { domain = d }
 
@@ -23,11 +23,12 @@ func (s *FewshotSynthetic) Synthesize(prompt string, params map[string]interface
 
 	req := model.VLLMRequest{
 		Model:       params["model"].(string),
+		Seed:        external.SeedParam(params),
 		Messages:    []model.Message{{Role: "system", Content: sysPrompt}, {Role: "user", Content: prompt}},
 		MaxTokens:   2048, Temperature: 0.8,
 	}
 
-	resp, err := vllm.CallChatCompletion(params["vllm_base_url"].(string), req)
-	if err != nil || len(resp.Choices) == 0 { return nil, fmt.Errorf("fewshot generation failed") }
+	resp, err := llm.CallChatCompletion(params["vllm_base_url"].(string), req)
+	if err != nil { return nil, fmt.Errorf("fewshot generation failed: %w", err) }
 	return resp.Choices[0].Message.Content, nil
 }
\ No newline at end of file
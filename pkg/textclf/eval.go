@@ -0,0 +1,97 @@
+package textclf
+
+import (
+	"graunt/pkg/jsonl"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+type LabelMetrics struct {
+	Label     string  `json:"label"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	Support   int     `json:"support"`
+}
+
+type Metrics struct {
+	Examples       int            `json:"examples"`
+	Accuracy       float64        `json:"accuracy"`
+	MacroPrecision float64        `json:"macro_precision"`
+	MacroRecall    float64        `json:"macro_recall"`
+	MacroF1        float64        `json:"macro_f1"`
+	Labels         []LabelMetrics `json:"labels"`
+}
+
+// Evaluate 以 top-1 预测计算准确率及每个标签的精确率/召回率；模型未见过的标签计为召回失败
+func (m *Model) Evaluate(examples []Example) Metrics {
+	tp, predicted, actual := map[string]int{}, map[string]int{}, map[string]int{}
+	correct := 0
+	for _, ex := range examples {
+		preds := m.Predict(ex.Text)
+		actual[ex.Label]++
+		if len(preds) == 0 { continue }
+		predicted[preds[0].Label]++
+		if preds[0].Label == ex.Label { tp[ex.Label]++; correct++ }
+	}
+	labels := make([]string, 0, len(actual))
+	seen := map[string]bool{}
+	for _, l := range append(append([]string{}, m.Labels...), keys(actual)...) {
+		if !seen[l] { seen[l] = true; labels = append(labels, l) }
+	}
+	sort.Strings(labels)
+
+	res := Metrics{Examples: len(examples)}
+	if len(examples) > 0 { res.Accuracy = float64(correct) / float64(len(examples)) }
+	for _, l := range labels {
+		lbl := LabelMetrics{Label: l, Support: actual[l]}
+		if predicted[l] > 0 { lbl.Precision = float64(tp[l]) / float64(predicted[l]) }
+		if actual[l] > 0 { lbl.Recall = float64(tp[l]) / float64(actual[l]) }
+		if lbl.Precision+lbl.Recall > 0 { lbl.F1 = 2 * lbl.Precision * lbl.Recall / (lbl.Precision + lbl.Recall) }
+		res.Labels = append(res.Labels, lbl)
+		res.MacroPrecision += lbl.Precision
+		res.MacroRecall += lbl.Recall
+		res.MacroF1 += lbl.F1
+	}
+	if n := float64(len(res.Labels)); n > 0 { res.MacroPrecision /= n; res.MacroRecall /= n; res.MacroF1 /= n }
+	return res
+}
+
+func keys(m map[string]int) []string {
+	out := make([]string, 0, len(m))
+	for k := range m { out = append(out, k) }
+	return out
+}
+
+// Split 按种子打乱后切出 fraction 比例的验证集
+func Split(examples []Example, fraction float64, seed int64) (train, valid []Example) {
+	if fraction <= 0 || fraction >= 1 { return examples, nil }
+	shuffled := append([]Example(nil), examples...)
+	rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	n := int(float64(len(shuffled)) * fraction)
+	return shuffled[n:], shuffled[:n]
+}
+
+// ReadExamples 读取 {"text","label"} 每行一条的 JSONL (可 .gz/.zst 压缩)；label 为空时使用 defaultLabel
+func ReadExamples(path, defaultLabel string) ([]Example, error) {
+	in, err := jsonl.Open(path)
+	if err != nil { return nil, err }
+	defer in.Close()
+	sc := jsonl.NewScanner(in)
+	var out []Example
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 { continue }
+		var ex Example
+		if err := json.Unmarshal(raw, &ex); err != nil { return out, fmt.Errorf("%s:%d: %v", path, line, err) }
+		if ex.Label == "" { ex.Label = defaultLabel }
+		if ex.Label == "" { return out, fmt.Errorf("%s:%d: missing label", path, line) }
+		out = append(out, ex)
+	}
+	return out, sc.Err()
+}
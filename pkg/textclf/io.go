@@ -0,0 +1,77 @@
+package textclf
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Ext 是分类器模型文件扩展名
+const Ext = ".clf"
+
+const modelVersion = 1
+
+type fileHeader struct {
+	Version int
+	Labels  []string
+}
+
+// Save 以 zstd 压缩的 gob 格式原子写入模型
+func (m *Model) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return err }
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".clf-*")
+	if err != nil { return err }
+	zw, err := zstd.NewWriter(tmp)
+	if err != nil { tmp.Close(); os.Remove(tmp.Name()); return err }
+	enc := gob.NewEncoder(zw)
+	if err := enc.Encode(fileHeader{modelVersion, m.Labels}); err != nil { zw.Close(); tmp.Close(); os.Remove(tmp.Name()); return err }
+	if err := enc.Encode(m); err != nil { zw.Close(); tmp.Close(); os.Remove(tmp.Name()); return err }
+	if err := zw.Close(); err != nil { tmp.Close(); os.Remove(tmp.Name()); return err }
+	if err := tmp.Close(); err != nil { os.Remove(tmp.Name()); return err }
+	return os.Rename(tmp.Name(), path)
+}
+
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, err }
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil { return nil, err }
+	defer zr.Close()
+	dec := gob.NewDecoder(zr)
+	var hdr fileHeader
+	if err := dec.Decode(&hdr); err != nil { return nil, fmt.Errorf("%s: invalid classifier model: %v", path, err) }
+	if hdr.Version != modelVersion { return nil, fmt.Errorf("%s: unsupported classifier model version %d", path, hdr.Version) }
+	m := &Model{}
+	if err := dec.Decode(m); err != nil { return nil, fmt.Errorf("%s: truncated classifier model: %v", path, err) }
+	if len(m.Input) != m.Buckets*m.Dim || len(m.Output) != len(m.Labels)*m.Dim { return nil, fmt.Errorf("%s: corrupt classifier model", path) }
+	return m, nil
+}
+
+type cachedModel struct {
+	model   *Model
+	modTime time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cachedModel)
+)
+
+// Cached 按路径缓存已加载的模型，文件被重新训练覆盖后自动重新加载
+func Cached(path string) (*Model, error) {
+	st, err := os.Stat(path)
+	if err != nil { return nil, err }
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := cache[path]; ok && c.modTime.Equal(st.ModTime()) { return c.model, nil }
+	m, err := Load(path)
+	if err != nil { return nil, err }
+	cache[path] = cachedModel{m, st.ModTime()}
+	return m, nil
+}
@@ -0,0 +1,175 @@
+package textclf
+
+import (
+	"graunt/pkg/nlp"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Model 是 fastText 式的监督分类器：词与词 n-gram 经哈希映射到 Buckets 行的输入矩阵，
+// 取平均作为文本向量，再经线性层与 softmax 输出各标签概率
+type Model struct {
+	Labels     []string
+	Dim        int
+	WordNgrams int
+	Buckets    int
+	Input      []float32 // Buckets x Dim
+	Output     []float32 // len(Labels) x Dim
+}
+
+type Options struct {
+	Dim        int     `json:"dim,omitempty"`         // 向量维度，默认 16
+	Epochs     int     `json:"epochs,omitempty"`      // 默认 5
+	LR         float64 `json:"lr,omitempty"`          // 初始学习率，线性衰减到 0，默认 0.1
+	WordNgrams int     `json:"word_ngrams,omitempty"` // 最大词 n-gram 阶数，默认 2
+	Buckets    int     `json:"buckets,omitempty"`     // 哈希桶数，默认 2^18
+	Seed       int64   `json:"seed,omitempty"`
+}
+
+// 训练参数上限：输入矩阵有 Buckets×Dim 个 float32，参数来自请求，不加限制会耗尽内存或溢出 int
+const (
+	MaxDim     = 512
+	MaxBuckets = 1 << 22
+	MaxParams  = 1 << 27 // Buckets×Dim，约 512 MiB
+)
+
+// Validate 检查 Dim / Buckets 没有超过上限 (未给出时取默认值)
+func (o Options) Validate() error {
+	o = o.withDefaults()
+	if o.Dim > MaxDim { return fmt.Errorf("dim %d exceeds %d", o.Dim, MaxDim) }
+	if o.Buckets > MaxBuckets { return fmt.Errorf("buckets %d exceeds %d", o.Buckets, MaxBuckets) }
+	if o.Dim*o.Buckets > MaxParams { return fmt.Errorf("dim x buckets = %d exceeds %d", o.Dim*o.Buckets, MaxParams) }
+	return nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.Dim <= 0 { o.Dim = 16 }
+	if o.Epochs <= 0 { o.Epochs = 5 }
+	if o.LR <= 0 { o.LR = 0.1 }
+	if o.WordNgrams <= 0 { o.WordNgrams = 2 }
+	if o.Buckets <= 0 { o.Buckets = 1 << 18 }
+	if o.Seed == 0 { o.Seed = 1 }
+	return o
+}
+
+type Example struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+type Prediction struct {
+	Label string  `json:"label"`
+	Prob  float64 `json:"prob"`
+}
+
+func hashFeature(s string, buckets int) int {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int(h.Sum64() % uint64(buckets))
+}
+
+// features 返回文本的哈希特征：分词 (中日韩按字) 后的 1..wordNgrams 元组
+func features(text string, wordNgrams, buckets int) []int {
+	toks := nlp.Tokens(text)
+	out := make([]int, 0, len(toks)*wordNgrams)
+	for n := 1; n <= wordNgrams; n++ {
+		for i := 0; i+n <= len(toks); i++ { out = append(out, hashFeature(strings.Join(toks[i:i+n], " "), buckets)) }
+	}
+	return out
+}
+
+func (m *Model) hidden(feats []int, h []float32) {
+	for i := range h { h[i] = 0 }
+	if len(feats) == 0 { return }
+	for _, f := range feats {
+		row := m.Input[f*m.Dim : (f+1)*m.Dim]
+		for i, v := range row { h[i] += v }
+	}
+	inv := 1 / float32(len(feats))
+	for i := range h { h[i] *= inv }
+}
+
+func (m *Model) softmax(h []float32, probs []float64) {
+	maxScore := math.Inf(-1)
+	for l := range m.Labels {
+		s := 0.0
+		row := m.Output[l*m.Dim : (l+1)*m.Dim]
+		for i, v := range row { s += float64(v * h[i]) }
+		probs[l] = s
+		if s > maxScore { maxScore = s }
+	}
+	sum := 0.0
+	for l := range probs { probs[l] = math.Exp(probs[l] - maxScore); sum += probs[l] }
+	for l := range probs { probs[l] /= sum }
+}
+
+// Train 以 softmax 交叉熵做 SGD，学习率随训练进度线性衰减
+func Train(examples []Example, opts Options) *Model {
+	opts = opts.withDefaults()
+	labelIdx := make(map[string]int)
+	m := &Model{Dim: opts.Dim, WordNgrams: opts.WordNgrams, Buckets: opts.Buckets}
+	for _, ex := range examples {
+		if _, ok := labelIdx[ex.Label]; !ok { labelIdx[ex.Label] = 0; m.Labels = append(m.Labels, ex.Label) }
+	}
+	sort.Strings(m.Labels)
+	for i, l := range m.Labels { labelIdx[l] = i }
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	m.Input = make([]float32, m.Buckets*m.Dim)
+	for i := range m.Input { m.Input[i] = float32((rng.Float64()*2 - 1) / float64(m.Dim)) }
+	m.Output = make([]float32, len(m.Labels)*m.Dim)
+
+	feats := make([][]int, len(examples))
+	for i, ex := range examples { feats[i] = features(ex.Text, m.WordNgrams, m.Buckets) }
+	order := make([]int, len(examples))
+	for i := range order { order[i] = i }
+
+	h := make([]float32, m.Dim)
+	grad := make([]float32, m.Dim)
+	probs := make([]float64, len(m.Labels))
+	total, step := opts.Epochs*len(examples), 0
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		for _, idx := range order {
+			lr := float32(opts.LR * (1 - float64(step)/float64(total)))
+			step++
+			f := feats[idx]
+			if len(f) == 0 { continue }
+			m.hidden(f, h)
+			m.softmax(h, probs)
+			target := labelIdx[examples[idx].Label]
+			for i := range grad { grad[i] = 0 }
+			for l := range m.Labels {
+				g := float32(probs[l])
+				if l == target { g -= 1 }
+				row := m.Output[l*m.Dim : (l+1)*m.Dim]
+				for i := range row {
+					grad[i] += g * row[i]
+					row[i] -= lr * g * h[i]
+				}
+			}
+			scale := lr / float32(len(f))
+			for _, fi := range f {
+				row := m.Input[fi*m.Dim : (fi+1)*m.Dim]
+				for i := range row { row[i] -= scale * grad[i] }
+			}
+		}
+	}
+	return m
+}
+
+// Predict 返回按概率降序排列的全部标签
+func (m *Model) Predict(text string) []Prediction {
+	h := make([]float32, m.Dim)
+	probs := make([]float64, len(m.Labels))
+	m.hidden(features(text, m.WordNgrams, m.Buckets), h)
+	m.softmax(h, probs)
+	out := make([]Prediction, len(m.Labels))
+	for l, label := range m.Labels { out[l] = Prediction{label, probs[l]} }
+	sort.Slice(out, func(i, j int) bool { return out[i].Prob > out[j].Prob })
+	return out
+}
@@ -0,0 +1,97 @@
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 两类可逆的乱码：UTF-8 字节被当成 GBK 解码 ("浣犲ソ" ← "你好")，或被当成 Windows-1252/Latin-1 解码 ("ä½ å¥½"、"Ã©")。
+// 按可疑字符的连续片段处理：把片段按错误的编码重新编码，若得到合法 UTF-8 且字符更少、不含控制字符，则替换。
+// GBK 字节被当成 UTF-8 解码时已变成 U+FFFD，无法恢复。
+
+// sloppy1252 按 Windows-1252 编码，1252 未定义的 0x81/0x8D/0x8F/0x90/0x9D 与其余 Latin-1 字符按码位直接取字节，
+// 这样误解码时保留为 C1 控制字符的字节也能还原
+var sloppy1252 = func() map[rune]byte {
+	m := make(map[rune]byte, 256)
+	for b := 0x80; b <= 0xff; b++ {
+		m[rune(b)] = byte(b)
+		if r := charmap.Windows1252.DecodeByte(byte(b)); r != utf8.RuneError { m[r] = byte(b) }
+	}
+	return m
+}()
+
+func isLatinSuspect(r rune) bool { _, ok := sloppy1252[r]; return ok }
+
+func encodeLatin(run string) ([]byte, bool) {
+	b := make([]byte, 0, len(run))
+	for _, r := range run {
+		c, ok := sloppy1252[r]
+		if !ok { return nil, false }
+		b = append(b, c)
+	}
+	return b, true
+}
+
+func isGBKSuspect(r rune) bool {
+	return r > 0x7f && !unicode.IsSpace(r) && r != utf8.RuneError
+}
+
+func encodeGBK(run string) ([]byte, bool) {
+	b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(run))
+	return b, err == nil
+}
+
+// reencode 把片段按误用的编码还原成字节，并检查这些字节能否解码为更短的正常 UTF-8 文本
+func reencode(run string, encode func(string) ([]byte, bool)) (string, bool) {
+	b, ok := encode(run)
+	if !ok || !utf8.Valid(b) { return "", false }
+	fixed := string(b)
+	if utf8.RuneCountInString(fixed) >= utf8.RuneCountInString(run) { return "", false }
+	for _, r := range fixed {
+		if r == utf8.RuneError || (unicode.IsControl(r) && r != '\n' && r != '\t') { return "", false }
+	}
+	return fixed, true
+}
+
+// repairRuns 对 suspect 字符组成的每个连续片段 (至少 minLen 个字符) 尝试 reencode；
+// 若整段失败，再尝试去掉末尾一个字符 (奇数字节被解码器吞并的情况)
+func repairRuns(text string, suspect func(rune) bool, encode func(string) ([]byte, bool), minLen int) (string, int) {
+	var b strings.Builder
+	fixed := 0
+	rs := []rune(text)
+	for i := 0; i < len(rs); {
+		if !suspect(rs[i]) { b.WriteRune(rs[i]); i++; continue }
+		j := i
+		for j < len(rs) && suspect(rs[j]) { j++ }
+		run := string(rs[i:j])
+		if j-i >= minLen {
+			if out, ok := reencode(run, encode); ok {
+				b.WriteString(out)
+				fixed++
+				i = j
+				continue
+			}
+			if out, ok := reencode(string(rs[i:j-1]), encode); ok && j-1-i >= minLen {
+				b.WriteString(out)
+				b.WriteRune(rs[j-1])
+				fixed++
+				i = j
+				continue
+			}
+		}
+		b.WriteString(run)
+		i = j
+	}
+	return b.String(), fixed
+}
+
+// RepairMojibake 修复常见的 GBK/Latin-1 误解码乱码，返回修复后的文本与修复的片段数
+func RepairMojibake(text string) (string, int) {
+	out, n1 := repairRuns(text, isLatinSuspect, encodeLatin, 2)
+	out, n2 := repairRuns(out, isGBKSuspect, encodeGBK, 2)
+	return out, n1 + n2
+}
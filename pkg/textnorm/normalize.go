@@ -0,0 +1,230 @@
+package textnorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 步骤按固定顺序执行：先修乱码 (需要原始字符)，再删不可见字符，再做兼容分解与全角转换，繁简转换在其后，最后整理空白
+const (
+	StepMojibake   = "mojibake"
+	StepControl    = "control"
+	StepNFKC       = "nfkc"
+	StepFullwidth  = "fullwidth"
+	StepT2S        = "t2s"
+	StepWhitespace = "whitespace"
+)
+
+var (
+	AllSteps = []string{StepMojibake, StepControl, StepNFKC, StepFullwidth, StepT2S, StepWhitespace}
+	// DefaultSteps 不含 t2s：繁简转换会改写正体中文语料，需要显式开启
+	DefaultSteps = []string{StepMojibake, StepControl, StepNFKC, StepFullwidth, StepWhitespace}
+)
+
+// Options 为一次规范化选中的步骤；Converter 为空时 t2s 使用内置字表
+type Options struct {
+	Steps     map[string]bool
+	Converter *Converter
+}
+
+// Stats 记录各步骤实际改动的字符或片段数
+type Stats map[string]int
+
+// ParseSteps 校验步骤名；空列表返回 DefaultSteps
+func ParseSteps(names []string) (map[string]bool, error) {
+	if len(names) == 0 { names = DefaultSteps }
+	steps := make(map[string]bool, len(names))
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "all" {
+			for _, s := range AllSteps { steps[s] = true }
+			continue
+		}
+		known := false
+		for _, s := range AllSteps {
+			if s == n { known = true; break }
+		}
+		if !known { return nil, fmt.Errorf("unknown normalize step '%s' (want one of %s)", n, strings.Join(AllSteps, ", ")) }
+		steps[n] = true
+	}
+	return steps, nil
+}
+
+// Key 返回步骤组合的稳定表示，用作缓存键
+func (o Options) Key() string {
+	var parts []string
+	for _, s := range AllSteps {
+		if o.Steps[s] { parts = append(parts, s) }
+	}
+	return strings.Join(parts, ",")
+}
+
+// invisible 为需要删除的零宽与方向控制字符；ZWJ 只在不连接 emoji 时删除
+func invisible(r rune) bool {
+	switch {
+	case r == '\u200b', r == '\u200c', r == '\u200e', r == '\u200f', r == '\ufeff', r == '\u00ad', r == '\u180e':
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2060' && r <= '\u2064', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+func removeControl(text string) (string, int) {
+	var b strings.Builder
+	removed := 0
+	rs := []rune(text)
+	for i, r := range rs {
+		drop := false
+		switch {
+		case r == '\n', r == '\t':
+		case r == '\r':
+			drop = i+1 < len(rs) && rs[i+1] == '\n'
+			if !drop { r = '\n' }
+		case unicode.IsControl(r), invisible(r):
+			drop = true
+		case r == '\u200d':
+			drop = i == 0 || i+1 == len(rs) || !unicode.Is(unicode.So, rs[i-1]) && !unicode.Is(unicode.Sk, rs[i-1])
+		}
+		if drop { removed++; continue }
+		b.WriteRune(r)
+	}
+	return b.String(), removed
+}
+
+// toHalfwidth 把全角 ASCII (U+FF01–U+FF5E) 与全角空格转为半角
+func toHalfwidth(text string) (string, int) {
+	changed := 0
+	out := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '\uff01' && r <= '\uff5e':
+			changed++
+			return r - 0xfee0
+		case r == '\u3000':
+			changed++
+			return ' '
+		}
+		return r
+	}, text)
+	return out, changed
+}
+
+const hspace = `[ \t\x{00a0}\x{2000}-\x{200a}\x{202f}\x{205f}\x{3000}]`
+
+var (
+	hspaceRun = regexp.MustCompile(hspace + `+`)
+	indent    = regexp.MustCompile(`^` + hspace + `*`)
+)
+
+// codeFence 返回以 ``` 或 ~~~ 开头的行的围栏标记，否则返回空串
+func codeFence(trimmed string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, c))
+		if n >= 3 { return strings.Repeat(c, n) }
+	}
+	return ""
+}
+
+// collapseWhitespace 合并行内连续空白、去掉行尾空白，行首缩进保留；连续空行合并为一个，去掉首尾空行。
+// 围栏代码块 (``` 或 ~~~) 内的内容原样保留
+func collapseWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for _, line := range lines {
+		lead := indent.FindString(line)
+		body := line[len(lead):]
+		if fence != "" {
+			out = append(out, line)
+			if strings.HasPrefix(body, fence) && strings.TrimSpace(strings.TrimLeft(body, fence[:1])) == "" { fence = "" }
+			continue
+		}
+		if fence = codeFence(body); fence != "" {
+			out = append(out, line)
+			continue
+		}
+		body = strings.TrimRight(hspaceRun.ReplaceAllString(body, " "), " ")
+		if body == "" {
+			if len(out) > 0 && out[len(out)-1] != "" { out = append(out, "") }
+			continue
+		}
+		out = append(out, lead+body)
+	}
+	for len(out) > 0 && out[len(out)-1] == "" { out = out[:len(out)-1] }
+	return strings.Join(out, "\n")
+}
+
+func diffRunes(a, b string) int {
+	if a == b { return 0 }
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		if ra[i] != rb[i] { n++ }
+	}
+	if d := len(ra) - len(rb); d > 0 { n += d } else { n -= d }
+	return n
+}
+
+// Normalize 按 opts 选中的步骤规范化文本
+func Normalize(text string, opts Options) (string, Stats) {
+	st := make(Stats)
+	ascii := true
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 0x80 { ascii = false; break }
+	}
+	if opts.Steps[StepMojibake] && !ascii {
+		var n int
+		if text, n = RepairMojibake(text); n > 0 { st[StepMojibake] = n }
+	}
+	if opts.Steps[StepControl] {
+		var n int
+		if text, n = removeControl(text); n > 0 { st[StepControl] = n }
+	}
+	if opts.Steps[StepNFKC] && !ascii {
+		if out := norm.NFKC.String(text); out != text { st[StepNFKC] = diffRunes(text, out); text = out }
+	}
+	if opts.Steps[StepFullwidth] && !ascii {
+		var n int
+		if text, n = toHalfwidth(text); n > 0 { st[StepFullwidth] = n }
+	}
+	if opts.Steps[StepT2S] && !ascii {
+		conv := opts.Converter
+		if conv == nil { conv = DefaultConverter() }
+		if out := conv.Convert(text); out != text { st[StepT2S] = diffRunes(text, out); text = out }
+	}
+	if opts.Steps[StepWhitespace] {
+		if out := collapseWhitespace(text); out != text { st[StepWhitespace] = len(text) - len(out); text = out }
+	}
+	return text, st
+}
+
+// ParseOptions 解析参数形式的步骤 (字符串列表、逗号分隔字符串，或 true 表示 DefaultSteps) 与可选的 OpenCC 格式词典名 (DictDir 下的文件)
+func ParseOptions(steps interface{}, dictName string) (Options, error) {
+	var names []string
+	switch v := steps.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" { names = append(names, s) }
+		}
+	case []string:
+		names = v
+	case []interface{}:
+		for _, x := range v {
+			if s, ok := x.(string); ok { names = append(names, s) }
+		}
+	}
+	var opts Options
+	var err error
+	if opts.Steps, err = ParseSteps(names); err != nil { return opts, err }
+	if dictName != "" {
+		path, err := DictPath(dictName)
+		if err != nil { return opts, err }
+		if opts.Converter, err = CachedDict(path); err != nil { return opts, fmt.Errorf("load t2s dict: %v", err) }
+		opts.Steps[StepT2S] = true
+	}
+	return opts, nil
+}
@@ -0,0 +1,144 @@
+package textnorm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// t2sPhrases 为逐字转换会出错的词，优先于单字表匹配
+var t2sPhrases = map[string]string{
+	"乾隆": "乾隆", "乾坤": "乾坤", "乾卦": "乾卦", "乾元": "乾元", "乾清宮": "乾清宫", "乾陵": "乾陵",
+	"慰藉": "慰藉", "狼藉": "狼藉", "蘊藉": "蕴藉", "枕藉": "枕藉",
+}
+
+// Converter 做繁→简转换：先按最长词匹配，再逐字查表
+type Converter struct {
+	chars     map[rune]rune
+	phrases   map[string]string
+	starts    map[rune]bool // 词表中词的首字
+	maxPhrase int
+}
+
+var (
+	defaultOnce sync.Once
+	defaultConv *Converter
+)
+
+// DefaultConverter 返回内置字表加常见例外词的转换器
+func DefaultConverter() *Converter {
+	defaultOnce.Do(func() {
+		c := &Converter{chars: make(map[rune]rune, len(t2sPairs)/6), phrases: make(map[string]string), starts: make(map[rune]bool)}
+		rs := []rune(t2sPairs)
+		for i := 0; i+1 < len(rs); i += 2 { c.chars[rs[i]] = rs[i+1] }
+		for k, v := range t2sPhrases { c.add(k, v) }
+		defaultConv = c
+	})
+	return defaultConv
+}
+
+func (c *Converter) add(from, to string) {
+	if utf8.RuneCountInString(from) == 1 && utf8.RuneCountInString(to) == 1 {
+		r, _ := utf8.DecodeRuneInString(from)
+		t, _ := utf8.DecodeRuneInString(to)
+		c.chars[r] = t
+		return
+	}
+	c.phrases[from] = to
+	first, _ := utf8.DecodeRuneInString(from)
+	c.starts[first] = true
+	c.maxPhrase = max(c.maxPhrase, utf8.RuneCountInString(from))
+}
+
+// LoadDict 在内置表之上叠加 OpenCC 格式的词典 (每行 "繁体\t简体[ 其他候选]"，# 开头为注释)，单字条目进字表，多字条目进词表
+func LoadDict(path string) (*Converter, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, err }
+	defer f.Close()
+	base := DefaultConverter()
+	c := &Converter{chars: make(map[rune]rune, len(base.chars)), phrases: make(map[string]string, len(base.phrases)), starts: make(map[rune]bool)}
+	for k, v := range base.chars { c.chars[k] = v }
+	for k, v := range base.phrases { c.add(k, v) }
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") { continue }
+		from, to, ok := strings.Cut(text, "\t")
+		if !ok { return nil, fmt.Errorf("%s:%d: expected 'traditional<TAB>simplified'", path, line) }
+		if fields := strings.Fields(to); len(fields) > 0 { c.add(from, fields[0]) }
+	}
+	return c, sc.Err()
+}
+
+// Convert 把 text 中的繁体字转为简体
+func (c *Converter) Convert(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if c.starts[r] {
+			// 从最长的候选词开始尝试
+			ends := make([]int, 0, c.maxPhrase)
+			for end, n := i, 0; end < len(text) && n < c.maxPhrase; n++ {
+				_, sz := utf8.DecodeRuneInString(text[end:])
+				end += sz
+				ends = append(ends, end)
+			}
+			matched := false
+			for j := len(ends) - 1; j >= 1 && !matched; j-- {
+				if to, ok := c.phrases[text[i:ends[j]]]; ok {
+					b.WriteString(to)
+					i, matched = ends[j], true
+				}
+			}
+			if matched { continue }
+		}
+		if s, ok := c.chars[r]; ok { r = s }
+		b.WriteRune(r)
+		i += size
+	}
+	return b.String()
+}
+
+type cachedDict struct {
+	conv    *Converter
+	modTime time.Time
+}
+
+var (
+	dictMu    sync.Mutex
+	dictCache = make(map[string]cachedDict)
+)
+
+// DictDir 是 normalize_t2s_dict 词典所在的目录，由 main 设为 GRAUNT_DATA_DIR/t2s。
+// 参数只能给出该目录下的文件名，不能借词典参数读取服务器上的任意文件
+var DictDir = filepath.Join("data", "t2s")
+
+var dictNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// DictPath 校验词典名并返回 DictDir 下的路径
+func DictPath(name string) (string, error) {
+	if !dictNamePattern.MatchString(name) || name == "." || name == ".." { return "", fmt.Errorf("invalid t2s dict name '%s'", name) }
+	return filepath.Join(DictDir, name), nil
+}
+
+// CachedDict 按路径缓存词典，文件修改后重新加载
+func CachedDict(path string) (*Converter, error) {
+	st, err := os.Stat(path)
+	if err != nil { return nil, err }
+	dictMu.Lock()
+	defer dictMu.Unlock()
+	if c, ok := dictCache[path]; ok && c.modTime.Equal(st.ModTime()) { return c.conv, nil }
+	conv, err := LoadDict(path)
+	if err != nil { return nil, err }
+	dictCache[path] = cachedDict{conv, st.ModTime()}
+	return conv, nil
+}
@@ -0,0 +1,301 @@
+package vault
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 文件格式: 一行 JSON 头 (KDF 参数与口令校验密文)，随后是追加写入的加密记录，
+// 每条为 4 字节长度 + 12 字节 nonce + AES-256-GCM 密文 (一个 JSON 编码的 Entry)。
+// 只追加不改写，批处理中每新增一条映射只写一条记录。
+const (
+	Ext         = ".vault"
+	fileVersion = 1
+	kdfIter     = 200000
+	checkPlain  = "graunt-vault"
+)
+
+var (
+	ErrPassphrase = errors.New("wrong vault passphrase")
+	namePattern   = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+type header struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Iter    int    `json:"iter"`
+	Check   []byte `json:"check"`
+}
+
+// Entry 是一条 原文↔替身 映射；Scope 为映射生效的范围 (数据集名或文档摘要)
+type Entry struct {
+	Scope     string `json:"scope"`
+	Category  string `json:"category"`
+	Original  string `json:"original"`
+	Surrogate string `json:"surrogate"`
+}
+
+type Vault struct {
+	path        string
+	aead        cipher.AEAD
+	secret      []byte // 生成确定性替身用的 HMAC 密钥，与加密密钥分开派生
+	mu          sync.Mutex
+	f           *os.File
+	byOriginal  map[string]string
+	bySurrogate map[string]Entry
+	lengths     []int // 替身的不同字节长度，从长到短，Reidentify 用
+}
+
+// Path 校验保险库名并返回 dir/<name>.vault
+func Path(dir, name string) (string, error) {
+	if !namePattern.MatchString(name) || name == "." || name == ".." { return "", fmt.Errorf("invalid vault name '%s'", name) }
+	return filepath.Join(dir, name+Ext), nil
+}
+
+// pbkdf2 即 RFC 8018 的 PBKDF2-HMAC-SHA256
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var out []byte
+	for block := uint32(1); len(out) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t { t[j] ^= u[j] }
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
+
+func deriveKeys(passphrase string, salt []byte, iter int) (cipher.AEAD, []byte, error) {
+	key := pbkdf2([]byte(passphrase), salt, iter, 64)
+	block, err := aes.NewCipher(key[:32])
+	if err != nil { return nil, nil, err }
+	aead, err := cipher.NewGCM(block)
+	if err != nil { return nil, nil, err }
+	return aead, key[32:], nil
+}
+
+func (v *Vault) seal(plain []byte) []byte {
+	nonce := make([]byte, v.aead.NonceSize())
+	rand.Read(nonce)
+	return v.aead.Seal(nonce, nonce, plain, nil)
+}
+
+func (v *Vault) open(sealed []byte) ([]byte, error) {
+	n := v.aead.NonceSize()
+	if len(sealed) < n { return nil, errors.New("short vault record") }
+	return v.aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
+
+// Open 打开或新建保险库；口令错误时返回 ErrPassphrase
+func Open(path, passphrase string) (*Vault, error) {
+	if passphrase == "" { return nil, errors.New("vault passphrase is required") }
+	v := &Vault{path: path, byOriginal: make(map[string]string), bySurrogate: make(map[string]Entry)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil { return nil, err }
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil { return nil, err }
+	st, err := f.Stat()
+	if err != nil { f.Close(); return nil, err }
+	if st.Size() == 0 {
+		hdr := header{Version: fileVersion, Salt: make([]byte, 16), Iter: kdfIter}
+		rand.Read(hdr.Salt)
+		if v.aead, v.secret, err = deriveKeys(passphrase, hdr.Salt, hdr.Iter); err != nil { f.Close(); return nil, err }
+		hdr.Check = v.seal([]byte(checkPlain))
+		line, _ := json.Marshal(hdr)
+		if _, err := f.Write(append(line, '\n')); err != nil { f.Close(); return nil, err }
+		v.f = f
+		return v, nil
+	}
+	end, err := v.load(f, st.Size(), passphrase)
+	if err != nil { f.Close(); return nil, err }
+	// 截掉中断写入留下的半条记录，否则新记录追加在残片之后，下次打开时无法解析
+	if end < st.Size() {
+		if err := f.Truncate(end); err != nil { f.Close(); return nil, err }
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil { f.Close(); return nil, err }
+	v.f = f
+	return v, nil
+}
+
+// load 读入全部映射，返回最后一条完整记录的结束位置
+func (v *Vault) load(f *os.File, fileSize int64, passphrase string) (int64, error) {
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil { return 0, fmt.Errorf("%s: invalid vault header: %v", v.path, err) }
+	var hdr header
+	if err := json.Unmarshal(line, &hdr); err != nil { return 0, fmt.Errorf("%s: invalid vault header: %v", v.path, err) }
+	if hdr.Version != fileVersion { return 0, fmt.Errorf("%s: unsupported vault version %d", v.path, hdr.Version) }
+	if v.aead, v.secret, err = deriveKeys(passphrase, hdr.Salt, hdr.Iter); err != nil { return 0, err }
+	if plain, err := v.open(hdr.Check); err != nil || string(plain) != checkPlain { return 0, ErrPassphrase }
+	end := int64(len(line))
+	var size [4]byte
+	for {
+		// 末尾半条记录来自中断的写入，忽略
+		if _, err := io.ReadFull(r, size[:]); err != nil { return end, nil }
+		n := int64(binary.BigEndian.Uint32(size[:]))
+		if end+int64(len(size))+n > fileSize { return end, nil }
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(r, sealed); err != nil { return end, nil }
+		plain, err := v.open(sealed)
+		if err != nil { return 0, fmt.Errorf("%s: corrupt vault record: %v", v.path, err) }
+		var e Entry
+		if err := json.Unmarshal(plain, &e); err != nil { return 0, fmt.Errorf("%s: corrupt vault record: %v", v.path, err) }
+		v.index(e)
+		end += int64(len(size) + len(sealed))
+	}
+}
+
+func entryKey(scope, category, original string) string { return scope + "\x00" + category + "\x00" + original }
+
+func (v *Vault) index(e Entry) {
+	v.byOriginal[entryKey(e.Scope, e.Category, e.Original)] = e.Surrogate
+	v.bySurrogate[e.Surrogate] = e
+	v.lengths = nil
+}
+
+// Secret 返回用于派生确定性替身的密钥
+func (v *Vault) Secret() []byte { return v.secret }
+
+// Surrogate 返回已有映射，或用 generate 生成一个未被占用的新替身并追加写入。
+// generate 的参数为重试序号，同一原文在同一范围内总是得到同一替身。
+func (v *Vault) Surrogate(scope, category, original string, generate func(attempt int) string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := entryKey(scope, category, original)
+	if s, ok := v.byOriginal[key]; ok { return s, nil }
+	var s string
+	for attempt := 0; ; attempt++ {
+		s = generate(attempt)
+		if _, taken := v.bySurrogate[s]; !taken && s != original { break }
+		if attempt > 100 { return "", fmt.Errorf("cannot find a free surrogate for %s", category) }
+	}
+	e := Entry{Scope: scope, Category: category, Original: original, Surrogate: s}
+	plain, _ := json.Marshal(e)
+	sealed := v.seal(plain)
+	rec := make([]byte, 4+len(sealed))
+	binary.BigEndian.PutUint32(rec, uint32(len(sealed)))
+	copy(rec[4:], sealed)
+	if _, err := v.f.Write(rec); err != nil { return "", err }
+	v.index(e)
+	return s, nil
+}
+
+// Lookup 按替身查原文
+func (v *Vault) Lookup(surrogate string) (Entry, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	e, ok := v.bySurrogate[surrogate]
+	return e, ok
+}
+
+func isAlnum(c byte) bool { return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' }
+
+// tokenSep 是号码、地址内部的连接符，两侧都是字母数字时不构成边界 (如 "10.1.2.3.4"、"a-b")
+func tokenSep(c byte) bool { return c == '.' || c == '-' || c == ':' || c == '@' || c == '+' }
+
+// boundary 判断 text[i] 前后是否断开：edge 为替身在该侧的字节，before 表示检查替身之前
+func boundary(text string, i int, edge byte, before bool) bool {
+	if !isAlnum(edge) { return true }
+	step := 1
+	if before { i, step = i-1, -1 }
+	if i < 0 || i >= len(text) { return true }
+	if isAlnum(text[i]) { return false }
+	j := i + step
+	return !(tokenSep(text[i]) && j >= 0 && j < len(text) && isAlnum(text[j]))
+}
+
+// Reidentify 把文本中出现的替身还原为原文。只替换完整的词 (前后不与字母数字相连)，
+// 短替身 (QQ 号、10.x.y.z) 不会改写其他号码的一部分；重叠时优先匹配较长的替身
+func (v *Vault) Reidentify(text string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.lengths == nil {
+		seen := make(map[int]bool)
+		for s := range v.bySurrogate {
+			if !seen[len(s)] { seen[len(s)] = true; v.lengths = append(v.lengths, len(s)) }
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(v.lengths)))
+	}
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(text); {
+		n := 0
+		if boundary(text, i, text[i], true) {
+			for _, l := range v.lengths {
+				if i+l > len(text) { continue }
+				if e, ok := v.bySurrogate[text[i:i+l]]; ok && boundary(text, i+l, text[i+l-1], false) {
+					b.WriteString(text[last:i])
+					b.WriteString(e.Original)
+					n = l
+					break
+				}
+			}
+		}
+		if n > 0 { i += n; last = i; continue }
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	if last == 0 { return text }
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (v *Vault) Len() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.bySurrogate)
+}
+
+func (v *Vault) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.f.Close()
+}
+
+type cachedVault struct {
+	vault *Vault
+	pass  [32]byte
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cachedVault)
+)
+
+// Cached 按路径复用已打开的保险库，避免每篇文档重新派生密钥
+func Cached(path, passphrase string) (*Vault, error) {
+	sum := sha256.Sum256([]byte(passphrase))
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := cache[path]; ok {
+		if !hmac.Equal(c.pass[:], sum[:]) { return nil, ErrPassphrase }
+		return c.vault, nil
+	}
+	v, err := Open(path, passphrase)
+	if err != nil { return nil, err }
+	cache[path] = cachedVault{v, sum}
+	return v, nil
+}
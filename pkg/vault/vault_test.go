@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTornTailIsTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t"+Ext)
+	v, err := Open(path, "pw")
+	if err != nil { t.Fatal(err) }
+	gen := func(s string) func(int) string { return func(int) string { return s } }
+	if _, err := v.Surrogate("ds", "name", "张三", gen("李四")); err != nil { t.Fatal(err) }
+	v.Close()
+
+	// 模拟写到一半时中断：长度前缀完整，密文只写了一部分
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil { t.Fatal(err) }
+	f.Write([]byte{0, 0, 0, 64, 1, 2, 3})
+	f.Close()
+
+	v, err = Open(path, "pw")
+	if err != nil { t.Fatalf("open with torn tail: %v", err) }
+	if v.Len() != 1 { t.Fatalf("Len = %d, want 1", v.Len()) }
+	if _, err := v.Surrogate("ds", "name", "王五", gen("赵六")); err != nil { t.Fatal(err) }
+	v.Close()
+
+	v, err = Open(path, "pw")
+	if err != nil { t.Fatalf("reopen after append: %v", err) }
+	defer v.Close()
+	if v.Len() != 2 { t.Fatalf("Len = %d, want 2", v.Len()) }
+	if e, ok := v.Lookup("赵六"); !ok || e.Original != "王五" { t.Fatalf("Lookup = %+v, %v", e, ok) }
+}
+
+func TestReidentifyMatchesWholeTokens(t *testing.T) {
+	v, err := Open(filepath.Join(t.TempDir(), "r"+Ext), "pw")
+	if err != nil { t.Fatal(err) }
+	defer v.Close()
+	for _, e := range []Entry{{"d", "qq", "88888", "12345"}, {"d", "ipv4", "192.168.0.7", "10.1.2.3"}, {"d", "email", "a@b.cn", "wei.wang000123@example.com"}} {
+		if _, err := v.Surrogate(e.Scope, e.Category, e.Original, func(int) string { return e.Surrogate }); err != nil { t.Fatal(err) }
+	}
+	for in, want := range map[string]string{
+		"QQ 12345，IP 10.1.2.3。":              "QQ 88888，IP 192.168.0.7。",
+		"订单号 9912345678 与 110.1.2.30 不变":      "订单号 9912345678 与 110.1.2.30 不变",
+		"10.1.2.3.4 和 12345-6 不变":             "10.1.2.3.4 和 12345-6 不变",
+		"邮箱wei.wang000123@example.com联系":     "邮箱a@b.cn联系",
+		"(12345) and 10.1.2.3.":               "(88888) and 192.168.0.7.",
+	} {
+		if got := v.Reidentify(in); got != want { t.Errorf("Reidentify(%q) = %q, want %q", in, got, want) }
+	}
+}